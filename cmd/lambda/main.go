@@ -8,16 +8,15 @@ import (
 	"os"
 	"strconv"
 	"time"
+	_ "time/tzdata" // users' time zones are loaded by name
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	"github.com/hareku/habit-tracker-app/internal/api"
 	"github.com/hareku/habit-tracker-app/internal/applog"
 	"github.com/hareku/habit-tracker-app/internal/auth"
-	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/storage"
+	"github.com/hareku/habit-tracker-app/internal/webhook"
 )
 
 var (
@@ -63,14 +62,9 @@ func newHandler(ctx context.Context) (*httpadapter.HandlerAdapter, error) {
 	}
 	slog.Info("Loaded SECURE env", slog.Bool("secure", secure))
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+	repo, _, err := storage.Open(ctx, storage.ConfigFromEnv())
 	if err != nil {
-		return nil, fmt.Errorf("load aws config: %w", err)
-	}
-	cfg.Region = "ap-northeast-1"
-	if e := os.Getenv("AWS_ENDPOINT"); e != "" {
-		cfg.BaseEndpoint = aws.String(e)
-		slog.Info("Loaded AWS_ENDPOINT env", slog.String("endpoint", e))
+		return nil, fmt.Errorf("open storage: %w", err)
 	}
 
 	csrfKey, err := secretsDir.ReadFile(".secrets/csrf-token.key")
@@ -79,14 +73,14 @@ func newHandler(ctx context.Context) (*httpadapter.HandlerAdapter, error) {
 	}
 
 	return httpadapter.New(api.NewHTTPHandler(&api.NewHTTPHandlerInput{
-		AuthMiddleware: api.NewAuthMiddleware(fa),
-		CSRFMiddleware: api.NewCSRFMiddleware(csrfKey, secure),
-		Authenticator:  fa,
-		Repository: &repository.DynamoRepository{
-			Client:    dynamodb.NewFromConfig(cfg),
-			TableName: "HabitTrackerApp",
-		},
-		Secure: secure,
+		AuthMiddleware:    api.NewAuthMiddleware(fa),
+		APIAuthMiddleware: api.NewAccessTokenMiddleware(repo, api.NewAPIAuthMiddleware(fa)),
+		CSRFMiddleware:    api.NewCSRFMiddleware(csrfKey, secure),
+		Authenticator:     fa,
+		Repository:        repo,
+		Webhooks:          webhook.NewDispatcher(repo),
+		Secure:            secure,
+		CursorKey:         csrfKey,
 	})), nil
 }
 
@@ -0,0 +1,136 @@
+// Command reminders sends the reminders of habits which are due, and retries the webhook deliveries which are due.
+//
+// On AWS Lambda, it is invoked by a scheduled EventBridge rule whose rate is the window, e.g. rate(5 minutes),
+// and reads its configuration from the environment. Elsewhere, it runs once with the flags, for local testing.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	_ "time/tzdata" // users' time zones are loaded by name
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/hareku/habit-tracker-app/internal/applog"
+	"github.com/hareku/habit-tracker-app/internal/reminder"
+	"github.com/hareku/habit-tracker-app/internal/storage"
+	"github.com/hareku/habit-tracker-app/internal/webhook"
+)
+
+// The kinds of notifiers.
+const (
+	notifierLog  = "log"
+	notifierFile = "file"
+)
+
+func main() {
+	slog.SetDefault(slog.New(
+		applog.NewContextValueLogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+			AddSource: true,
+			Level:     slog.LevelInfo,
+		})),
+	))
+
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		lambda.Start(handleSchedule)
+		return
+	}
+
+	backend := flag.String("storage", storage.BackendSQLite, "storage backend: sqlite or dynamodb")
+	sqlitePath := flag.String("sqlite-path", "habit-tracker.db", "path of the SQLite database file")
+	notifier := flag.String("notifier", notifierLog, "where to send the reminders: log or file")
+	file := flag.String("file", "reminders.jsonl", "path of the file which the file notifier appends the reminders to")
+	window := flag.Duration("window", reminder.DefaultWindow, "interval of the runs; the reminder times in (now-window, now] are due")
+	at := flag.String("now", "", "time to run at in RFC 3339, instead of the current time")
+	flag.Parse()
+
+	ctx := context.Background()
+	now := time.Now()
+	if *at != "" {
+		var err error
+		if now, err = time.Parse(time.RFC3339, *at); err != nil {
+			slog.ErrorContext(ctx, fmt.Errorf("parse now: %w", err).Error())
+			os.Exit(1)
+		}
+	}
+	if err := run(ctx, now, *window, *notifier, *file, storage.Config{
+		Backend:    *backend,
+		SQLitePath: *sqlitePath,
+	}); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+}
+
+// handleSchedule runs at the time of the scheduled event, so that a delayed invocation does not skip reminders.
+// The notifier and the window are read from REMINDER_NOTIFIER, REMINDER_FILE and REMINDER_WINDOW.
+func handleSchedule(ctx context.Context, e events.CloudWatchEvent) error {
+	window := reminder.DefaultWindow
+	if s := os.Getenv("REMINDER_WINDOW"); s != "" {
+		var err error
+		if window, err = time.ParseDuration(s); err != nil {
+			return fmt.Errorf("parse REMINDER_WINDOW: %w", err)
+		}
+	}
+	notifier := os.Getenv("REMINDER_NOTIFIER")
+	if notifier == "" {
+		notifier = notifierLog
+	}
+	return run(ctx, scheduledTime(e, time.Now()), window, notifier, os.Getenv("REMINDER_FILE"), storage.ConfigFromEnv())
+}
+
+// scheduledTime returns the time of the event, or now if the event has no time.
+func scheduledTime(e events.CloudWatchEvent, now time.Time) time.Time {
+	if e.Time.IsZero() {
+		return now
+	}
+	return e.Time
+}
+
+func run(ctx context.Context, now time.Time, window time.Duration, notifierKind, file string, sc storage.Config) error {
+	notifier, err := newNotifier(notifierKind, file)
+	if err != nil {
+		return err
+	}
+
+	repo, closeRepo, err := storage.Open(ctx, sc)
+	if err != nil {
+		return fmt.Errorf("open storage: %w", err)
+	}
+	defer closeRepo()
+
+	r := reminder.NewRunner(repo, notifier)
+	r.Window = window
+	sent, err := r.Run(ctx, now)
+	slog.InfoContext(ctx, "Sent reminders", slog.Time("now", now), slog.Int("sent", sent))
+	if err != nil {
+		err = fmt.Errorf("run reminders: %w", err)
+	}
+
+	// The webhook deliveries are retried even if some reminders fail.
+	retried, retryErr := webhook.NewDispatcher(repo).RetryDue(ctx, now)
+	slog.InfoContext(ctx, "Retried webhook deliveries", slog.Time("now", now), slog.Int("retried", retried))
+	if retryErr != nil {
+		retryErr = fmt.Errorf("retry webhook deliveries: %w", retryErr)
+	}
+	return errors.Join(err, retryErr)
+}
+
+func newNotifier(kind, file string) (reminder.Notifier, error) {
+	switch kind {
+	case notifierLog:
+		return &reminder.LogNotifier{}, nil
+	case notifierFile:
+		if file == "" {
+			return nil, fmt.Errorf("the file notifier needs a file path")
+		}
+		return &reminder.FileNotifier{Path: file}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", kind)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 1, 1, 9, 7, 0, 0, time.UTC)
+
+	// The event which a scheduled EventBridge rule sends to the function.
+	payload := `{
+		"version": "0",
+		"id": "53dc4d37-cffa-4f76-80c9-8b7d4a4d2eaa",
+		"detail-type": "Scheduled Event",
+		"source": "aws.events",
+		"account": "123456789012",
+		"time": "2021-01-01T09:05:00Z",
+		"region": "us-east-1",
+		"resources": ["arn:aws:events:us-east-1:123456789012:rule/reminders"],
+		"detail": {}
+	}`
+	var e events.CloudWatchEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &e))
+	require.Equal(t, time.Date(2021, 1, 1, 9, 5, 0, 0, time.UTC), scheduledTime(e, now))
+
+	// An event without the time, e.g. of EventBridge Scheduler, runs at the current time.
+	e = events.CloudWatchEvent{}
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &e))
+	require.Equal(t, now, scheduledTime(e, now))
+}
@@ -0,0 +1,140 @@
+// Command server serves the app over plain HTTP, for self-hosting without AWS Lambda.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+	_ "time/tzdata" // users' time zones are loaded by name
+
+	"github.com/hareku/habit-tracker-app/internal/api"
+	"github.com/hareku/habit-tracker-app/internal/applog"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/storage"
+	"github.com/hareku/habit-tracker-app/internal/webhook"
+)
+
+func main() {
+	slog.SetDefault(slog.New(
+		applog.NewContextValueLogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+			AddSource: true,
+			Level:     slog.LevelInfo,
+		})),
+	))
+
+	addr := flag.String("addr", ":3000", "address to listen on")
+	secrets := flag.String("secrets", "cmd/lambda/.secrets", "directory of habittrackerapp-cred.json and csrf-token.key")
+	secure := flag.Bool("secure", false, "serve cookies only over HTTPS")
+	backend := flag.String("storage", storage.BackendSQLite, "storage backend: sqlite or dynamodb")
+	sqlitePath := flag.String("sqlite-path", "habit-tracker.db", "path of the SQLite database file")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *addr, *secrets, *secure, storage.Config{
+		Backend:    *backend,
+		SQLitePath: *sqlitePath,
+	}); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, addr, secrets string, secure bool, sc storage.Config) error {
+	googleCred, err := os.ReadFile(filepath.Join(secrets, "habittrackerapp-cred.json"))
+	if err != nil {
+		return fmt.Errorf("open google cred: %w", err)
+	}
+	fa, err := auth.NewFirebaseAuthenticator(googleCred)
+	if err != nil {
+		return fmt.Errorf("init firebase authenticator: %w", err)
+	}
+	csrfKey, err := os.ReadFile(filepath.Join(secrets, "csrf-token.key"))
+	if err != nil {
+		return fmt.Errorf("open csrf key: %w", err)
+	}
+
+	repo, closeRepo, err := storage.Open(ctx, sc)
+	if err != nil {
+		return fmt.Errorf("open storage: %w", err)
+	}
+	defer closeRepo()
+	webhooks := webhook.NewDispatcher(repo)
+	// The failed deliveries are retried here, as the scheduled worker of cmd/reminders does on AWS Lambda.
+	retryCtx, stopRetries := context.WithCancel(ctx)
+	retriesDone := make(chan struct{})
+	go func() {
+		defer close(retriesDone)
+		retryWebhooks(retryCtx, webhooks)
+	}()
+	defer func() {
+		stopRetries()
+		<-retriesDone
+	}()
+
+	srv := &http.Server{
+		Addr: addr,
+		Handler: api.NewHTTPHandler(&api.NewHTTPHandlerInput{
+			AuthMiddleware:    api.NewAuthMiddleware(fa),
+			APIAuthMiddleware: api.NewAccessTokenMiddleware(repo, api.NewAPIAuthMiddleware(fa)),
+			CSRFMiddleware:    api.NewCSRFMiddleware(csrfKey, secure),
+			Authenticator:     fa,
+			Repository:        repo,
+			Webhooks:          webhooks,
+			Secure:            secure,
+			CursorKey:         csrfKey,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", slog.String("addr", addr))
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("listen and serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listen and serve: %w", err)
+	}
+	// The pending retries are recorded, so only the running first attempts are waited for.
+	if err := webhooks.Wait(shutdownCtx); err != nil {
+		return fmt.Errorf("wait for webhooks: %w", err)
+	}
+	return nil
+}
+
+// retryWebhooks retries the due webhook deliveries every minute until ctx is done.
+func retryWebhooks(ctx context.Context, d *webhook.Dispatcher) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := d.RetryDue(ctx, now); err != nil {
+				slog.ErrorContext(ctx, fmt.Errorf("retry webhook deliveries: %w", err).Error())
+			}
+		}
+	}
+}
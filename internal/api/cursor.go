@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// historyCursor is the position of a page of the check history of a habit.
+type historyCursor struct {
+	HabitID string `json:"h"`
+	// After is the date which the page starts after.
+	After     string `json:"a,omitempty"`
+	Ascending bool   `json:"asc,omitempty"`
+	From      string `json:"f,omitempty"`
+	To        string `json:"t,omitempty"`
+}
+
+// notesCursor is the position of a page of the notes of a habit.
+type notesCursor struct {
+	HabitID string `json:"h"`
+	// Before is the date which the page starts before.
+	Before string `json:"b"`
+}
+
+// cursorCodec encodes cursors into opaque strings for query strings, signed so that they can not be tampered with.
+type cursorCodec struct {
+	key []byte
+}
+
+// newCursorCodec derives the signing key from secret, so that secret may be shared with another purpose.
+func newCursorCodec(secret []byte) *cursorCodec {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("habit-tracker-app cursor"))
+	return &cursorCodec{key: mac.Sum(nil)}
+}
+
+func (c *cursorCodec) encode(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *cursorCodec) decode(s string, v any) error {
+	p, sig, ok := bytes.Cut([]byte(s), []byte("."))
+	if !ok {
+		return fmt.Errorf("cursor has no signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(string(p))
+	if err != nil {
+		return fmt.Errorf("decode cursor payload: %w", err)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(string(sig))
+	if err != nil {
+		return fmt.Errorf("decode cursor signature: %w", err)
+	}
+	if !hmac.Equal(mac, c.sign(payload)) {
+		return fmt.Errorf("cursor signature mismatch")
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("unmarshal cursor: %w", err)
+	}
+	return nil
+}
+
+func (c *cursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
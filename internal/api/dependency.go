@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"net/http"
+	"time"
 
 	firebase "firebase.google.com/go/auth"
 	"github.com/hareku/habit-tracker-app/internal/auth"
@@ -19,17 +20,48 @@ type Authenticator interface {
 type DynamoRepository interface {
 	AllArchivedHabits(ctx context.Context, uid auth.UserID) ([]*repository.DynamoHabit, error)
 	AllHabits(ctx context.Context, uid auth.UserID) ([]*repository.DynamoHabit, error)
+	AllReminders(ctx context.Context) ([]*repository.DynamoReminder, error)
 	ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error
-	CreateCheck(ctx context.Context, uid auth.UserID, hid, date string) (*repository.DynamoCheck, error)
-	CreateHabit(ctx context.Context, uid auth.UserID, title string) (*repository.DynamoHabit, error)
+	BackfillChecks(ctx context.Context, in *repository.DynamoRepositoryBackfillChecksInput) ([]string, error)
+	CreateAccessToken(ctx context.Context, in *repository.DynamoRepositoryCreateAccessTokenInput) (*repository.DynamoAccessToken, error)
+	CreateCheck(ctx context.Context, in *repository.DynamoRepositoryCreateCheckInput) (*repository.DynamoCheck, error)
+	CreateChecks(ctx context.Context, in []*repository.DynamoRepositoryCreateCheckInput) ([]*repository.DynamoCheck, []error)
+	CreateHabit(ctx context.Context, in *repository.DynamoRepositoryCreateHabitInput) (*repository.DynamoHabit, error)
+	CreateWebhook(ctx context.Context, in *repository.DynamoRepositoryCreateWebhookInput) (*repository.DynamoWebhook, error)
+	DeleteAccessToken(ctx context.Context, uid auth.UserID, tid string) error
 	DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error
+	DeleteFeedToken(ctx context.Context, uid auth.UserID) error
 	DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error
+	DeleteUserData(ctx context.Context, uid auth.UserID) error
+	DeleteWebhook(ctx context.Context, uid auth.UserID, wid string) error
+	FindAccessToken(ctx context.Context, uid auth.UserID, tid string) (*repository.DynamoAccessToken, error)
 	FindArchivedHabit(ctx context.Context, uid auth.UserID, hid string) (*repository.DynamoHabit, error)
+	FindFeedToken(ctx context.Context, uid auth.UserID) (*repository.DynamoFeedToken, error)
 	FindHabit(ctx context.Context, uid auth.UserID, hid string) (*repository.DynamoHabit, error)
-	ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID) ([]*repository.DynamoCheck, error)
-	ListLatestChecksWithLimit(ctx context.Context, uid auth.UserID, hid string, limit int32) ([]*repository.DynamoCheck, error)
+	FindProfile(ctx context.Context, uid auth.UserID) (*repository.DynamoProfile, error)
+	FindReminder(ctx context.Context, uid auth.UserID, hid string) (*repository.DynamoReminder, error)
+	ImportChecks(ctx context.Context, in *repository.DynamoRepositoryImportChecksInput) ([]string, error)
+	ListAccessTokens(ctx context.Context, uid auth.UserID) ([]*repository.DynamoAccessToken, error)
+	ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*repository.DynamoCheck, error)
+	ListChecksBetweenInAllHabits(ctx context.Context, uid auth.UserID, from, to string) ([]*repository.DynamoCheck, error)
+	ListChecks(ctx context.Context, in *repository.DynamoRepositoryListChecksInput) (*repository.DynamoRepositoryListChecksOutput, error)
+	ListCheckNotes(ctx context.Context, in *repository.DynamoRepositoryListCheckNotesInput) (*repository.DynamoRepositoryListCheckNotesOutput, error)
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*repository.DynamoWebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, uid auth.UserID, wid string, limit int32) ([]*repository.DynamoWebhookDelivery, error)
+	ListWebhooks(ctx context.Context, uid auth.UserID) ([]*repository.DynamoWebhook, error)
+	PutFeedToken(ctx context.Context, in *repository.DynamoRepositoryPutFeedTokenInput) error
+	PutReminder(ctx context.Context, in *repository.DynamoRepositoryPutReminderInput) error
+	PutWebhookDelivery(ctx context.Context, d *repository.DynamoWebhookDelivery) error
+	TouchAccessToken(ctx context.Context, uid auth.UserID, tid string, at time.Time) error
 	UnarchiveHabit(ctx context.Context, uid auth.UserID, hid string) error
+	UpdateCheckNote(ctx context.Context, in *repository.DynamoRepositoryUpdateCheckNoteInput) error
 	UpdateHabit(ctx context.Context, in *repository.DynamoRepositoryUpdateHabitInput) error
+	UpdateProfile(ctx context.Context, in *repository.DynamoRepositoryUpdateProfileInput) error
+}
+
+// WebhookPublisher delivers the events of a user to the webhooks of the user.
+type WebhookPublisher interface {
+	Publish(ctx context.Context, uid auth.UserID, eventType string, data any)
 }
 
 type Middleware func(next http.Handler) http.Handler
@@ -1,9 +1,20 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
 
 //go:generate mockgen -package ${GOPACKAGE} -destination mock_${GOFILE} -source dependency.go
 
+// Every backend implement DynamoRepository.
+var (
+	_ DynamoRepository = (*repository.DynamoRepository)(nil)
+	_ DynamoRepository = (*repository.MemoryRepository)(nil)
+	_ DynamoRepository = (*repository.SQLiteRepository)(nil)
+)
+
 // noopMiddleware is a middleware that does nothing.
 var noopMiddleware = func(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -3,6 +3,13 @@ package api
 
 type TypeTemplatePage string
 
+const TemplatePageAccount TypeTemplatePage = "account.html"
+const TemplatePageCalendar TypeTemplatePage = "calendar.html"
+const TemplatePageChecks TypeTemplatePage = "checks.html"
+const TemplatePageFeed TypeTemplatePage = "feed.html"
 const TemplatePageHabit TypeTemplatePage = "habit.html"
+const TemplatePageImport TypeTemplatePage = "import.html"
 const TemplatePageLogin TypeTemplatePage = "login.html"
+const TemplatePageStats TypeTemplatePage = "stats.html"
 const TemplatePageTop TypeTemplatePage = "top.html"
+const TemplatePageWebhooks TypeTemplatePage = "webhooks.html"
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+const (
+	// heatmapCellSize is the size of a cell of the heatmap including the gap between cells, in pixels.
+	heatmapCellSize = 12
+	// heatmapTop is the height of the month labels above the cells.
+	heatmapTop = 15
+)
+
+// heatmapColors are the colors of the levels of a day, from no check to a complete check.
+var heatmapColors = [...]string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+// heatmap is an SVG heatmap of the checks of the last 12 months, with a column for each week (Monday to Sunday).
+type heatmap struct {
+	Width  int
+	Height int
+	Cells  []heatmapCell
+	Months []heatmapMonth
+}
+
+type heatmapCell struct {
+	X, Y  int
+	Color string
+	Title string
+}
+
+type heatmapMonth struct {
+	X     int
+	Label string
+}
+
+// heatmapStart returns the first date of the heatmap ending on today.
+func heatmapStart(today time.Time) time.Time {
+	return schedule.WeekStart(today.AddDate(-1, 0, 1))
+}
+
+// newHeatmap builds the heatmap of checks from heatmapStart(today) to today.
+// The color of a day reflects the ratio of its value to the target of the habit,
+// or to the largest value in the heatmap if the habit is quantitative without a target.
+func newHeatmap(habit *repository.DynamoHabit, checks []*repository.DynamoCheck, today time.Time) *heatmap {
+	values := make(map[string]float64, len(checks))
+	maxValue := 0.0
+	for _, c := range checks {
+		values[c.Date] = c.Value
+		maxValue = max(maxValue, c.Value)
+	}
+	target := habit.Target
+	if target <= 0 && habit.Quantitative() {
+		target = maxValue
+	}
+
+	start := heatmapStart(today)
+	end := today.Format("2006-01-02")
+	hm := &heatmap{Height: heatmapTop + 7*heatmapCellSize}
+	for d, i := start, 0; d.Format("2006-01-02") <= end; d, i = d.AddDate(0, 0, 1), i+1 {
+		date := d.Format("2006-01-02")
+		week, weekday := i/7, i%7
+		x := week * heatmapCellSize
+		if d.Day() == 1 {
+			hm.Months = append(hm.Months, heatmapMonth{X: x, Label: d.Format("Jan")})
+		}
+
+		cell := heatmapCell{X: x, Y: heatmapTop + weekday*heatmapCellSize, Color: heatmapColors[0], Title: date}
+		if v, ok := values[date]; ok {
+			level := int(math.Ceil(schedule.Ratio(v, target) * 4))
+			cell.Color = heatmapColors[min(max(level, 1), 4)]
+			if habit.Quantitative() {
+				cell.Title = fmt.Sprintf("%s: %s", date, formatQuantity(v))
+				if habit.Unit != "" {
+					cell.Title += " " + habit.Unit
+				}
+			} else {
+				cell.Title = fmt.Sprintf("%s: checked", date)
+			}
+		}
+		hm.Cells = append(hm.Cells, cell)
+		hm.Width = x + heatmapCellSize
+	}
+	return hm
+}
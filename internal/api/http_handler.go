@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"html/template"
 	"log/slog"
+	"math"
 	"net/http"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -19,10 +22,16 @@ import (
 
 type NewHTTPHandlerInput struct {
 	AuthMiddleware Middleware
-	CSRFMiddleware Middleware
-	Authenticator  Authenticator
-	Repository     DynamoRepository
-	Secure         bool
+	// APIAuthMiddleware authenticates the requests of the JSON API. It rejects every request if it is nil.
+	APIAuthMiddleware Middleware
+	CSRFMiddleware    Middleware
+	Authenticator     Authenticator
+	Repository        DynamoRepository
+	// Webhooks publishes the events of the writes of Repository. No events are published if it is nil.
+	Webhooks WebhookPublisher
+	Secure   bool
+	// CursorKey is the secret to sign the cursors of paginations.
+	CursorKey []byte
 }
 
 type HTTPHandler struct {
@@ -30,15 +39,23 @@ type HTTPHandler struct {
 	Repository    DynamoRepository
 	Secure        bool
 
-	mux   *chi.Mux
-	tmpls map[TypeTemplatePage]*template.Template
+	mux     *chi.Mux
+	tmpls   map[TypeTemplatePage]*template.Template
+	cursors *cursorCodec
+	now     func() time.Time
 }
 
 func NewHTTPHandler(in *NewHTTPHandlerInput) *HTTPHandler {
+	repo := in.Repository
+	if in.Webhooks != nil {
+		repo = &webhookRepository{DynamoRepository: repo, publisher: in.Webhooks}
+	}
 	h := &HTTPHandler{
 		Authenticator: in.Authenticator,
-		Repository:    in.Repository,
+		Repository:    repo,
 		Secure:        in.Secure,
+		cursors:       newCursorCodec(in.CursorKey),
+		now:           time.Now,
 	}
 
 	common := template.Must(template.ParseFS(templates, "templates/_*.html")).
@@ -46,6 +63,7 @@ func NewHTTPHandler(in *NewHTTPHandlerInput) *HTTPHandler {
 			"method_field": func(method string) template.HTML {
 				return formmethod.TemplateField(method)
 			},
+			"quantity": formatQuantity,
 		})
 	tmpls := map[TypeTemplatePage]*template.Template{}
 	for _, page := range ListPages() {
@@ -56,36 +74,71 @@ func NewHTTPHandler(in *NewHTTPHandlerInput) *HTTPHandler {
 	}
 	h.tmpls = tmpls
 
+	apiAuthMiddleware := in.APIAuthMiddleware
+	if apiAuthMiddleware == nil {
+		apiAuthMiddleware = func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "The JSON API is disabled.")
+			})
+		}
+	}
+
 	r := chi.NewMux()
+	r.Use(limitRequestBody)
 	r.Use(formmethod.Middleware)
 	r.Use(slogchi.New(slog.Default()))
 	r.Use(middleware.Recoverer)
-	r.Use(in.CSRFMiddleware)
+	// The JSON API authenticates requests by the Authorization header, so it is not protected by CSRF tokens.
+	r.Mount("/api/v1", h.newAPIRouter(apiAuthMiddleware))
 
 	r.Group(func(r chi.Router) {
-		r.Use(in.AuthMiddleware)
-		r.Get("/", h.showTopPage)
-
-		r.Route(fmt.Sprintf("/habits/{%s}", URLParamHabitID), func(r chi.Router) {
-			r.Get("/", h.showHabitPage)
+		r.Use(in.CSRFMiddleware)
+
+		r.Group(func(r chi.Router) {
+			r.Use(in.AuthMiddleware)
+			r.Get("/", h.showTopPage)
+
+			r.Route(fmt.Sprintf("/habits/{%s}", URLParamHabitID), func(r chi.Router) {
+				r.Get("/", h.showHabitPage)
+				r.Get("/calendar", h.showCalendarPage)
+				r.Get("/stats", h.showStatsPage)
+			})
+
+			r.Route("/archived-habits", func(r chi.Router) {
+				r.Post("/", h.archiveHabit)
+				r.Delete("/", h.unarchiveHabit)
+			})
+
+			r.Post("/habits", h.createHabit)
+			r.Post("/checks", h.createCheck)
+			r.Post("/bulk-checks", h.createChecks)
+			r.Post("/update-habit", h.updateHabit)
+			r.Post("/delete-habit", h.deleteHabit)
+			r.Delete(fmt.Sprintf("/habits/{%s}/checks", URLParamHabitID), h.deleteCheck)
+			r.Put(fmt.Sprintf("/habits/{%s}/checks", URLParamHabitID), h.updateCheckNote)
+			r.Post(fmt.Sprintf("/habits/{%s}/backfill", URLParamHabitID), h.backfillChecks)
+			r.Put(fmt.Sprintf("/habits/{%s}/reminder", URLParamHabitID), h.updateReminder)
+			r.Put("/profile", h.updateProfile)
+			r.Get("/export", h.exportData)
+			r.Post("/feed-token", h.createFeedToken)
+			r.Delete("/feed-token", h.deleteFeedToken)
+			r.Get("/account", h.showAccountPage)
+			r.Post("/access-tokens", h.createAccessToken)
+			r.Delete(fmt.Sprintf("/access-tokens/{%s}", URLParamAccessTokenID), h.deleteAccessToken)
+			r.Get("/webhooks", h.showWebhooksPage)
+			r.Post("/webhooks", h.createWebhook)
+			r.Delete(fmt.Sprintf("/webhooks/{%s}", URLParamWebhookID), h.deleteWebhook)
+			r.Get("/import", h.showImportPage)
+			r.Post("/import", h.previewImport)
+			r.Post("/import/commit", h.commitImport)
+			r.Post("/logout", h.logout)
+			r.Post("/delete-account", h.deleteAccount)
 		})
-
-		r.Route("/archived-habits", func(r chi.Router) {
-			r.Post("/", h.archiveHabit)
-			r.Delete("/", h.unarchiveHabit)
-		})
-
-		r.Post("/habits", h.createHabit)
-		r.Post("/checks", h.createCheck)
-		r.Post("/update-habit", h.updateHabit)
-		r.Post("/delete-habit", h.deleteHabit)
-		r.Delete(fmt.Sprintf("/habits/{%s}/checks", URLParamHabitID), h.deleteCheck)
-		r.Post("/logout", h.logout)
-		r.Post("/delete-account", h.deleteAccount)
+		r.Get(fmt.Sprintf("/feeds/{%s}/{%s}.ics", URLParamUserID, URLParamFeedToken), h.showCalendarFeed)
+		r.Get("/__/auth/*", h.handleFirebaseAuth)
+		r.Get("/login", h.showLoginPage)
+		r.Post("/session-cookie", h.storeSessionCookie)
 	})
-	r.Get("/__/auth/*", h.handleFirebaseAuth)
-	r.Get("/login", h.showLoginPage)
-	r.Post("/session-cookie", h.storeSessionCookie)
 	h.mux = r
 
 	return h
@@ -101,12 +154,20 @@ func (h *HTTPHandler) redirect(w http.ResponseWriter, loc string) {
 }
 
 func (h *HTTPHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, apperrors.ErrArchived) {
+		http.Error(w, "The habit is archived. Unarchive it to change it.", http.StatusConflict)
+		return
+	}
 	if errors.Is(err, apperrors.ErrNotFound) {
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 	if errors.Is(err, apperrors.ErrConflict) {
-		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		http.Error(w, "The data has been changed by another request. Reload the page and try again.", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, apperrors.ErrValueRequired) {
+		http.Error(w, "Enter the value of the check.", http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -122,7 +183,7 @@ func (h *HTTPHandler) writePage(w http.ResponseWriter, r *http.Request, status i
 	}
 
 	var buf bytes.Buffer // write to buffer first to prevent partial writes
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.ExecuteTemplate(&buf, "_index.html", data); err != nil {
 		h.handleError(w, r, fmt.Errorf("execute template: %w", err))
 		return
 	}
@@ -150,6 +211,16 @@ func (h *HTTPHandler) extractHabitID(w http.ResponseWriter, r *http.Request) (st
 	return v.String(), true
 }
 
+// formatQuantity formats a value of a quantitative habit with at most 2 decimal places.
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
 const (
-	URLParamHabitID = "habitID"
+	URLParamHabitID   = "habitID"
+	URLParamUserID    = "userID"
+	URLParamFeedToken = "feedToken"
+
+	URLParamAccessTokenID = "tokenID"
+	URLParamWebhookID     = "webhookID"
 )
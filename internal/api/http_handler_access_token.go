@@ -0,0 +1,142 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+const (
+	// accessTokenPrefix is the prefix of personal access tokens, which tells them from session cookies.
+	accessTokenPrefix = "hta"
+	// maxAccessTokens is the maximum number of access tokens of a user.
+	maxAccessTokens = 20
+	// maxAccessTokenNameLength is the maximum number of characters of the name of an access token.
+	maxAccessTokenNameLength = 50
+)
+
+// formatAccessToken returns a personal access token of the form "hta.<user ID>.<token ID>.<secret>".
+// The user ID and the token ID locate the stored hash of the secret, and the user ID is encoded because
+// it may contain any character.
+func formatAccessToken(uid auth.UserID, tid, secret string) string {
+	return strings.Join([]string{
+		accessTokenPrefix,
+		base64.RawURLEncoding.EncodeToString([]byte(uid)),
+		tid,
+		secret,
+	}, ".")
+}
+
+// parseAccessToken splits a token formatted by formatAccessToken. It returns false if the token is not an access token.
+func parseAccessToken(token string) (uid auth.UserID, tid, secret string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || parts[0] != accessTokenPrefix || parts[2] == "" || parts[3] == "" {
+		return "", "", "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(b) == 0 {
+		return "", "", "", false
+	}
+	return auth.UserID(b), parts[2], parts[3], true
+}
+
+// showAccountPage shows the personal access tokens of the user.
+func (h *HTTPHandler) showAccountPage(w http.ResponseWriter, r *http.Request) {
+	h.writeAccountPage(w, r, http.StatusOK, "")
+}
+
+// writeAccountPage writes the account page. newToken is shown only once right after it is created.
+func (h *HTTPHandler) writeAccountPage(w http.ResponseWriter, r *http.Request, status int, newToken string) {
+	ctx := r.Context()
+	tokens, err := h.Repository.ListAccessTokens(ctx, auth.MustGetUserID(ctx))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list access tokens: %w", err))
+		return
+	}
+	slices.SortFunc(tokens, func(a, b *repository.DynamoAccessToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	h.writePage(w, r, status, TemplatePageAccount, map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+		"AccessTokens":    tokens,
+		"NewToken":        newToken,
+		"MaxNameLength":   maxAccessTokenNameLength,
+		"MaxAccessTokens": maxAccessTokens,
+		"CanCreate":       len(tokens) < maxAccessTokens,
+	})
+}
+
+// createAccessToken creates a personal access token and shows it.
+// The token is shown only once, because only the hash of its secret is stored.
+func (h *HTTPHandler) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenNameLength {
+		http.Error(w, fmt.Sprintf("Name must be 1 to %d characters", maxAccessTokenNameLength), http.StatusUnprocessableEntity)
+		return
+	}
+	scope := repository.AccessTokenScope(r.FormValue("scope"))
+	if scope != repository.AccessTokenScopeRead && scope != repository.AccessTokenScopeWrite {
+		http.Error(w, `Scope must be "read" or "write"`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	tokens, err := h.Repository.ListAccessTokens(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list access tokens: %w", err))
+		return
+	}
+	if len(tokens) >= maxAccessTokens {
+		http.Error(w, fmt.Sprintf("You can have at most %d access tokens. Revoke one to create another.", maxAccessTokens), http.StatusUnprocessableEntity)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		h.handleError(w, r, fmt.Errorf("generate access token: %w", err))
+		return
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	t, err := h.Repository.CreateAccessToken(ctx, &repository.DynamoRepositoryCreateAccessTokenInput{
+		UserID:    uid,
+		Name:      name,
+		Scope:     scope,
+		TokenHash: hashToken(secret),
+	})
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("create access token: %w", err))
+		return
+	}
+
+	h.writeAccountPage(w, r, http.StatusCreated, formatAccessToken(uid, t.ID, secret))
+}
+
+// deleteAccessToken revokes a personal access token.
+func (h *HTTPHandler) deleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tid, err := uuid.Parse(chi.URLParam(r, URLParamAccessTokenID))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("parse %q failed %q: %w", URLParamAccessTokenID, err, apperrors.ErrNotFound))
+		return
+	}
+	if err := h.Repository.DeleteAccessToken(ctx, auth.MustGetUserID(ctx), tid.String()); err != nil {
+		h.handleError(w, r, fmt.Errorf("delete access token: %w", err))
+		return
+	}
+	h.redirect(w, "/account")
+}
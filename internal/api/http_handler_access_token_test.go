@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_AccessTokens(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("user/1")
+	ctx := auth.SetUserID(context.Background(), uid)
+	repo := repository.NewMemoryRepository()
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		APIAuthMiddleware: NewAccessTokenMiddleware(repo, func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "session")
+			})
+		}),
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+
+	sendForm := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+	sendAPI := func(method, target, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(w, r)
+		return w
+	}
+	tokenRe := regexp.MustCompile(`value="(hta\.[^"]+)"`)
+	createToken := func(name, scope string) string {
+		t.Helper()
+		w := sendForm("POST", "/access-tokens", url.Values{"name": {name}, "scope": {scope}})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		m := tokenRe.FindStringSubmatch(w.Body.String())
+		require.NotNil(t, m, w.Body.String())
+		return html.UnescapeString(m[1])
+	}
+
+	w := sendForm("GET", "/account", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "No access tokens.")
+
+	require.Equal(t, http.StatusUnprocessableEntity, sendForm("POST", "/access-tokens", url.Values{"name": {""}, "scope": {"read"}}).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, sendForm("POST", "/access-tokens", url.Values{"name": {"CLI"}, "scope": {"admin"}}).Code)
+
+	readToken := createToken("Dashboard", "read")
+	writeToken := createToken("Sync script", "write")
+	require.NotEqual(t, readToken, writeToken)
+
+	// Only the hashes are stored, and the tokens are listed without them.
+	w = sendForm("GET", "/account", nil)
+	require.Contains(t, w.Body.String(), "Dashboard")
+	require.Contains(t, w.Body.String(), "Sync script")
+	require.NotContains(t, w.Body.String(), readToken)
+	require.Contains(t, w.Body.String(), "never")
+
+	// A read-only token can only read.
+	require.Equal(t, http.StatusOK, sendAPI("GET", "/api/v1/habits", readToken, "").Code)
+	w = sendAPI("POST", "/api/v1/habits", readToken, `{"title":"Running"}`)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "insufficient_scope")
+	require.Equal(t, http.StatusCreated, sendAPI("POST", "/api/v1/habits", writeToken, `{"title":"Running"}`).Code)
+
+	tokens, err := repo.ListAccessTokens(ctx, uid)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, tok := range tokens {
+		require.NotNil(t, tok.LastUsedAt, tok.Name)
+		require.NotContains(t, []string{readToken, writeToken}, tok.TokenHash)
+	}
+
+	// A wrong secret, a malformed token and another user's token ID are rejected.
+	require.Equal(t, http.StatusUnauthorized, sendAPI("GET", "/api/v1/habits", readToken[:len(readToken)-1]+"x", "").Code)
+	require.Equal(t, http.StatusUnauthorized, sendAPI("GET", "/api/v1/habits", "hta.broken", "").Code)
+	_, tid, secret, ok := parseAccessToken(readToken)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnauthorized, sendAPI("GET", "/api/v1/habits", formatAccessToken("other", tid, secret), "").Code)
+	// Other tokens are passed to the session authentication.
+	w = sendAPI("GET", "/api/v1/habits", "session-cookie", "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "session")
+
+	// A revoked token stops working.
+	w = sendForm("DELETE", "/access-tokens/"+tid, nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "/account", w.Header().Get("Location"))
+	require.Equal(t, http.StatusUnauthorized, sendAPI("GET", "/api/v1/habits", readToken, "").Code)
+	require.Equal(t, http.StatusOK, sendAPI("GET", "/api/v1/habits", writeToken, "").Code)
+	require.Equal(t, http.StatusNotFound, sendForm("DELETE", "/access-tokens/unknown", nil).Code)
+}
+
+func TestParseAccessToken(t *testing.T) {
+	t.Parallel()
+
+	uid, tid, secret, ok := parseAccessToken(formatAccessToken("a.b/c", "id", "secret"))
+	require.True(t, ok)
+	require.Equal(t, auth.UserID("a.b/c"), uid)
+	require.Equal(t, "id", tid)
+	require.Equal(t, "secret", secret)
+
+	for _, token := range []string{"", "hta", "hta.YQ.id", "xyz.YQ.id.secret", "hta.!.id.secret", "hta..id.secret", "hta.YQ..secret", "hta.YQ.id."} {
+		_, _, _, ok := parseAccessToken(token)
+		require.False(t, ok, token)
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+// maxAPIRequestBytes is the maximum size of a request body of the JSON API.
+const maxAPIRequestBytes = 1 << 20
+
+// URLParamCheckDate is the date of a check in the URLs of the JSON API.
+const URLParamCheckDate = "date"
+
+// newAPIRouter returns the router of the JSON API, which is mounted at /api/v1.
+// It authenticates requests by the Authorization header instead of the session cookie,
+// so it is not protected by the CSRF middleware.
+func (h *HTTPHandler) newAPIRouter(authMiddleware Middleware) chi.Router {
+	r := chi.NewRouter()
+	r.Use(authMiddleware)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "The resource is not found.")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "The method is not allowed.")
+	})
+
+	r.Route("/habits", func(r chi.Router) {
+		r.Get("/", h.apiListHabits)
+		r.Post("/", h.apiCreateHabit)
+		r.Route(fmt.Sprintf("/{%s}", URLParamHabitID), func(r chi.Router) {
+			r.Get("/", h.apiGetHabit)
+			r.Put("/", h.apiUpdateHabit)
+			r.Delete("/", h.apiDeleteHabit)
+			r.Get("/checks", h.apiListChecks)
+			r.Post("/checks", h.apiCreateCheck)
+			r.Patch(fmt.Sprintf("/checks/{%s}", URLParamCheckDate), h.apiUpdateCheck)
+			r.Delete(fmt.Sprintf("/checks/{%s}", URLParamCheckDate), h.apiDeleteCheck)
+		})
+	})
+	r.Route("/archived-habits", func(r chi.Router) {
+		r.Get("/", h.apiListArchivedHabits)
+		r.Put(fmt.Sprintf("/{%s}", URLParamHabitID), h.apiArchiveHabit)
+		r.Delete(fmt.Sprintf("/{%s}", URLParamHabitID), h.apiUnarchiveHabit)
+	})
+	return r
+}
+
+// apiHabit is the JSON representation of a habit.
+type apiHabit struct {
+	ID       string       `json:"id"`
+	Title    string       `json:"title"`
+	Archived bool         `json:"archived"`
+	Schedule jsonSchedule `json:"schedule"`
+	Unit     string       `json:"unit"`
+	Target   float64      `json:"target"`
+	// ChecksCount to LastCheckDate are computed from the checks.
+	// CurrentStreak is the streak ending on LastCheckDate, which may be already broken today.
+	ChecksCount   int     `json:"checks_count"`
+	TotalValue    float64 `json:"total_value"`
+	CurrentStreak int     `json:"current_streak"`
+	LongestStreak int     `json:"longest_streak"`
+	LastCheckDate string  `json:"last_check_date"`
+	// Version is passed to an update to detect a concurrent write.
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAPIHabit(h *repository.DynamoHabit, archived bool) *apiHabit {
+	return &apiHabit{
+		ID:            h.ID,
+		Title:         h.Title,
+		Archived:      archived,
+		Schedule:      newJSONSchedule(h.Schedule),
+		Unit:          h.Unit,
+		Target:        h.Target,
+		ChecksCount:   h.ChecksCount,
+		TotalValue:    h.TotalValue,
+		CurrentStreak: h.CurrentStreak,
+		LongestStreak: h.LongestStreak,
+		LastCheckDate: h.LastCheckDate,
+		Version:       h.Version,
+		CreatedAt:     h.CreatedAt.UTC(),
+		UpdatedAt:     h.UpdatedAt.UTC(),
+	}
+}
+
+func newAPIHabits(habits []*repository.DynamoHabit, archived bool) []*apiHabit {
+	res := make([]*apiHabit, 0, len(habits))
+	for _, h := range habits {
+		res = append(res, newAPIHabit(h, archived))
+	}
+	return res
+}
+
+// apiCheck is the JSON representation of a check.
+type apiCheck struct {
+	HabitID   string    `json:"habit_id"`
+	Date      string    `json:"date"`
+	Value     float64   `json:"value"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAPICheck(c *repository.DynamoCheck) *apiCheck {
+	return &apiCheck{
+		HabitID:   c.HabitID,
+		Date:      c.Date,
+		Value:     c.Value,
+		Note:      c.Note,
+		CreatedAt: c.CreatedAt.UTC(),
+		UpdatedAt: c.UpdatedAt.UTC(),
+	}
+}
+
+// apiError is the body of an error response of the JSON API.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	// Code is a stable identifier of the error, such as "not_found".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error(fmt.Errorf("encode json response: %w", err).Error())
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, &apiError{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// handleAPIError writes the error response of the JSON API, like handleError of the pages.
+func (h *HTTPHandler) handleAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, apperrors.ErrArchived):
+		writeAPIError(w, http.StatusConflict, "archived", "The habit is archived. Unarchive it to change it.")
+	case errors.Is(err, apperrors.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found", "The resource is not found.")
+	case errors.Is(err, apperrors.ErrConflict):
+		writeAPIError(w, http.StatusConflict, "conflict", "The data has been changed by another request.")
+	case errors.Is(err, apperrors.ErrValueRequired):
+		writeAPIError(w, http.StatusUnprocessableEntity, "value_required", "The habit is quantitative, so the check needs a positive value.")
+	default:
+		slog.ErrorContext(r.Context(), err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "internal", http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// decodeAPIRequest decodes the JSON body of the request into v.
+// If the body is not valid JSON of v, it writes an error response and returns false.
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", `Content-Type must be "application/json".`)
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", fmt.Sprintf("Invalid JSON: %s", err))
+		return false
+	}
+	return true
+}
+
+// apiHabitID extracts URLParamHabitID from URL path like extractHabitID.
+func apiHabitID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	v, err := uuid.Parse(chi.URLParam(r, URLParamHabitID))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "The resource is not found.")
+		return "", false
+	}
+	return v.String(), true
+}
+
+// apiCheckDate extracts URLParamCheckDate from URL path, which must be a date like "2006-01-02".
+func apiCheckDate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	layout := "2006-01-02"
+	date := chi.URLParam(r, URLParamCheckDate)
+	if _, err := time.Parse(layout, date); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", fmt.Sprintf("date format must be %q", layout))
+		return "", false
+	}
+	return date, true
+}
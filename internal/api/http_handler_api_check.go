@@ -0,0 +1,220 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+const (
+	// defaultAPIChecksLimit is the number of checks in a page of the JSON API by default.
+	defaultAPIChecksLimit = 30
+	// maxAPIChecksLimit is the maximum number of checks in a page of the JSON API.
+	maxAPIChecksLimit = 100
+)
+
+// apiCheckInput is the request body to create a check.
+type apiCheckInput struct {
+	// Date is today of the user if it is omitted.
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+	Note  string  `json:"note"`
+}
+
+// apiCheckNoteInput is the request body to update the note of a check.
+type apiCheckNoteInput struct {
+	Note string `json:"note"`
+}
+
+// apiListChecks returns a page of the checks of the habit, active or archived, from the latest by default.
+// The query parameters are from and to (inclusive dates), order ("asc" or "desc"), limit and after,
+// which is next_after of the previous page.
+func (h *HTTPHandler) apiListChecks(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	q := r.URL.Query()
+	in := &repository.DynamoRepositoryListChecksInput{
+		UserID:  uid,
+		HabitID: hid,
+		From:    q.Get("from"),
+		To:      q.Get("to"),
+		After:   q.Get("after"),
+		Limit:   defaultAPIChecksLimit,
+	}
+	layout := "2006-01-02"
+	for name, v := range map[string]string{"from": in.From, "to": in.To, "after": in.After} {
+		if _, err := time.Parse(layout, v); v != "" && err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid", fmt.Sprintf("%s format must be %q", name, layout))
+			return
+		}
+	}
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		in.Ascending = true
+	default:
+		writeAPIError(w, http.StatusBadRequest, "invalid", `order must be "asc" or "desc"`)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAPIChecksLimit {
+			writeAPIError(w, http.StatusBadRequest, "invalid", fmt.Sprintf("limit must be between 1 and %d", maxAPIChecksLimit))
+			return
+		}
+		in.Limit = int32(n)
+	}
+
+	if _, _, err := h.findAnyHabit(r, uid, hid); err != nil {
+		h.handleAPIError(w, r, err)
+		return
+	}
+	out, err := h.Repository.ListChecks(ctx, in)
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("list checks: %w", err))
+		return
+	}
+
+	checks := make([]*apiCheck, 0, len(out.Checks))
+	for _, c := range out.Checks {
+		checks = append(checks, newAPICheck(c))
+	}
+	res := map[string]any{"checks": checks}
+	if out.LastEvaluatedDate != "" {
+		res["next_after"] = out.LastEvaluatedDate
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) apiCreateCheck(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	var in apiCheckInput
+	if !decodeAPIRequest(w, r, &in) {
+		return
+	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleAPIError(w, r, err)
+		return
+	}
+	layout := "2006-01-02"
+	if in.Date == "" {
+		in.Date = today.Format(layout)
+	}
+	if _, err := time.Parse(layout, in.Date); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", fmt.Sprintf("check date format must be %q", layout))
+		return
+	}
+	if in.Date > today.Format(layout) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", "check date must not be in the future")
+		return
+	}
+	if err := validateQuantity(in.Value); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", fmt.Sprintf("value %s", err))
+		return
+	}
+	note, err := parseNote(in.Note)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	c, err := h.Repository.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{
+		UserID:  uid,
+		HabitID: hid,
+		Date:    in.Date,
+		Value:   in.Value,
+		Note:    note,
+	})
+	if errors.Is(err, apperrors.ErrConflict) {
+		writeAPIError(w, http.StatusConflict, "already_checked", "The habit is already checked on the date.")
+		return
+	}
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("create a check: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAPICheck(c))
+}
+
+func (h *HTTPHandler) apiUpdateCheck(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	date, ok := apiCheckDate(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	var in apiCheckNoteInput
+	if !decodeAPIRequest(w, r, &in) {
+		return
+	}
+	note, err := parseNote(in.Note)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	if err := h.Repository.UpdateCheckNote(ctx, &repository.DynamoRepositoryUpdateCheckNoteInput{
+		UserID:  uid,
+		HabitID: hid,
+		Date:    date,
+		Note:    note,
+	}); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("update a check note: %w", err))
+		return
+	}
+
+	checks, err := h.Repository.ListChecksBetween(ctx, uid, hid, date, date)
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("list checks between: %w", err))
+		return
+	}
+	if len(checks) == 0 {
+		h.handleAPIError(w, r, fmt.Errorf("check [%s] is deleted concurrently: %w", date, apperrors.ErrNotFound))
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPICheck(checks[0]))
+}
+
+func (h *HTTPHandler) apiDeleteCheck(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	date, ok := apiCheckDate(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if err := h.Repository.DeleteCheck(ctx, auth.MustGetUserID(ctx), hid, date); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("delete a check: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// apiHabitInput is the request body to create or update a habit.
+type apiHabitInput struct {
+	Title string `json:"title"`
+	// Schedule is daily if it is omitted.
+	Schedule *jsonSchedule `json:"schedule"`
+	Unit     string        `json:"unit"`
+	Target   float64       `json:"target"`
+	// Version is the version of the habit which an update is based on.
+	// If it is omitted, the update overwrites a concurrent write.
+	Version *int `json:"version"`
+}
+
+// validate validates the input and returns its schedule.
+func (in *apiHabitInput) validate() (schedule.Schedule, error) {
+	if cnt := utf8.RuneCountInString(in.Title); cnt == 0 || cnt > 50 {
+		return schedule.Schedule{}, errors.New("habit title length must be less than 50")
+	}
+	if utf8.RuneCountInString(in.Unit) > 20 {
+		return schedule.Schedule{}, errors.New("unit length must be less than 20")
+	}
+	if err := validateQuantity(in.Target); err != nil {
+		return schedule.Schedule{}, fmt.Errorf("target %w", err)
+	}
+
+	s := schedule.Daily()
+	if in.Schedule != nil {
+		s = schedule.Schedule{
+			Kind:         in.Schedule.Kind,
+			Weekdays:     slices.Sorted(slices.Values(in.Schedule.Weekdays)),
+			TimesPerWeek: in.Schedule.TimesPerWeek,
+			IntervalDays: in.Schedule.IntervalDays,
+		}
+		if s.Kind == "" {
+			s.Kind = schedule.KindDaily
+		}
+	}
+	if err := s.Validate(); err != nil {
+		return schedule.Schedule{}, fmt.Errorf("invalid schedule: %w", err)
+	}
+	return s, nil
+}
+
+// validateQuantity validates an optional quantity of the JSON API, where zero means none.
+func validateQuantity(v float64) error {
+	if math.IsNaN(v) || v < 0 || v > maxQuantity {
+		return fmt.Errorf("must be between 0 and %d", maxQuantity)
+	}
+	return nil
+}
+
+func (h *HTTPHandler) apiListHabits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	habits, err := h.Repository.AllHabits(ctx, auth.MustGetUserID(ctx))
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("all habits: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"habits": newAPIHabits(habits, false)})
+}
+
+func (h *HTTPHandler) apiListArchivedHabits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	habits, err := h.Repository.AllArchivedHabits(ctx, auth.MustGetUserID(ctx))
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("all archived habits: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"habits": newAPIHabits(habits, true)})
+}
+
+func (h *HTTPHandler) apiCreateHabit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	ctx := r.Context()
+	var in apiHabitInput
+	if !decodeAPIRequest(w, r, &in) {
+		return
+	}
+	sched, err := in.validate()
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	habit, err := h.Repository.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{
+		UserID:   auth.MustGetUserID(ctx),
+		Title:    in.Title,
+		Schedule: sched,
+		Unit:     in.Unit,
+		Target:   in.Target,
+	})
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("create a habit: %w", err))
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/habits/%s", habit.ID))
+	writeJSON(w, http.StatusCreated, newAPIHabit(habit, false))
+}
+
+// apiGetHabit returns the habit, active or archived.
+func (h *HTTPHandler) apiGetHabit(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	habit, archived, err := h.findAnyHabit(r, auth.MustGetUserID(ctx), hid)
+	if err != nil {
+		h.handleAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIHabit(habit, archived))
+}
+
+// findAnyHabit returns the habit whether it is active or archived, and whether it is archived.
+func (h *HTTPHandler) findAnyHabit(r *http.Request, uid auth.UserID, hid string) (*repository.DynamoHabit, bool, error) {
+	ctx := r.Context()
+	habit, err := h.Repository.FindHabit(ctx, uid, hid)
+	if err == nil {
+		return habit, false, nil
+	}
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return nil, false, fmt.Errorf("find habit: %w", err)
+	}
+	habit, err = h.Repository.FindArchivedHabit(ctx, uid, hid)
+	if err != nil {
+		return nil, false, fmt.Errorf("find archived habit: %w", err)
+	}
+	return habit, true, nil
+}
+
+func (h *HTTPHandler) apiUpdateHabit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	var in apiHabitInput
+	if !decodeAPIRequest(w, r, &in) {
+		return
+	}
+	sched, err := in.validate()
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	var version int
+	if in.Version != nil {
+		version = *in.Version
+	} else {
+		current, err := h.Repository.FindHabit(ctx, uid, hid)
+		if err != nil {
+			h.handleAPIError(w, r, fmt.Errorf("find habit: %w", err))
+			return
+		}
+		version = current.Version
+	}
+
+	if err := h.Repository.UpdateHabit(ctx, &repository.DynamoRepositoryUpdateHabitInput{
+		UserID:   uid,
+		HabitID:  hid,
+		Version:  version,
+		Title:    in.Title,
+		Schedule: sched,
+		Unit:     in.Unit,
+		Target:   in.Target,
+	}); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("update a habit: %w", err))
+		return
+	}
+
+	habit, err := h.Repository.FindHabit(ctx, uid, hid)
+	if err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("find habit: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIHabit(habit, false))
+}
+
+func (h *HTTPHandler) apiDeleteHabit(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if err := h.Repository.DeleteHabit(ctx, auth.MustGetUserID(ctx), hid); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("delete a habit: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) apiArchiveHabit(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if err := h.Repository.ArchiveHabit(ctx, auth.MustGetUserID(ctx), hid); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("archive a habit: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) apiUnarchiveHabit(w http.ResponseWriter, r *http.Request) {
+	hid, ok := apiHabitID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if err := h.Repository.UnarchiveHabit(ctx, auth.MustGetUserID(ctx), hid); err != nil {
+		h.handleAPIError(w, r, fmt.Errorf("unarchive a habit: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_API(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware:    noopMiddleware,
+		APIAuthMiddleware: noopMiddleware,
+		// The JSON API must not be behind the CSRF protection.
+		CSRFMiddleware: func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "CSRF", http.StatusForbidden)
+			})
+		},
+		Repository: repository.NewMemoryRepository(),
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	send := func(method, target, body string, want int) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		h.ServeHTTP(w, r.WithContext(ctx))
+		require.Equal(t, want, w.Code, w.Body.String())
+		if w.Code == http.StatusNoContent {
+			return nil
+		}
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+	errorCode := func(res map[string]any) string {
+		return res["error"].(map[string]any)["code"].(string)
+	}
+
+	habit := send("POST", "/api/v1/habits", `{"title":"Running","schedule":{"kind":"weekdays","weekdays":[5,1]},"unit":"km","target":5}`, http.StatusCreated)
+	hid := habit["id"].(string)
+	require.Equal(t, "Running", habit["title"])
+	require.Equal(t, []any{1.0, 5.0}, habit["schedule"].(map[string]any)["weekdays"])
+	require.Equal(t, false, habit["archived"])
+
+	require.Equal(t, "invalid", errorCode(send("POST", "/api/v1/habits", `{"title":""}`, http.StatusUnprocessableEntity)))
+	require.Equal(t, "invalid_json", errorCode(send("POST", "/api/v1/habits", `{"title":"Read","color":"red"}`, http.StatusBadRequest)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/habits", strings.NewReader("title=Read")).WithContext(ctx))
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	habits := send("GET", "/api/v1/habits", "", http.StatusOK)["habits"].([]any)
+	require.Len(t, habits, 1)
+
+	// Checks
+	check := send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"date":"2021-01-01","value":3,"note":"easy"}`, http.StatusCreated)
+	require.Equal(t, "2021-01-01", check["date"])
+	require.Equal(t, 3.0, check["value"])
+	require.Equal(t, "already_checked", errorCode(send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"date":"2021-01-01","value":1}`, http.StatusConflict)))
+	require.Equal(t, "invalid", errorCode(send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"date":"2021-01-04","value":1}`, http.StatusUnprocessableEntity)))
+	require.Equal(t, "value_required", errorCode(send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"date":"2021-01-02"}`, http.StatusUnprocessableEntity)))
+	// The date is today by default.
+	check = send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"value":2}`, http.StatusCreated)
+	require.Equal(t, "2021-01-03", check["date"])
+
+	page := send("GET", fmt.Sprintf("/api/v1/habits/%s/checks?limit=1", hid), "", http.StatusOK)
+	require.Equal(t, "2021-01-03", page["checks"].([]any)[0].(map[string]any)["date"])
+	page = send("GET", fmt.Sprintf("/api/v1/habits/%s/checks?limit=1&after=%s", hid, page["next_after"]), "", http.StatusOK)
+	require.Equal(t, "2021-01-01", page["checks"].([]any)[0].(map[string]any)["date"])
+	page = send("GET", fmt.Sprintf("/api/v1/habits/%s/checks?order=asc", hid), "", http.StatusOK)
+	require.Len(t, page["checks"], 2)
+	require.Nil(t, page["next_after"])
+	send("GET", fmt.Sprintf("/api/v1/habits/%s/checks?limit=0", hid), "", http.StatusBadRequest)
+
+	check = send("PATCH", fmt.Sprintf("/api/v1/habits/%s/checks/2021-01-01", hid), `{"note":"hard"}`, http.StatusOK)
+	require.Equal(t, "hard", check["note"])
+	send("DELETE", fmt.Sprintf("/api/v1/habits/%s/checks/2021-01-03", hid), "", http.StatusNoContent)
+	send("DELETE", fmt.Sprintf("/api/v1/habits/%s/checks/2021-01-03", hid), "", http.StatusNotFound)
+	require.Equal(t, "invalid", errorCode(send("DELETE", fmt.Sprintf("/api/v1/habits/%s/checks/1", hid), "", http.StatusUnprocessableEntity)))
+	require.Equal(t, "invalid", errorCode(send("PATCH", fmt.Sprintf("/api/v1/habits/%s/checks/1", hid), `{"note":""}`, http.StatusUnprocessableEntity)))
+
+	// Updates
+	habit = send("GET", "/api/v1/habits/"+hid, "", http.StatusOK)
+	require.Equal(t, 1.0, habit["checks_count"])
+	version := int(habit["version"].(float64))
+	habit = send("PUT", "/api/v1/habits/"+hid, fmt.Sprintf(`{"title":"Jogging","version":%d}`, version), http.StatusOK)
+	require.Equal(t, "Jogging", habit["title"])
+	require.Equal(t, "daily", habit["schedule"].(map[string]any)["kind"])
+	require.Equal(t, "conflict", errorCode(send("PUT", "/api/v1/habits/"+hid, fmt.Sprintf(`{"title":"Walking","version":%d}`, version), http.StatusConflict)))
+	send("PUT", "/api/v1/habits/"+hid, `{"title":"Walking"}`, http.StatusOK)
+
+	// Archives
+	send("PUT", "/api/v1/archived-habits/"+hid, "", http.StatusNoContent)
+	require.Empty(t, send("GET", "/api/v1/habits", "", http.StatusOK)["habits"])
+	require.Len(t, send("GET", "/api/v1/archived-habits", "", http.StatusOK)["habits"], 1)
+	require.Equal(t, true, send("GET", "/api/v1/habits/"+hid, "", http.StatusOK)["archived"])
+	require.Len(t, send("GET", fmt.Sprintf("/api/v1/habits/%s/checks", hid), "", http.StatusOK)["checks"], 1)
+	require.Equal(t, "archived", errorCode(send("POST", fmt.Sprintf("/api/v1/habits/%s/checks", hid), `{"date":"2021-01-02"}`, http.StatusConflict)))
+	send("DELETE", "/api/v1/archived-habits/"+hid, "", http.StatusNoContent)
+
+	send("DELETE", "/api/v1/habits/"+hid, "", http.StatusNoContent)
+	require.Equal(t, "not_found", errorCode(send("GET", "/api/v1/habits/"+hid, "", http.StatusNotFound)))
+	require.Equal(t, "not_found", errorCode(send("GET", "/api/v1/habits/not-a-uuid", "", http.StatusNotFound)))
+	require.Equal(t, "not_found", errorCode(send("GET", "/api/v1/unknown", "", http.StatusNotFound)))
+
+	// The pages are still behind the CSRF protection.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHTTPHandler_API_Disabled(t *testing.T) {
+	t.Parallel()
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/habits", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.JSONEq(t, `{"error":{"code":"unauthorized","message":"The JSON API is disabled."}}`, w.Body.String())
+}
@@ -28,6 +28,11 @@ func (h *HTTPHandler) logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HTTPHandler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Delete the data first, so that the user can retry the deletion by logging in again if it fails.
+	if err := h.Repository.DeleteUserData(r.Context(), auth.MustGetUserID(r.Context())); err != nil {
+		h.handleError(w, r, fmt.Errorf("delete user data: %w", err))
+		return
+	}
 	if err := h.Authenticator.DeleteUser(r.Context(), auth.MustGetUserID(r.Context())); err != nil {
 		h.handleError(w, r, fmt.Errorf("delete account: %w", err))
 		return
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHTTPHandler_deleteAccount(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	t.Run("deletes data before the user", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+
+		repo := NewMockDynamoRepository(ctrl)
+		authn := NewMockAuthenticator(ctrl)
+		gomock.InOrder(
+			repo.EXPECT().DeleteUserData(gomock.Any(), uid).Times(1).Return(nil),
+			authn.EXPECT().DeleteUser(gomock.Any(), uid).Times(1).Return(nil),
+		)
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Authenticator:  authn,
+			Repository:     repo,
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/delete-account", nil)
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusFound, w.Result().StatusCode)
+		require.Equal(t, "/login", w.Result().Header.Get("Location"))
+	})
+
+	t.Run("keeps the user if data deletion fails", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+
+		repo := NewMockDynamoRepository(ctrl)
+		repo.EXPECT().DeleteUserData(gomock.Any(), uid).Times(1).Return(errors.New("throttled"))
+		authn := NewMockAuthenticator(ctrl)
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Authenticator:  authn,
+			Repository:     repo,
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/delete-account", nil)
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+// calendarDay is a day in the month grid of the calendar page.
+type calendarDay struct {
+	Date    string
+	Day     int
+	Checked bool
+	Value   float64
+	Future  bool
+}
+
+func (h *HTTPHandler) showCalendarPage(w http.ResponseWriter, r *http.Request) {
+	hid, ok := h.extractHabitID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+	userRec, err := h.Authenticator.GetUser(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("get auth user: %w", err))
+		return
+	}
+
+	habit, err := h.Repository.FindHabit(ctx, uid, hid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("find a habit: %w", err))
+		return
+	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if m := r.URL.Query().Get("month"); m != "" {
+		first, err = time.Parse("2006-01", m)
+		if err != nil {
+			http.Error(w, `Month format must be "2006-01"`, http.StatusBadRequest)
+			return
+		}
+	}
+	last := first.AddDate(0, 1, -1)
+
+	checks, err := h.Repository.ListChecksBetween(ctx, uid, hid, first.Format("2006-01-02"), last.Format("2006-01-02"))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list checks: %w", err))
+		return
+	}
+
+	h.writePage(w, r, http.StatusOK, TemplatePageCalendar, map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+		"User":            userRec.UserInfo,
+		"Habit":           habit,
+		"Month":           first.Format("January 2006"),
+		"PrevMonth":       first.AddDate(0, -1, 0).Format("2006-01"),
+		"NextMonth":       first.AddDate(0, 1, 0).Format("2006-01"),
+		"Weeks":           calendarWeeks(first, today, checks),
+		"Location":        fmt.Sprintf("/habits/%s/calendar?month=%s", hid, first.Format("2006-01")),
+	})
+}
+
+// calendarWeeks returns the weeks (Monday to Sunday) of the month starting on first.
+// The days out of the month are nil.
+func calendarWeeks(first, today time.Time, checks []*repository.DynamoCheck) [][]*calendarDay {
+	values := make(map[string]float64, len(checks))
+	for _, c := range checks {
+		values[c.Date] = c.Value
+	}
+	end := today.Format("2006-01-02")
+
+	week := make([]*calendarDay, (int(first.Weekday())+6)%7, 7)
+	var weeks [][]*calendarDay
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		v, ok := values[date]
+		week = append(week, &calendarDay{Date: date, Day: d.Day(), Checked: ok, Value: v, Future: date > end})
+		if len(week) == 7 {
+			weeks = append(weeks, week)
+			week = make([]*calendarDay, 0, 7)
+		}
+	}
+	if len(week) > 0 {
+		weeks = append(weeks, append(week, make([]*calendarDay, 7-len(week))...))
+	}
+	return weeks
+}
+
+// redirectLocation returns the local path in the form field "location", or fallback if it is not a local path.
+// It lets a form return to the page which it was submitted from.
+func redirectLocation(r *http.Request, fallback string) string {
+	loc := r.PostFormValue("location")
+	if !strings.HasPrefix(loc, "/") || strings.HasPrefix(loc, "//") || strings.HasPrefix(loc, "/\\") {
+		return fallback
+	}
+	return loc
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/auth"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/repository/repositorytest"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHTTPHandler_showCalendarPage(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	authn := NewMockAuthenticator(ctrl)
+	authn.EXPECT().GetUser(gomock.Any(), uid).Times(1).
+		Return(&firebase.UserRecord{UserInfo: &firebase.UserInfo{UID: uid.String(), DisplayName: "test"}}, nil)
+
+	repo := NewMockDynamoRepository(ctrl)
+	seeder := repositorytest.NewSeeder()
+	habit := seeder.SeedHabit(uid, func(h *repository.DynamoHabit) {
+		h.Unit = "km"
+	})
+	repo.EXPECT().FindHabit(gomock.Any(), uid, habit.ID).Times(1).Return(habit, nil)
+	repo.EXPECT().FindProfile(gomock.Any(), uid).Times(1).Return(&repository.DynamoProfile{UserID: uid, TimeZone: "Asia/Tokyo"}, nil)
+	repo.EXPECT().ListChecksBetween(gomock.Any(), uid, habit.ID, "2021-01-01", "2021-01-31").Times(1).Return([]*repository.DynamoCheck{
+		seeder.SeedCheck(uid, habit.ID, "2021-01-01", func(c *repository.DynamoCheck) {
+			c.Value = 5
+		}),
+		seeder.SeedCheck(uid, habit.ID, "2021-01-02", nil),
+	}, nil)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Authenticator:  authn,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", fmt.Sprintf("/habits/%s/calendar", habit.ID), nil)
+	r = r.WithContext(ctx)
+	h.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	snapshotHTML(t, w.Result().Body)
+}
+
+func TestHTTPHandler_showCalendarPage_Toggle(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	authn := NewMockAuthenticator(ctrl)
+	authn.EXPECT().GetUser(gomock.Any(), uid).AnyTimes().
+		Return(&firebase.UserRecord{UserInfo: &firebase.UserInfo{UID: uid.String()}}, nil)
+
+	repo := repository.NewMemoryRepository()
+	habit, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Read"})
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Authenticator:  authn,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	calendar := fmt.Sprintf("/habits/%s/calendar?month=2020-12", habit.ID)
+	post := func(path string, form url.Values) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w.Result()
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil).WithContext(ctx))
+		return w
+	}
+
+	res := post("/checks", url.Values{"habit_id": {habit.ID}, "date": {"2020-12-24"}, "location": {calendar}})
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	require.Equal(t, calendar, res.Header.Get("Location"))
+	require.Contains(t, get(calendar).Body.String(), `title="uncheck 2020-12-24"`)
+
+	res = post("/habits/"+habit.ID+"/checks", url.Values{"_method": {"DELETE"}, "date": {"2020-12-24"}, "location": {calendar}})
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	require.Equal(t, calendar, res.Header.Get("Location"))
+	require.Contains(t, get(calendar).Body.String(), `title="check 2020-12-24"`)
+
+	// A location outside the app is ignored.
+	res = post("/checks", url.Values{"habit_id": {habit.ID}, "date": {"2020-12-24"}, "location": {"//example.com/"}})
+	require.Equal(t, "/", res.Header.Get("Location"))
+
+	require.Equal(t, http.StatusBadRequest, get(fmt.Sprintf("/habits/%s/calendar?month=2020-13", habit.ID)).Code)
+}
@@ -1,11 +1,19 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
 )
 
 func (h *HTTPHandler) createCheck(w http.ResponseWriter, r *http.Request) {
@@ -21,14 +29,242 @@ func (h *HTTPHandler) createCheck(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Check date format must be %q", layout), http.StatusUnprocessableEntity)
 		return
 	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	if date > today.Format(layout) {
+		http.Error(w, "Check date must not be in the future", http.StatusUnprocessableEntity)
+		return
+	}
+
+	value := 0.0
+	if v := r.PostFormValue("value"); v != "" {
+		var err error
+		value, err = parseQuantity(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid value: %s", err), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	note, err := parseNote(r.PostFormValue("note"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid note: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
 
-	_, err := h.Repository.CreateCheck(ctx, uid, hid, date)
+	_, err = h.Repository.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{
+		UserID:  uid,
+		HabitID: hid,
+		Date:    date,
+		Value:   value,
+		Note:    note,
+	})
 	if err != nil {
 		h.handleError(w, r, fmt.Errorf("create a check: %w", err))
 		return
 	}
 
-	h.redirect(w, "/")
+	h.redirect(w, redirectLocation(r, "/"))
+}
+
+// maxBulkChecks is the maximum number of habits which can be checked at once.
+const maxBulkChecks = 100
+
+// bulkCheckResult is the result of checking a habit in createChecks.
+type bulkCheckResult struct {
+	Habit  *repository.DynamoHabit
+	Result string
+}
+
+// createChecks checks the selected habits on the same date, which is today by default.
+// It redirects to the top page if all checks are created, or shows the result of each habit otherwise.
+func (h *HTTPHandler) createChecks(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	layout := "2006-01-02"
+	date := r.PostFormValue("date")
+	if date == "" {
+		date = today.Format(layout)
+	}
+	if _, err := time.Parse(layout, date); err != nil {
+		http.Error(w, fmt.Sprintf("Check date format must be %q", layout), http.StatusUnprocessableEntity)
+		return
+	}
+	if date > today.Format(layout) {
+		http.Error(w, "Check date must not be in the future", http.StatusUnprocessableEntity)
+		return
+	}
+
+	hids := slices.Compact(slices.Sorted(slices.Values(r.PostForm["habit_id"])))
+	if len(hids) == 0 {
+		http.Error(w, "Select habits to check", http.StatusUnprocessableEntity)
+		return
+	}
+	if len(hids) > maxBulkChecks {
+		http.Error(w, fmt.Sprintf("At most %d habits can be checked at once", maxBulkChecks), http.StatusUnprocessableEntity)
+		return
+	}
+
+	habits, err := h.Repository.AllHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all habits: %w", err))
+		return
+	}
+	var in []*repository.DynamoRepositoryCreateCheckInput
+	var results []*bulkCheckResult
+	for _, habit := range habits {
+		if !slices.Contains(hids, habit.ID) {
+			continue
+		}
+		value := 0.0
+		if v := r.PostFormValue("value." + habit.ID); v != "" {
+			value, err = parseQuantity(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid value of %q: %s", habit.Title, err), http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		in = append(in, &repository.DynamoRepositoryCreateCheckInput{
+			UserID:  uid,
+			HabitID: habit.ID,
+			Date:    date,
+			Value:   value,
+		})
+		results = append(results, &bulkCheckResult{Habit: habit})
+	}
+	if len(in) != len(hids) {
+		h.handleError(w, r, fmt.Errorf("some of habits %v: %w", hids, apperrors.ErrNotFound))
+		return
+	}
+
+	failed := false
+	_, errs := h.Repository.CreateChecks(ctx, in)
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			results[i].Result = "Checked"
+			continue
+		case errors.Is(err, apperrors.ErrConflict):
+			results[i].Result = "Already checked"
+		case errors.Is(err, apperrors.ErrArchived):
+			results[i].Result = "Archived"
+		case errors.Is(err, apperrors.ErrNotFound):
+			results[i].Result = "Deleted"
+		case errors.Is(err, apperrors.ErrValueRequired):
+			results[i].Result = "Value required"
+		default:
+			slog.ErrorContext(ctx, fmt.Errorf("create a check of habit [%s]: %w", in[i].HabitID, err).Error())
+			results[i].Result = "Failed. Try again later."
+		}
+		failed = true
+	}
+	if !failed {
+		h.redirect(w, "/")
+		return
+	}
+
+	h.writePage(w, r, http.StatusOK, TemplatePageChecks, map[string]interface{}{
+		"Date":    date,
+		"Results": results,
+	})
+}
+
+// maxBackfillDays is the maximum number of days which can be backfilled at once.
+const maxBackfillDays = 366
+
+// backfillChecks checks the habit on the dates in a range which are not checked yet, optionally only on some weekdays.
+func (h *HTTPHandler) backfillChecks(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := h.extractHabitID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	layout := "2006-01-02"
+	from, err := time.Parse(layout, r.PostFormValue("from"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Start date format must be %q", layout), http.StatusUnprocessableEntity)
+		return
+	}
+	to, err := time.Parse(layout, r.PostFormValue("to"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("End date format must be %q", layout), http.StatusUnprocessableEntity)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, "End date must not be before start date", http.StatusUnprocessableEntity)
+		return
+	}
+	if to.Sub(from) >= maxBackfillDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf("At most %d days can be backfilled at once", maxBackfillDays), http.StatusUnprocessableEntity)
+		return
+	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	if to.Format(layout) > today.Format(layout) {
+		http.Error(w, "Check date must not be in the future", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var weekdays []time.Weekday
+	for _, v := range r.PostForm["weekdays"] {
+		wd, err := strconv.Atoi(v)
+		if err != nil || wd < 0 || wd > 6 {
+			http.Error(w, fmt.Sprintf("Invalid weekday %q", v), http.StatusUnprocessableEntity)
+			return
+		}
+		weekdays = append(weekdays, time.Weekday(wd))
+	}
+
+	value := 0.0
+	if v := r.PostFormValue("value"); v != "" {
+		value, err = parseQuantity(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid value: %s", err), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	if _, err := h.Repository.BackfillChecks(ctx, &repository.DynamoRepositoryBackfillChecksInput{
+		UserID:   uid,
+		HabitID:  hid,
+		From:     from.Format(layout),
+		To:       to.Format(layout),
+		Weekdays: weekdays,
+		Value:    value,
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("backfill checks: %w", err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/habits/%s", hid))
+	w.WriteHeader(http.StatusSeeOther)
 }
 
 func (h *HTTPHandler) deleteCheck(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +277,12 @@ func (h *HTTPHandler) deleteCheck(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	uid := auth.MustGetUserID(ctx)
+	layout := "2006-01-02"
 	date := r.PostFormValue("date")
+	if _, err := time.Parse(layout, date); err != nil {
+		http.Error(w, fmt.Sprintf("Check date format must be %q", layout), http.StatusUnprocessableEntity)
+		return
+	}
 
 	err := h.Repository.DeleteCheck(ctx, uid, hid, date)
 	if err != nil {
@@ -49,6 +290,53 @@ func (h *HTTPHandler) deleteCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("Location", redirectLocation(r, fmt.Sprintf("/habits/%s", hid)))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+func (h *HTTPHandler) updateCheckNote(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := h.extractHabitID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	layout := "2006-01-02"
+	date := r.PostFormValue("date")
+	if _, err := time.Parse(layout, date); err != nil {
+		http.Error(w, fmt.Sprintf("Check date format must be %q", layout), http.StatusUnprocessableEntity)
+		return
+	}
+	note, err := parseNote(r.PostFormValue("note"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid note: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.Repository.UpdateCheckNote(ctx, &repository.DynamoRepositoryUpdateCheckNoteInput{
+		UserID:  auth.MustGetUserID(ctx),
+		HabitID: hid,
+		Date:    date,
+		Note:    note,
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("update a check note: %w", err))
+		return
+	}
+
 	w.Header().Set("Location", fmt.Sprintf("/habits/%s", hid))
 	w.WriteHeader(http.StatusSeeOther)
 }
+
+// maxNoteLength is the maximum number of characters of a check note.
+const maxNoteLength = 500
+
+// parseNote normalizes the line breaks of a note submitted from a textarea and validates its length.
+func parseNote(s string) (string, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if utf8.RuneCountInString(s) > maxNoteLength {
+		return "", fmt.Errorf("note length must be less than %d", maxNoteLength)
+	}
+	return s, nil
+}
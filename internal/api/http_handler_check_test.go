@@ -0,0 +1,380 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHTTPHandler_createCheck(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+	hid := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	tests := []struct {
+		name       string
+		timeZone   string
+		form       url.Values
+		wantInput  *repository.DynamoRepositoryCreateCheckInput
+		repoErr    error
+		wantStatus int
+	}{
+		{
+			name:       "without value",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01"},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "with value",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}, "value": {"2.5"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01", Value: 2.5},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "with note",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}, "note": {" Went well.\r\nSlept early. "}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01", Note: "Went well.\nSlept early."},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "too long note",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}, "note": {strings.Repeat("a", maxNoteLength+1)}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "negative value",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}, "value": {"-1"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown habit",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01"},
+			repoErr:    apperrors.ErrNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "archived habit",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01"},
+			repoErr:    apperrors.ErrArchived,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "checked twice",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-01"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-01"},
+			repoErr:    apperrors.ErrConflict,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "today in the user's time zone",
+			timeZone:   "Asia/Tokyo",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-02"}},
+			wantInput:  &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: hid, Date: "2021-01-02"},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "future date",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-02"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "future date in the user's time zone",
+			timeZone:   "Asia/Tokyo",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021-01-03"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid date",
+			form:       url.Values{"habit_id": {hid}, "date": {"2021/01/01"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			repo := NewMockDynamoRepository(ctrl)
+			repo.EXPECT().FindProfile(gomock.Any(), uid).AnyTimes().Return(&repository.DynamoProfile{UserID: uid, TimeZone: tt.timeZone}, nil)
+			if tt.wantInput != nil {
+				repo.EXPECT().CreateCheck(gomock.Any(), tt.wantInput).Times(1).Return(&repository.DynamoCheck{}, tt.repoErr)
+			}
+
+			h := NewHTTPHandler(&NewHTTPHandlerInput{
+				AuthMiddleware: noopMiddleware,
+				CSRFMiddleware: noopMiddleware,
+				Repository:     repo,
+			})
+			// 2021-01-02 05:00 in Asia/Tokyo.
+			h.now = func() time.Time { return time.Date(2021, 1, 1, 20, 0, 0, 0, time.UTC) }
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/checks", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r = r.WithContext(ctx)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHTTPHandler_updateCheckNote(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+	hid := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	repo := NewMockDynamoRepository(ctrl)
+	repo.EXPECT().UpdateCheckNote(gomock.Any(), &repository.DynamoRepositoryUpdateCheckNoteInput{
+		UserID:  uid,
+		HabitID: hid,
+		Date:    "2021-01-01",
+		Note:    "Edited",
+	}).Times(1).Return(nil)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+
+	form := url.Values{"_method": {"PUT"}, "date": {"2021-01-01"}, "note": {"Edited"}}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/habits/"+hid+"/checks", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = r.WithContext(ctx)
+	h.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	require.Equal(t, "/habits/"+hid, w.Result().Header.Get("Location"))
+}
+
+func TestHTTPHandler_invalidCheckDate(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+	hid := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	// The repository is not called with a malformed date.
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     NewMockDynamoRepository(ctrl),
+	})
+
+	for _, method := range []string{"DELETE", "PUT"} {
+		form := url.Values{"_method": {method}, "date": {"1"}, "note": {"Edited"}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/habits/"+hid+"/checks", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode, method)
+	}
+}
+
+func TestHTTPHandler_createCheck_MemoryRepository(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	repo := repository.NewMemoryRepository()
+	habit, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Read"})
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	post := func(path string, form url.Values) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+		return w.Result().StatusCode
+	}
+
+	form := url.Values{"habit_id": {habit.ID}, "date": {"2021-01-03"}}
+	require.Equal(t, http.StatusFound, post("/checks", form))
+	require.Equal(t, http.StatusConflict, post("/checks", form))
+	form.Set("habit_id", "52fdfc07-2182-454f-963f-5f0f9a621d72")
+	require.Equal(t, http.StatusNotFound, post("/checks", form))
+
+	got, err := repo.FindHabit(ctx, uid, habit.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, got.ChecksCount)
+
+	del := url.Values{"_method": {"DELETE"}, "date": {"2021-01-03"}}
+	require.Equal(t, http.StatusSeeOther, post("/habits/"+habit.ID+"/checks", del))
+	require.Equal(t, http.StatusNotFound, post("/habits/"+habit.ID+"/checks", del))
+}
+
+func TestHTTPHandler_createChecks(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	setup := func(t *testing.T) (*HTTPHandler, *repository.MemoryRepository, []*repository.DynamoHabit) {
+		repo := repository.NewMemoryRepository()
+		var habits []*repository.DynamoHabit
+		for _, in := range []*repository.DynamoRepositoryCreateHabitInput{
+			{UserID: uid, Title: "Read"},
+			{UserID: uid, Title: "Run", Unit: "km", Target: 5},
+		} {
+			habit, err := repo.CreateHabit(ctx, in)
+			require.NoError(t, err)
+			habits = append(habits, habit)
+		}
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Repository:     repo,
+		})
+		h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+		return h, repo, habits
+	}
+	post := func(h *HTTPHandler, form url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/bulk-checks", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	t.Run("all checked", func(t *testing.T) {
+		t.Parallel()
+		h, repo, habits := setup(t)
+
+		w := post(h, url.Values{
+			"habit_id":              {habits[0].ID, habits[1].ID},
+			"value." + habits[1].ID: {"3.5"},
+		})
+		require.Equal(t, http.StatusFound, w.Code)
+		require.Equal(t, "/", w.Header().Get("Location"))
+
+		checks, err := repo.ListChecksBetweenInAllHabits(ctx, uid, "2021-01-03", "2021-01-03")
+		require.NoError(t, err)
+		require.Len(t, checks, 2)
+		got, err := repo.FindHabit(ctx, uid, habits[1].ID)
+		require.NoError(t, err)
+		require.Equal(t, 3.5, got.TotalValue)
+	})
+
+	t.Run("already checked", func(t *testing.T) {
+		t.Parallel()
+		h, repo, habits := setup(t)
+		_, err := repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: habits[0].ID, Date: "2021-01-02"})
+		require.NoError(t, err)
+
+		w := post(h, url.Values{"habit_id": {habits[0].ID, habits[1].ID}, "date": {"2021-01-02"}, "value." + habits[1].ID: {"2"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		require.Regexp(t, `Read\s*</a></td>\s*<td>Already checked</td>`, body)
+		require.Regexp(t, `Run\s*</a></td>\s*<td>Checked</td>`, body)
+
+		got, err := repo.FindHabit(ctx, uid, habits[1].ID)
+		require.NoError(t, err)
+		require.Equal(t, "2021-01-02", got.LastCheckDate)
+	})
+
+	t.Run("value required", func(t *testing.T) {
+		t.Parallel()
+		h, repo, habits := setup(t)
+
+		w := post(h, url.Values{"habit_id": {habits[0].ID, habits[1].ID}})
+		require.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		require.Regexp(t, `Read\s*</a></td>\s*<td>Checked</td>`, body)
+		require.Regexp(t, `Run\s*</a></td>\s*<td>Value required</td>`, body)
+
+		got, err := repo.FindHabit(ctx, uid, habits[1].ID)
+		require.NoError(t, err)
+		require.Zero(t, got.ChecksCount)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		h, _, habits := setup(t)
+
+		require.Equal(t, http.StatusUnprocessableEntity, post(h, url.Values{}).Code)
+		require.Equal(t, http.StatusUnprocessableEntity, post(h, url.Values{"habit_id": {habits[0].ID}, "date": {"2021-01-04"}}).Code)
+		require.Equal(t, http.StatusUnprocessableEntity, post(h, url.Values{"habit_id": {habits[1].ID}, "value." + habits[1].ID: {"-1"}}).Code)
+		require.Equal(t, http.StatusNotFound, post(h, url.Values{"habit_id": {habits[0].ID, "unknown"}}).Code)
+	})
+}
+
+func TestHTTPHandler_backfillChecks(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	repo := repository.NewMemoryRepository()
+	habit, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Read"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: habit.ID, Date: "2020-12-30"})
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	post := func(form url.Values) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", fmt.Sprintf("/habits/%s/backfill", habit.ID), strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusSeeOther, post(url.Values{"from": {"2020-12-27"}, "to": {"2021-01-03"}, "weekdays": {"1", "3", "5"}}))
+	checks, err := repo.ListChecksBetween(ctx, uid, habit.ID, "", "")
+	require.NoError(t, err)
+	var dates []string
+	for _, c := range checks {
+		dates = append(dates, c.Date)
+	}
+	require.Equal(t, []string{"2020-12-28", "2020-12-30", "2021-01-01"}, dates)
+
+	require.Equal(t, http.StatusSeeOther, post(url.Values{"from": {"2020-12-27"}, "to": {"2021-01-03"}}))
+	got, err := repo.FindHabit(ctx, uid, habit.ID)
+	require.NoError(t, err)
+	require.Equal(t, 8, got.ChecksCount)
+
+	require.Equal(t, http.StatusUnprocessableEntity, post(url.Values{"from": {"2021-01-03"}, "to": {"2021-01-04"}}))
+	require.Equal(t, http.StatusUnprocessableEntity, post(url.Values{"from": {"2021-01-03"}, "to": {"2021-01-02"}}))
+	require.Equal(t, http.StatusUnprocessableEntity, post(url.Values{"from": {"2019-01-01"}, "to": {"2021-01-02"}}))
+	require.Equal(t, http.StatusUnprocessableEntity, post(url.Values{"from": {"2021-01-01"}, "to": {"2021-01-02"}, "weekdays": {"7"}}))
+}
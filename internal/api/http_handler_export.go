@@ -0,0 +1,216 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// ExportVersion is the version of the schema of the JSON export.
+// It is incremented when a field is removed or its meaning is changed, but not when a field is added.
+//
+// Version 1 is a document of the form:
+//
+//	{
+//	  "version": 1,
+//	  "exported_at": "2021-01-03T09:00:00Z",
+//	  "habits": [
+//	    {
+//	      "id": "UUID of the habit",
+//	      "title": "Running",
+//	      "archived": false,
+//	      "schedule": {"kind": "daily|weekdays|times_per_week|interval", "weekdays": [1, 3], "times_per_week": 0, "interval_days": 0},
+//	      "unit": "km",
+//	      "target": 5,
+//	      "created_at": "2021-01-01T00:00:00Z",
+//	      "checks": [
+//	        {"date": "2021-01-02", "value": 3, "note": "text", "created_at": "2021-01-02T00:00:00Z"}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// weekdays are numbered from 0 (Sunday) to 6 (Saturday), and checks are in ascending order of the date.
+// unit, target, value and note are empty or zero if they are not set.
+// They are exported as they are stored, unlike the text cells of the CSV export, which are escaped by csvText.
+const ExportVersion = 1
+
+type exportHabit struct {
+	ID        string        `json:"id"`
+	Title     string        `json:"title"`
+	Archived  bool          `json:"archived"`
+	Schedule  jsonSchedule  `json:"schedule"`
+	Unit      string        `json:"unit"`
+	Target    float64       `json:"target"`
+	CreatedAt time.Time     `json:"created_at"`
+	Checks    []exportCheck `json:"checks"`
+}
+
+// jsonSchedule is the JSON representation of a schedule, shared by the export and the JSON API.
+type jsonSchedule struct {
+	Kind         schedule.Kind  `json:"kind"`
+	Weekdays     []time.Weekday `json:"weekdays"`
+	TimesPerWeek int            `json:"times_per_week"`
+	IntervalDays int            `json:"interval_days"`
+}
+
+func newJSONSchedule(s schedule.Schedule) jsonSchedule {
+	js := jsonSchedule{
+		Kind:         s.Kind,
+		Weekdays:     s.Weekdays,
+		TimesPerWeek: s.TimesPerWeek,
+		IntervalDays: s.IntervalDays,
+	}
+	if js.Kind == "" {
+		js.Kind = schedule.KindDaily
+	}
+	if js.Weekdays == nil {
+		js.Weekdays = []time.Weekday{}
+	}
+	return js
+}
+
+type exportCheck struct {
+	Date      string    `json:"date"`
+	Value     float64   `json:"value"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportCSVHeader is the header of the CSV export, which has a row for each check.
+// The text cells, habit_title, unit and note, are escaped by csvText.
+var exportCSVHeader = []string{"habit_id", "habit_title", "archived", "unit", "date", "value", "note"}
+
+// csvText escapes a text cell of the CSV export, so that a spreadsheet does not evaluate it as a formula.
+// A cell starting with "=", "+", "-", "@", a tab or a carriage return is prefixed with "'".
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// exportData writes all habits and checks of the user as JSON or CSV, selected by the query "format".
+// It is streamed habit by habit, so that the whole data is never held in memory.
+func (h *HTTPHandler) exportData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, `Format must be "json" or "csv"`, http.StatusBadRequest)
+		return
+	}
+
+	habits, err := h.Repository.AllHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all habits: %w", err))
+		return
+	}
+	archivedHabits, err := h.Repository.AllArchivedHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all archived habits: %w", err))
+		return
+	}
+	all := slices.Concat(habits, archivedHabits)
+
+	now := h.now()
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="habit-tracker-%s.%s"`, now.Format("2006-01-02"), format))
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	}
+
+	write := h.exportJSON
+	if format == "csv" {
+		write = h.exportCSV
+	}
+	// The status is already written when an error occurs, so the error is only logged and the body is left broken.
+	if err := write(w, r, now, all, len(habits)); err != nil {
+		slog.ErrorContext(ctx, fmt.Errorf("export %s: %w", format, err).Error())
+	}
+}
+
+// exportJSON writes the habits in the JSON schema of ExportVersion. The habits from nActive are archived.
+func (h *HTTPHandler) exportJSON(w http.ResponseWriter, r *http.Request, now time.Time, habits []*repository.DynamoHabit, nActive int) error {
+	if _, err := fmt.Fprintf(w, `{"version":%d,"exported_at":%s,"habits":[`, ExportVersion, strconv.Quote(now.UTC().Format(time.RFC3339))); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	for i, habit := range habits {
+		checks, err := h.Repository.ListChecksBetween(r.Context(), habit.UserID, habit.ID, "", "")
+		if err != nil {
+			return fmt.Errorf("list checks of habit [%s]: %w", habit.ID, err)
+		}
+
+		eh := exportHabit{
+			ID:        habit.ID,
+			Title:     habit.Title,
+			Archived:  i >= nActive,
+			Schedule:  newJSONSchedule(habit.Schedule),
+			Unit:      habit.Unit,
+			Target:    habit.Target,
+			CreatedAt: habit.CreatedAt.UTC(),
+			Checks:    make([]exportCheck, 0, len(checks)),
+		}
+		for _, c := range checks {
+			eh.Checks = append(eh.Checks, exportCheck{Date: c.Date, Value: c.Value, Note: c.Note, CreatedAt: c.CreatedAt.UTC()})
+		}
+
+		b, err := json.Marshal(eh)
+		if err != nil {
+			return fmt.Errorf("marshal habit: %w", err)
+		}
+		if i > 0 {
+			b = append([]byte(","), b...)
+		}
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("write habit: %w", err)
+		}
+	}
+	if _, err := fmt.Fprint(w, "]}\n"); err != nil {
+		return fmt.Errorf("write footer: %w", err)
+	}
+	return nil
+}
+
+// exportCSV writes a row for each check of the habits. The habits from nActive are archived.
+func (h *HTTPHandler) exportCSV(w http.ResponseWriter, r *http.Request, _ time.Time, habits []*repository.DynamoHabit, nActive int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	for i, habit := range habits {
+		checks, err := h.Repository.ListChecksBetween(r.Context(), habit.UserID, habit.ID, "", "")
+		if err != nil {
+			return fmt.Errorf("list checks of habit [%s]: %w", habit.ID, err)
+		}
+		archived := strconv.FormatBool(i >= nActive)
+		for _, c := range checks {
+			if err := cw.Write([]string{
+				habit.ID, csvText(habit.Title), archived, csvText(habit.Unit), c.Date, strconv.FormatFloat(c.Value, 'f', -1, 64), csvText(c.Note),
+			}); err != nil {
+				return fmt.Errorf("write check: %w", err)
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
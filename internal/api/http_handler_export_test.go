@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_exportData(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	repo := repository.NewMemoryRepository()
+	running, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running", Unit: "km", Target: 5})
+	require.NoError(t, err)
+	reading, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Read"})
+	require.NoError(t, err)
+	for _, in := range []*repository.DynamoRepositoryCreateCheckInput{
+		{UserID: uid, HabitID: running.ID, Date: "2021-01-02", Value: 3.5, Note: "Felt good, \"fast\""},
+		{UserID: uid, HabitID: running.ID, Date: "2021-01-01", Value: 2},
+		{UserID: uid, HabitID: reading.ID, Date: "2021-01-01", Note: "=HYPERLINK(\"https://example.com\")"},
+	} {
+		_, err := repo.CreateCheck(ctx, in)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.ArchiveHabit(ctx, uid, reading.ID))
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	get := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/export?format="+format, nil)
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := get("json")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `attachment; filename="habit-tracker-2021-01-03.json"`, w.Header().Get("Content-Disposition"))
+
+		var doc struct {
+			Version    int           `json:"version"`
+			ExportedAt time.Time     `json:"exported_at"`
+			Habits     []exportHabit `json:"habits"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		require.Equal(t, ExportVersion, doc.Version)
+		require.True(t, doc.ExportedAt.Equal(h.now()))
+		require.Len(t, doc.Habits, 2)
+
+		require.Equal(t, running.ID, doc.Habits[0].ID)
+		require.False(t, doc.Habits[0].Archived)
+		require.Equal(t, "km", doc.Habits[0].Unit)
+		require.Equal(t, 5.0, doc.Habits[0].Target)
+		require.Len(t, doc.Habits[0].Checks, 2)
+		require.Equal(t, "2021-01-01", doc.Habits[0].Checks[0].Date)
+		require.Equal(t, "2021-01-02", doc.Habits[0].Checks[1].Date)
+		require.Equal(t, 3.5, doc.Habits[0].Checks[1].Value)
+
+		require.Equal(t, reading.ID, doc.Habits[1].ID)
+		require.True(t, doc.Habits[1].Archived)
+		require.Len(t, doc.Habits[1].Checks, 1)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := get("csv")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `attachment; filename="habit-tracker-2021-01-03.csv"`, w.Header().Get("Content-Disposition"))
+
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			exportCSVHeader,
+			{running.ID, "Running", "false", "km", "2021-01-01", "2", ""},
+			{running.ID, "Running", "false", "km", "2021-01-02", "3.5", `Felt good, "fast"`},
+			{reading.ID, "Read", "true", "", "2021-01-01", "0", `'=HYPERLINK("https://example.com")`},
+		}, rows)
+	})
+
+	t.Run("csv text", func(t *testing.T) {
+		for in, want := range map[string]string{
+			"":         "",
+			"Running":  "Running",
+			"=1+1":     "'=1+1",
+			"+1":       "'+1",
+			"-1":       "'-1",
+			"@SUM(A1)": "'@SUM(A1)",
+			"\tx":      "'\tx",
+			"\rx":      "'\rx",
+			"a=1":      "a=1",
+		} {
+			require.Equal(t, want, csvText(in), in)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, get("xml").Code)
+	})
+}
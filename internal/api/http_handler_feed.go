@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// feedDays is the number of past days whose checks are in the calendar feed.
+const feedDays = 90
+
+// hashToken returns the hash of a secret token to store, so that a leaked table does not leak the tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createFeedToken creates a new secret URL of the calendar feed, which revokes the previous one.
+// The URL is shown only once, because only the hash of the token is stored.
+func (h *HTTPHandler) createFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		h.handleError(w, r, fmt.Errorf("generate feed token: %w", err))
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := h.Repository.PutFeedToken(ctx, &repository.DynamoRepositoryPutFeedTokenInput{
+		UserID:    uid,
+		TokenHash: hashToken(token),
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("put feed token: %w", err))
+		return
+	}
+
+	scheme := "http"
+	if h.Secure {
+		scheme = "https"
+	}
+	h.writePage(w, r, http.StatusOK, TemplatePageFeed, map[string]interface{}{
+		"URL": fmt.Sprintf("%s://%s/feeds/%s/%s.ics", scheme, r.Host, uid, token),
+	})
+}
+
+// deleteFeedToken revokes the secret URL of the calendar feed.
+func (h *HTTPHandler) deleteFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := h.Repository.DeleteFeedToken(ctx, auth.MustGetUserID(ctx)); err != nil {
+		h.handleError(w, r, fmt.Errorf("delete feed token: %w", err))
+		return
+	}
+	h.redirect(w, "/")
+}
+
+// showCalendarFeed serves the iCalendar feed of the user: the checks of the past days as all-day events,
+// and the habits which are due today as to-dos.
+// It is outside AuthMiddleware, because calendar apps fetch it without the session cookie,
+// so the secret token in the URL authenticates the request instead.
+func (h *HTTPHandler) showCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := auth.UserID(chi.URLParam(r, URLParamUserID))
+
+	stored, err := h.Repository.FindFeedToken(ctx, uid)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("find feed token: %w", err))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(chi.URLParam(r, URLParamFeedToken))), []byte(stored.TokenHash)) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	habits, err := h.Repository.AllHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all habits: %w", err))
+		return
+	}
+	archivedHabits, err := h.Repository.AllArchivedHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all archived habits: %w", err))
+		return
+	}
+	checks, err := h.Repository.ListChecksBetweenInAllHabits(ctx, uid, today.AddDate(0, 0, -feedDays).Format("2006-01-02"), "")
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list checks in all habits: %w", err))
+		return
+	}
+
+	byID := make(map[string]*repository.DynamoHabit)
+	for _, habit := range append(habits, archivedHabits...) {
+		byID[habit.ID] = habit
+	}
+	now := icalTime(h.now())
+
+	var cal icalWriter
+	cal.prop("BEGIN", "VCALENDAR")
+	cal.prop("VERSION", "2.0")
+	cal.prop("PRODID", "-//habit-tracker-app//EN")
+	cal.prop("CALSCALE", "GREGORIAN")
+	cal.prop("METHOD", "PUBLISH")
+	cal.prop("X-WR-CALNAME", "Habits")
+
+	for _, c := range checks {
+		habit, ok := byID[c.HabitID]
+		if !ok {
+			continue
+		}
+		summary := "✓ " + habit.Title
+		if c.Value > 0 {
+			summary += fmt.Sprintf(" (%s %s)", formatQuantity(c.Value), habit.Unit)
+		}
+		date, err := time.Parse("2006-01-02", c.Date)
+		if err != nil {
+			h.handleError(w, r, fmt.Errorf("parse check date: %w", err))
+			return
+		}
+		cal.prop("BEGIN", "VEVENT")
+		cal.prop("UID", fmt.Sprintf("check-%s-%s@habit-tracker-app", c.HabitID, c.Date))
+		cal.prop("DTSTAMP", icalTime(c.UpdatedAt))
+		cal.prop("DTSTART;VALUE=DATE", icalDate(c.Date))
+		cal.prop("DTEND;VALUE=DATE", icalDate(date.AddDate(0, 0, 1).Format("2006-01-02")))
+		cal.prop("SUMMARY", icalText(summary))
+		if c.Note != "" {
+			cal.prop("DESCRIPTION", icalText(c.Note))
+		}
+		cal.prop("TRANSP", "TRANSPARENT")
+		cal.prop("END", "VEVENT")
+	}
+
+	date := today.Format("2006-01-02")
+	for _, habit := range habits {
+		var habitChecks []*repository.DynamoCheck
+		for _, c := range checks {
+			if c.HabitID == habit.ID {
+				habitChecks = append(habitChecks, c)
+			}
+		}
+		eval := schedule.NewEvaluator(habit.Schedule, habit.CreatedAt.In(today.Location()), today, scheduleChecks(habit, habitChecks))
+		if eval.Current() != schedule.StatusDue {
+			continue
+		}
+		cal.prop("BEGIN", "VTODO")
+		cal.prop("UID", fmt.Sprintf("due-%s-%s@habit-tracker-app", habit.ID, date))
+		cal.prop("DTSTAMP", now)
+		cal.prop("DTSTART;VALUE=DATE", icalDate(date))
+		cal.prop("DUE;VALUE=DATE", icalDate(today.AddDate(0, 0, 1).Format("2006-01-02")))
+		cal.prop("SUMMARY", icalText(habit.Title))
+		cal.prop("STATUS", "NEEDS-ACTION")
+		cal.prop("END", "VTODO")
+	}
+	cal.prop("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	if _, err := w.Write([]byte(cal.String())); err != nil {
+		h.handleError(w, r, fmt.Errorf("write feed: %w", err))
+	}
+}
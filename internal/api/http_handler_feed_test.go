@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_showCalendarFeed(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	// The habits are created now, so the feed is served now to make them due.
+	now := time.Now().UTC()
+	yesterday := now.AddDate(0, 0, -1)
+	date := func(t time.Time, layout string) string { return t.Format(layout) }
+
+	repo := repository.NewMemoryRepository()
+	running, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running", Unit: "km"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: running.ID, Date: date(yesterday, "2006-01-02"), Value: 5, Note: "Windy, cold; fun"})
+	require.NoError(t, err)
+	_, err = repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Read"})
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+		Secure:         true,
+	})
+	h.now = func() time.Time { return now }
+
+	send := func(method, target string, form url.Values, withUser bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if withUser {
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// The feed is disabled until a token is created.
+	require.Equal(t, http.StatusNotFound, send("GET", "/feeds/123/unknown.ics", nil, false).Code)
+
+	w := send("POST", "/feed-token", nil, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	m := regexp.MustCompile(`value="https://example.com(/feeds/123/[A-Za-z0-9_-]+\.ics)"`).FindStringSubmatch(w.Body.String())
+	require.NotNil(t, m)
+	feedPath := html.UnescapeString(m[1])
+
+	w = send("GET", feedPath, nil, false)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	require.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	require.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	require.Contains(t, body, "BEGIN:VEVENT\r\nUID:check-"+running.ID+"-"+date(yesterday, "2006-01-02")+"@habit-tracker-app\r\n")
+	require.Contains(t, body, "DTSTART;VALUE=DATE:"+date(yesterday, "20060102")+"\r\nDTEND;VALUE=DATE:"+date(now, "20060102")+"\r\nSUMMARY:✓ Running (5 km)\r\nDESCRIPTION:Windy\\, cold\\; fun\r\n")
+	// Both habits are daily and not checked today.
+	require.Equal(t, 2, strings.Count(body, "BEGIN:VTODO"))
+	require.Contains(t, body, "DTSTART;VALUE=DATE:"+date(now, "20060102")+"\r\nDUE;VALUE=DATE:"+date(now.AddDate(0, 0, 1), "20060102")+"\r\nSUMMARY:Read\r\nSTATUS:NEEDS-ACTION\r\n")
+
+	require.Equal(t, http.StatusNotFound, send("GET", "/feeds/123/wrong.ics", nil, false).Code)
+	require.Equal(t, http.StatusNotFound, send("GET", strings.Replace(feedPath, "/123/", "/456/", 1), nil, false).Code)
+
+	// Creating a new token revokes the previous one.
+	require.Equal(t, http.StatusOK, send("POST", "/feed-token", nil, true).Code)
+	require.Equal(t, http.StatusNotFound, send("GET", feedPath, nil, false).Code)
+
+	require.Equal(t, http.StatusFound, send("POST", "/feed-token", url.Values{"_method": {"DELETE"}}, true).Code)
+	_, err = repo.FindFeedToken(ctx, uid)
+	require.Error(t, err)
+}
+
+func TestIcalWriter(t *testing.T) {
+	t.Parallel()
+
+	var w icalWriter
+	w.prop("SUMMARY", icalText(strings.Repeat("あ", 30)+"\n"))
+	got := w.String()
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), icalLineOctets)
+	}
+	require.Equal(t, "SUMMARY:"+strings.Repeat("あ", 30)+`\n`, strings.ReplaceAll(strings.TrimSuffix(got, "\r\n"), "\r\n ", ""))
+}
@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 	"unicode/utf8"
 
 	"github.com/gorilla/csrf"
 	"github.com/hareku/habit-tracker-app/internal/auth"
 	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
 )
 
 func (h *HTTPHandler) showHabitPage(w http.ResponseWriter, r *http.Request) {
@@ -30,26 +35,212 @@ func (h *HTTPHandler) showHabitPage(w http.ResponseWriter, r *http.Request) {
 		h.handleError(w, r, fmt.Errorf("find a habit: %w", err))
 		return
 	}
-	checks, err := h.Repository.ListLatestChecksWithLimit(ctx, uid, hid, 7)
+	cur, err := h.parseHistoryQuery(r, hid)
 	if err != nil {
-		h.handleError(w, r, fmt.Errorf("list latest checks: %w", err))
+		http.Error(w, fmt.Sprintf("Invalid history query: %s", err), http.StatusBadRequest)
+		return
+	}
+	history, err := h.checkHistory(ctx, uid, cur)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	notesCur := notesCursor{HabitID: hid}
+	if s := r.URL.Query().Get("notes"); s != "" {
+		if err := h.cursors.decode(s, &notesCur); err != nil || notesCur.HabitID != hid {
+			http.Error(w, "Invalid notes cursor", http.StatusBadRequest)
+			return
+		}
+	}
+	notes, err := h.checkNotes(ctx, uid, notesCur)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	// The checks of the heatmap cover more than the days which the schedule needs to be evaluated.
+	checks, err := h.Repository.ListChecksBetween(ctx, uid, hid, heatmapStart(today).Format("2006-01-02"), today.Format("2006-01-02"))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list checks of heatmap: %w", err))
 		return
 	}
 
+	reminderTimes, err := h.reminderTimes(ctx, uid, hid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	eval := schedule.NewEvaluator(habit.Schedule, habit.CreatedAt.In(today.Location()), today, scheduleChecks(habit, checks))
+	values := make(map[string]float64, len(checks))
+	for _, c := range checks {
+		values[c.Date] = c.Value
+	}
+	streak := 0
+	if eval.StreakAlive(habit.LastCheckDate) {
+		streak = habit.CurrentStreak
+	}
+
 	h.writePage(w, r, http.StatusOK, TemplatePageHabit, map[string]interface{}{
 		"CSRFHiddenInput": csrf.TemplateField(r),
 		"User":            userRec.UserInfo,
 		"Habit":           habit,
-		"Checks":          checks,
-		"NextCheckDate": func() string {
-			if len(checks) == 0 {
-				return ""
-			}
+		"History":         history,
+		"Heatmap":         newHeatmap(habit, checks, today),
+		"Days":            eval.Days(7),
+		"Values":          values,
+		"WeekTotal":       weekTotal(checks, eval.Today),
+		"Streak":          streak,
+		"Notes":           notes,
+		"MaxNoteLength":   maxNoteLength,
+		"ScheduleForm":    newScheduleForm(habit.Schedule),
+		"Today":           today.Format("2006-01-02"),
+		"NextCheckDate":   nextCheckDate(checks, today),
+		"ReminderTimes":   reminderTimes,
+		"TimeZone":        today.Location().String(),
+	})
+}
+
+// historyPageSize is the number of checks in a page of the check history.
+const historyPageSize = 20
+
+// checkHistoryPage is a page of the check history, from the latest check.
+type checkHistoryPage struct {
+	Checks []*repository.DynamoCheck
+	From   string
+	To     string
+	// Older and Newer are the cursors of the adjacent pages, which are empty if there is no such page.
+	Older string
+	Newer string
+}
+
+// parseHistoryQuery returns the position of the page of the check history which the query string points to.
+// The query string has either a cursor of another page, or the range of the dates to list from the latest.
+func (h *HTTPHandler) parseHistoryQuery(r *http.Request, hid string) (historyCursor, error) {
+	cur := historyCursor{HabitID: hid}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		if err := h.cursors.decode(s, &cur); err != nil {
+			return historyCursor{}, err
+		}
+		if cur.HabitID != hid {
+			return historyCursor{}, fmt.Errorf("cursor of another habit")
+		}
+		return cur, nil
+	}
+
+	cur.From = r.URL.Query().Get("from")
+	cur.To = r.URL.Query().Get("to")
+	for _, d := range []string{cur.From, cur.To} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			return historyCursor{}, fmt.Errorf("invalid date %q", d)
+		}
+	}
+	return cur, nil
+}
 
-			latest, _ := time.Parse("2006-01-02", checks[0].Date)
-			return latest.Add(24 * time.Hour).Format("2006-01-02")
-		}(),
+// checkHistory loads the page of the check history at cur.
+func (h *HTTPHandler) checkHistory(ctx context.Context, uid auth.UserID, cur historyCursor) (*checkHistoryPage, error) {
+	out, err := h.Repository.ListChecks(ctx, &repository.DynamoRepositoryListChecksInput{
+		UserID:    uid,
+		HabitID:   cur.HabitID,
+		From:      cur.From,
+		To:        cur.To,
+		After:     cur.After,
+		Ascending: cur.Ascending,
+		// One more check tells whether there is a next page, so that no link leads to an empty page.
+		Limit: historyPageSize + 1,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("list checks: %w", err)
+	}
+
+	hasMore := len(out.Checks) > historyPageSize
+	page := &checkHistoryPage{Checks: out.Checks[:min(len(out.Checks), historyPageSize)], From: cur.From, To: cur.To}
+	if cur.Ascending {
+		slices.Reverse(page.Checks)
+	}
+	if len(page.Checks) == 0 {
+		return page, nil
+	}
+
+	// A page listed in one direction has more pages in the other direction if it started after a date.
+	hasOlder, hasNewer := hasMore, cur.After != ""
+	if cur.Ascending {
+		hasOlder, hasNewer = hasNewer, hasOlder
+	}
+	if hasOlder {
+		if page.Older, err = h.encodeHistoryCursor(cur, page.Checks[len(page.Checks)-1].Date, false); err != nil {
+			return nil, err
+		}
+	}
+	if hasNewer {
+		if page.Newer, err = h.encodeHistoryCursor(cur, page.Checks[0].Date, true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func (h *HTTPHandler) encodeHistoryCursor(cur historyCursor, after string, ascending bool) (string, error) {
+	cur.After = after
+	cur.Ascending = ascending
+	return h.cursors.encode(cur)
+}
+
+// notesPageSize is the number of notes in a page of the notes.
+const notesPageSize = 10
+
+// checkNotesPage is a page of the notes, from the latest check.
+type checkNotesPage struct {
+	Checks []*repository.DynamoCheck
+	// Paged is whether the page is not the latest one.
+	Paged bool
+	// Older is the cursor of the next page, which is empty if there are no more notes.
+	Older string
+}
+
+// checkNotes loads the page of the notes at cur.
+func (h *HTTPHandler) checkNotes(ctx context.Context, uid auth.UserID, cur notesCursor) (*checkNotesPage, error) {
+	out, err := h.Repository.ListCheckNotes(ctx, &repository.DynamoRepositoryListCheckNotesInput{
+		UserID:  uid,
+		HabitID: cur.HabitID,
+		Before:  cur.Before,
+		Limit:   notesPageSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list check notes: %w", err)
+	}
+
+	page := &checkNotesPage{Checks: out.Checks, Paged: cur.Before != ""}
+	if out.LastEvaluatedDate != "" {
+		cur.Before = out.LastEvaluatedDate
+		if page.Older, err = h.cursors.encode(cur); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// nextCheckDate returns the default date of a new check, which is the day after the latest check.
+// checks must be in ascending order of the date.
+// It is never later than today, since a future date can not be checked.
+func nextCheckDate(checks []*repository.DynamoCheck, today time.Time) string {
+	end := today.Format("2006-01-02")
+	if len(checks) == 0 {
+		return end
+	}
+
+	latest, err := time.Parse("2006-01-02", checks[len(checks)-1].Date)
+	if err != nil {
+		return end
+	}
+	return min(latest.AddDate(0, 0, 1).Format("2006-01-02"), end)
 }
 
 func (h *HTTPHandler) createHabit(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +254,25 @@ func (h *HTTPHandler) createHabit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	habit, err := h.Repository.CreateHabit(ctx, uid, title)
+	sched, err := parseScheduleForm(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid schedule: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	unit, target, err := parseQuantityForm(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid quantity: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	habit, err := h.Repository.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{
+		UserID:   uid,
+		Title:    title,
+		Schedule: sched,
+		Unit:     unit,
+		Target:   target,
+	})
 	if err != nil {
 		h.handleError(w, r, fmt.Errorf("create a habit: %w", err))
 		return
@@ -88,6 +297,25 @@ func (h *HTTPHandler) updateHabit(w http.ResponseWriter, r *http.Request) {
 	}
 	in.Title = title
 
+	sched, err := parseScheduleForm(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid schedule: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+	in.Schedule = sched
+
+	in.Unit, in.Target, err = parseQuantityForm(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid quantity: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	in.Version, err = strconv.Atoi(r.PostFormValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusUnprocessableEntity)
+		return
+	}
+
 	if err := h.Repository.UpdateHabit(ctx, &in); err != nil {
 		h.handleError(w, r, fmt.Errorf("update a habit: %w", err))
 		return
@@ -109,3 +337,134 @@ func (h *HTTPHandler) deleteHabit(w http.ResponseWriter, r *http.Request) {
 
 	h.redirect(w, "/")
 }
+
+// parseScheduleForm parses the schedule fields rendered by the "schedule_fields" template.
+func parseScheduleForm(r *http.Request) (schedule.Schedule, error) {
+	if err := r.ParseForm(); err != nil {
+		return schedule.Schedule{}, fmt.Errorf("parse form: %w", err)
+	}
+
+	s := schedule.Schedule{Kind: schedule.Kind(r.PostFormValue("schedule_kind"))}
+	switch s.Kind {
+	case "":
+		s.Kind = schedule.KindDaily
+	case schedule.KindWeekdays:
+		for _, v := range r.PostForm["schedule_weekdays"] {
+			wd, err := strconv.Atoi(v)
+			if err != nil {
+				return schedule.Schedule{}, fmt.Errorf("parse weekday %q: %w", v, err)
+			}
+			s.Weekdays = append(s.Weekdays, time.Weekday(wd))
+		}
+		slices.Sort(s.Weekdays)
+	case schedule.KindTimesPerWeek:
+		n, err := strconv.Atoi(r.PostFormValue("schedule_times_per_week"))
+		if err != nil {
+			return schedule.Schedule{}, fmt.Errorf("parse times per week: %w", err)
+		}
+		s.TimesPerWeek = n
+	case schedule.KindInterval:
+		n, err := strconv.Atoi(r.PostFormValue("schedule_interval_days"))
+		if err != nil {
+			return schedule.Schedule{}, fmt.Errorf("parse interval days: %w", err)
+		}
+		s.IntervalDays = n
+	}
+
+	if err := s.Validate(); err != nil {
+		return schedule.Schedule{}, err
+	}
+	return s, nil
+}
+
+// parseQuantityForm parses the unit and the daily target of a quantitative habit.
+// Both are optional, and a habit without them is checked without a value.
+func parseQuantityForm(r *http.Request) (string, float64, error) {
+	unit := r.PostFormValue("unit")
+	if utf8.RuneCountInString(unit) > 20 {
+		return "", 0, fmt.Errorf("unit length must be less than 20")
+	}
+
+	target := 0.0
+	if v := r.PostFormValue("target"); v != "" {
+		var err error
+		target, err = parseQuantity(v)
+		if err != nil {
+			return "", 0, fmt.Errorf("target %w", err)
+		}
+	}
+	return unit, target, nil
+}
+
+// parseQuantity parses a positive quantity such as a target or a value of a check.
+func parseQuantity(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if math.IsNaN(v) || v <= 0 || v > maxQuantity {
+		return 0, fmt.Errorf("must be between 0 and %d", maxQuantity)
+	}
+	return v, nil
+}
+
+const maxQuantity = 1_000_000
+
+// scheduleChecks converts checks to evaluate them against the schedule and the target of the habit.
+func scheduleChecks(habit *repository.DynamoHabit, checks []*repository.DynamoCheck) []schedule.Check {
+	res := make([]schedule.Check, 0, len(checks))
+	for _, c := range checks {
+		res = append(res, schedule.Check{Date: c.Date, Ratio: schedule.Ratio(c.Value, habit.Target)})
+	}
+	return res
+}
+
+// weekTotal returns the sum of the values of checks in the week (Monday to Sunday) of today.
+func weekTotal(checks []*repository.DynamoCheck, today time.Time) float64 {
+	start := schedule.WeekStart(today).Format("2006-01-02")
+	end := today.Format("2006-01-02")
+	total := 0.0
+	for _, c := range checks {
+		if c.Date >= start && c.Date <= end {
+			total += c.Value
+		}
+	}
+	return total
+}
+
+type scheduleForm struct {
+	Kind         schedule.Kind
+	Weekdays     []scheduleFormWeekday
+	TimesPerWeek int
+	IntervalDays int
+	MaxInterval  int
+}
+
+type scheduleFormWeekday struct {
+	Value   int
+	Name    string
+	Checked bool
+}
+
+// newScheduleForm returns the data of the "schedule_fields" template filled with s.
+func newScheduleForm(s schedule.Schedule) *scheduleForm {
+	f := &scheduleForm{
+		Kind:         s.Kind,
+		TimesPerWeek: max(s.TimesPerWeek, 1),
+		IntervalDays: max(s.IntervalDays, 2),
+		MaxInterval:  schedule.MaxIntervalDays,
+	}
+	if f.Kind == "" {
+		f.Kind = schedule.KindDaily
+	}
+	// List weekdays from Monday.
+	for i := range 7 {
+		wd := time.Weekday((i + 1) % 7)
+		f.Weekdays = append(f.Weekdays, scheduleFormWeekday{
+			Value:   int(wd),
+			Name:    wd.String()[:3],
+			Checked: slices.Contains(s.Weekdays, wd),
+		})
+	}
+	return f
+}
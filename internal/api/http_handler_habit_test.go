@@ -3,13 +3,20 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	firebase "firebase.google.com/go/auth"
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
 	"github.com/hareku/habit-tracker-app/internal/repository"
 	"github.com/hareku/habit-tracker-app/internal/repository/repositorytest"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
 	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -33,12 +40,60 @@ func TestHTTPHandler_showHabitPage(t *testing.T) {
 	repo := NewMockDynamoRepository(ctrl)
 
 	seeder := repositorytest.NewSeeder()
-	habit := seeder.SeedHabit(uid, nil)
+	habit := seeder.SeedHabit(uid, func(h *repository.DynamoHabit) {
+		h.ChecksCount = 2
+		h.CurrentStreak = 2
+		h.LongestStreak = 5
+		h.LastCheckDate = "2021-01-02"
+		h.Unit = "km"
+		h.Target = 5
+		h.TotalValue = 8
+		h.Version = 3
+	})
 
 	repo.EXPECT().FindHabit(gomock.Any(), uid, habit.ID).Times(1).Return(habit, nil)
-	repo.EXPECT().ListLatestChecksWithLimit(gomock.Any(), uid, habit.ID, int32(7)).Times(1).Return([]*repository.DynamoCheck{
-		seeder.SeedCheck(uid, habit.ID, "2021-01-01", nil),
-		seeder.SeedCheck(uid, habit.ID, "2021-01-02", nil),
+	repo.EXPECT().FindProfile(gomock.Any(), uid).Times(1).Return(&repository.DynamoProfile{UserID: uid, TimeZone: "Asia/Tokyo"}, nil)
+	repo.EXPECT().ListChecksBetween(gomock.Any(), uid, habit.ID, "2019-12-30", "2021-01-03").Times(1).Return([]*repository.DynamoCheck{
+		seeder.SeedCheck(uid, habit.ID, "2020-06-01", func(c *repository.DynamoCheck) {
+			c.Value = 2
+		}),
+		seeder.SeedCheck(uid, habit.ID, "2021-01-01", func(c *repository.DynamoCheck) {
+			c.Value = 5
+		}),
+		seeder.SeedCheck(uid, habit.ID, "2021-01-02", func(c *repository.DynamoCheck) {
+			c.Value = 3
+		}),
+	}, nil)
+	repo.EXPECT().ListChecks(gomock.Any(), &repository.DynamoRepositoryListChecksInput{
+		UserID:  uid,
+		HabitID: habit.ID,
+		Limit:   historyPageSize + 1,
+	}).Times(1).Return(&repository.DynamoRepositoryListChecksOutput{
+		Checks: []*repository.DynamoCheck{
+			seeder.SeedCheck(uid, habit.ID, "2021-01-02", func(c *repository.DynamoCheck) {
+				c.Value = 3
+			}),
+			seeder.SeedCheck(uid, habit.ID, "2021-01-01", func(c *repository.DynamoCheck) {
+				c.Value = 5
+			}),
+		},
+	}, nil)
+	repo.EXPECT().ListCheckNotes(gomock.Any(), &repository.DynamoRepositoryListCheckNotesInput{
+		UserID:  uid,
+		HabitID: habit.ID,
+		Limit:   notesPageSize,
+	}).Times(1).Return(&repository.DynamoRepositoryListCheckNotesOutput{
+		Checks: []*repository.DynamoCheck{
+			seeder.SeedCheck(uid, habit.ID, "2021-01-01", func(c *repository.DynamoCheck) {
+				c.Value = 5
+				c.Note = "Felt great.\n<script>alert(1)</script>"
+			}),
+		},
+	}, nil)
+	repo.EXPECT().FindReminder(gomock.Any(), uid, habit.ID).Times(1).Return(&repository.DynamoReminder{
+		UserID:  uid,
+		HabitID: habit.ID,
+		Times:   []string{"07:30", "21:00"},
 	}, nil)
 
 	h := NewHTTPHandler(&NewHTTPHandlerInput{
@@ -47,6 +102,7 @@ func TestHTTPHandler_showHabitPage(t *testing.T) {
 		Authenticator:  authn,
 		Repository:     repo,
 	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", fmt.Sprintf("/habits/%s", habit.ID), nil)
@@ -56,3 +112,336 @@ func TestHTTPHandler_showHabitPage(t *testing.T) {
 	require.Equal(t, 200, w.Result().StatusCode)
 	snapshotHTML(t, w.Result().Body)
 }
+
+func TestHTTPHandler_showHabitPage_History(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	newHandler := func(t *testing.T) (*HTTPHandler, string) {
+		ctrl := gomock.NewController(t)
+		authn := NewMockAuthenticator(ctrl)
+		authn.EXPECT().GetUser(gomock.Any(), uid).AnyTimes().
+			Return(&firebase.UserRecord{UserInfo: &firebase.UserInfo{UID: uid.String()}}, nil)
+
+		repo := repository.NewMemoryRepository()
+		habit, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running"})
+		require.NoError(t, err)
+		start := time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)
+		for i := range 45 {
+			in := &repository.DynamoRepositoryCreateCheckInput{
+				UserID:  uid,
+				HabitID: habit.ID,
+				Date:    start.AddDate(0, 0, i).Format("2006-01-02"),
+			}
+			if i%2 == 0 {
+				in.Note = "note"
+			}
+			_, err := repo.CreateCheck(ctx, in)
+			require.NoError(t, err)
+		}
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Authenticator:  authn,
+			Repository:     repo,
+			CursorKey:      []byte("secret"),
+		})
+		h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+		return h, habit.ID
+	}
+
+	get := func(t *testing.T, h *HTTPHandler, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil).WithContext(ctx))
+		return w
+	}
+
+	t.Run("older and newer", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+
+		cur, err := h.checkHistory(ctx, uid, historyCursor{HabitID: hid})
+		require.NoError(t, err)
+		require.Len(t, cur.Checks, historyPageSize)
+		require.Equal(t, "2020-12-15", cur.Checks[0].Date)
+		require.Empty(t, cur.Newer)
+		require.NotEmpty(t, cur.Older)
+
+		var pages [][]*repository.DynamoCheck
+		for cur.Older != "" {
+			w := get(t, h, fmt.Sprintf("/habits/%s?cursor=%s", hid, cur.Older))
+			require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+			var c historyCursor
+			require.NoError(t, h.cursors.decode(cur.Older, &c))
+			cur, err = h.checkHistory(ctx, uid, c)
+			require.NoError(t, err)
+			pages = append(pages, cur.Checks)
+		}
+		require.Len(t, pages, 2)
+		require.Equal(t, "2020-11-25", pages[0][0].Date)
+		require.Len(t, pages[1], 5)
+		require.Equal(t, "2020-11-01", pages[1][4].Date)
+
+		var c historyCursor
+		require.NoError(t, h.cursors.decode(cur.Newer, &c))
+		cur, err = h.checkHistory(ctx, uid, c)
+		require.NoError(t, err)
+		require.Equal(t, pages[0], cur.Checks)
+		require.NotEmpty(t, cur.Newer)
+		require.NotEmpty(t, cur.Older)
+	})
+
+	t.Run("exact multiple of the page size", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+		for _, d := range []string{"2020-11-01", "2020-11-02", "2020-11-03", "2020-11-04", "2020-11-05"} {
+			require.NoError(t, h.Repository.DeleteCheck(ctx, uid, hid, d))
+		}
+
+		cur, err := h.checkHistory(ctx, uid, historyCursor{HabitID: hid})
+		require.NoError(t, err)
+		var c historyCursor
+		require.NoError(t, h.cursors.decode(cur.Older, &c))
+		cur, err = h.checkHistory(ctx, uid, c)
+		require.NoError(t, err)
+		require.Len(t, cur.Checks, historyPageSize)
+		require.Equal(t, "2020-11-06", cur.Checks[historyPageSize-1].Date)
+		require.Empty(t, cur.Older)
+		require.NotEmpty(t, cur.Newer)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+
+		w := get(t, h, fmt.Sprintf("/habits/%s?from=2020-11-03&to=2020-11-04", hid))
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		body := w.Body.String()
+		require.Contains(t, body, `<td>2020-11-04</td>`)
+		require.Contains(t, body, `<td>2020-11-03</td>`)
+		require.NotContains(t, body, `<td>2020-11-05</td>`)
+		require.NotContains(t, body, `<td>2020-11-02</td>`)
+	})
+
+	t.Run("tampered cursor", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+
+		cur, err := h.cursors.encode(historyCursor{HabitID: hid, After: "2020-12-01"})
+		require.NoError(t, err)
+		forged, err := newCursorCodec([]byte("another secret")).encode(historyCursor{HabitID: hid, After: "2020-11-01"})
+		require.NoError(t, err)
+		payload, _, _ := strings.Cut(forged, ".")
+		_, sig, _ := strings.Cut(cur, ".")
+
+		w := get(t, h, fmt.Sprintf("/habits/%s?cursor=%s.%s", hid, payload, sig))
+		require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+
+		cur, err := h.checkNotes(ctx, uid, notesCursor{HabitID: hid})
+		require.NoError(t, err)
+		require.Len(t, cur.Checks, notesPageSize)
+		require.Equal(t, "2020-12-15", cur.Checks[0].Date)
+		require.False(t, cur.Paged)
+
+		var pages [][]*repository.DynamoCheck
+		for cur.Older != "" {
+			w := get(t, h, fmt.Sprintf("/habits/%s?notes=%s", hid, cur.Older))
+			require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+			var c notesCursor
+			require.NoError(t, h.cursors.decode(cur.Older, &c))
+			cur, err = h.checkNotes(ctx, uid, c)
+			require.NoError(t, err)
+			require.True(t, cur.Paged)
+			pages = append(pages, cur.Checks)
+		}
+		require.Len(t, pages, 2)
+		require.Equal(t, "2020-11-25", pages[0][0].Date)
+		require.Len(t, pages[1], 3)
+		require.Equal(t, "2020-11-01", pages[1][2].Date)
+
+		other, err := h.cursors.encode(notesCursor{HabitID: uuid.NewString(), Before: "2020-12-01"})
+		require.NoError(t, err)
+		w := get(t, h, fmt.Sprintf("/habits/%s?notes=%s", hid, other))
+		require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("cursor of another habit", func(t *testing.T) {
+		t.Parallel()
+		h, hid := newHandler(t)
+
+		cur, err := h.cursors.encode(historyCursor{HabitID: uuid.NewString()})
+		require.NoError(t, err)
+		w := get(t, h, fmt.Sprintf("/habits/%s?cursor=%s", hid, cur))
+		require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func TestHTTPHandler_createHabit(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	t.Run("weekdays schedule", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+
+		repo := NewMockDynamoRepository(ctrl)
+		habit := repositorytest.NewSeeder().SeedHabit(uid, nil)
+		repo.EXPECT().CreateHabit(gomock.Any(), &repository.DynamoRepositoryCreateHabitInput{
+			UserID: uid,
+			Title:  "Language class",
+			Schedule: schedule.Schedule{
+				Kind:     schedule.KindWeekdays,
+				Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday},
+			},
+		}).Times(1).Return(habit, nil)
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Repository:     repo,
+		})
+
+		form := url.Values{
+			"title":             {"Language class"},
+			"schedule_kind":     {"weekdays"},
+			"schedule_weekdays": {"5", "1", "3"},
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/habits", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusFound, w.Result().StatusCode)
+		require.Equal(t, fmt.Sprintf("/habits/%s", habit.ID), w.Result().Header.Get("Location"))
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+
+		h := NewHTTPHandler(&NewHTTPHandlerInput{
+			AuthMiddleware: noopMiddleware,
+			CSRFMiddleware: noopMiddleware,
+			Repository:     NewMockDynamoRepository(ctrl),
+		})
+
+		form := url.Values{
+			"title":                   {"Gym"},
+			"schedule_kind":           {"times_per_week"},
+			"schedule_times_per_week": {"8"},
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/habits", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(ctx)
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	})
+}
+
+func TestHTTPHandler_updateHabit(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+	hid := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantInput  *repository.DynamoRepositoryUpdateHabitInput
+		repoErr    error
+		wantStatus int
+	}{
+		{
+			name:       "updated",
+			form:       url.Values{"habit_id": {hid}, "version": {"3"}, "title": {"Read"}},
+			wantInput:  &repository.DynamoRepositoryUpdateHabitInput{UserID: uid, HabitID: hid, Version: 3, Title: "Read", Schedule: schedule.Daily()},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "stale version",
+			form:       url.Values{"habit_id": {hid}, "version": {"2"}, "title": {"Read"}},
+			wantInput:  &repository.DynamoRepositoryUpdateHabitInput{UserID: uid, HabitID: hid, Version: 2, Title: "Read", Schedule: schedule.Daily()},
+			repoErr:    apperrors.ErrConflict,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "missing version",
+			form:       url.Values{"habit_id": {hid}, "title": {"Read"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			repo := NewMockDynamoRepository(ctrl)
+			if tt.wantInput != nil {
+				repo.EXPECT().UpdateHabit(gomock.Any(), tt.wantInput).Times(1).Return(tt.repoErr)
+			}
+
+			h := NewHTTPHandler(&NewHTTPHandlerInput{
+				AuthMiddleware: noopMiddleware,
+				CSRFMiddleware: noopMiddleware,
+				Repository:     repo,
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/update-habit", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r = r.WithContext(ctx)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestNewHeatmap(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	checks := []*repository.DynamoCheck{{Date: "2021-01-02", Value: 2}, {Date: "2021-01-03", Value: 4}}
+	cell := func(hm *heatmap, date string) heatmapCell {
+		t.Helper()
+		for _, c := range hm.Cells {
+			if strings.HasPrefix(c.Title, date) {
+				return c
+			}
+		}
+		t.Fatalf("no cell of %s", date)
+		return heatmapCell{}
+	}
+
+	// A habit with a target but no unit shows the values of its checks.
+	hm := newHeatmap(&repository.DynamoHabit{Target: 4}, checks, today)
+	require.Equal(t, "2021-01-02: 2", cell(hm, "2021-01-02").Title)
+	require.Equal(t, heatmapColors[2], cell(hm, "2021-01-02").Color)
+	require.Equal(t, "2021-01-03: 4", cell(hm, "2021-01-03").Title)
+	require.Equal(t, heatmapColors[4], cell(hm, "2021-01-03").Color)
+
+	hm = newHeatmap(&repository.DynamoHabit{Unit: "km"}, checks, today)
+	require.Equal(t, "2021-01-02: 2 km", cell(hm, "2021-01-02").Title)
+	require.Equal(t, heatmapColors[2], cell(hm, "2021-01-02").Color)
+
+	hm = newHeatmap(&repository.DynamoHabit{}, checks, today)
+	require.Equal(t, "2021-01-02: checked", cell(hm, "2021-01-02").Title)
+	require.Equal(t, heatmapColors[4], cell(hm, "2021-01-02").Color)
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/importer"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+const (
+	// maxImportBytes is the maximum size of an imported file.
+	maxImportBytes = 10 << 20
+	// maxImportRecords is the maximum number of checks which can be imported at once.
+	// The commit writes them in a request, so that with maxImportHabits it makes at most
+	// 40 transactions of repository.ImportChunkSize checks, well within the timeout of the function.
+	maxImportRecords = 2_000
+	// maxImportHabits is the maximum number of habits which can be imported at once.
+	maxImportHabits = 20
+)
+
+// importPlan is what importing the records of a habit does.
+type importPlan struct {
+	Title string
+	Unit  string
+	// Habit is the active habit of the same title, or nil if a habit is created.
+	Habit *repository.DynamoHabit
+	// Quantitative is true if the checks need positive values, since the habit has a unit or a target.
+	Quantitative bool
+	// Archived is true if an archived habit has the same title. Its records are not imported.
+	Archived bool
+	// Checks are the checks to create.
+	Checks []*repository.DynamoRepositoryImportCheck
+	// Skipped is the number of the records which are already checked or duplicated in the file.
+	Skipped int
+}
+
+func (h *HTTPHandler) showImportPage(w http.ResponseWriter, r *http.Request) {
+	h.writePage(w, r, http.StatusOK, TemplatePageImport, map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+	})
+}
+
+// previewImport parses the uploaded file and shows what importing it does, without writing anything.
+// The preview page posts the parsed records to commitImport.
+func (h *HTTPHandler) previewImport(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	// The body is limited by limitRequestBody, since the form is parsed by the middlewares before this handler.
+	f, fh, err := r.FormFile("file")
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		http.Error(w, fmt.Sprintf("File must be smaller than %d MB", maxImportBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Select a file to import", http.StatusUnprocessableEntity)
+		return
+	}
+	defer f.Close()
+	if fh.Size > maxImportBytes {
+		http.Error(w, fmt.Sprintf("File must be smaller than %d MB", maxImportBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxImportBytes))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("read uploaded file: %w", err))
+		return
+	}
+
+	format, records, err := importer.Parse(ctx, data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid file: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+	plans, ok := h.planImport(w, r, uid, records)
+	if !ok {
+		return
+	}
+
+	encoded, err := json.Marshal(records)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("marshal records: %w", err))
+		return
+	}
+	h.writePage(w, r, http.StatusOK, TemplatePageImport, map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+		"Format":          format,
+		"Plans":           plans,
+		"Records":         string(encoded),
+	})
+}
+
+// commitImport creates the habits and checks of the records posted from the preview page.
+// The records are planned again, so the checks created after the preview are skipped too.
+func (h *HTTPHandler) commitImport(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	var records []*importer.Record
+	if err := json.Unmarshal([]byte(r.PostFormValue("records")), &records); err != nil {
+		http.Error(w, "Invalid records", http.StatusBadRequest)
+		return
+	}
+	plans, ok := h.planImport(w, r, uid, records)
+	if !ok {
+		return
+	}
+
+	for _, p := range plans {
+		if p.Archived || len(p.Checks) == 0 {
+			continue
+		}
+		if p.Habit == nil {
+			habit, err := h.Repository.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{
+				UserID: uid,
+				Title:  p.Title,
+				Unit:   p.Unit,
+			})
+			if err != nil {
+				h.handleError(w, r, fmt.Errorf("create habit %q: %w", p.Title, err))
+				return
+			}
+			p.Habit = habit
+		}
+		if _, err := h.Repository.ImportChecks(ctx, &repository.DynamoRepositoryImportChecksInput{
+			UserID:  uid,
+			HabitID: p.Habit.ID,
+			Checks:  p.Checks,
+		}); err != nil {
+			h.handleError(w, r, fmt.Errorf("import checks of habit [%s]: %w", p.Habit.ID, err))
+			return
+		}
+	}
+
+	w.Header().Set("Location", "/")
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// planImport validates the records and groups them by the habit title in the order of appearance.
+// If the records are invalid, it writes an error response and returns false.
+func (h *HTTPHandler) planImport(w http.ResponseWriter, r *http.Request, uid auth.UserID, records []*importer.Record) ([]*importPlan, bool) {
+	ctx := r.Context()
+
+	if len(records) == 0 {
+		http.Error(w, "No check to import", http.StatusUnprocessableEntity)
+		return nil, false
+	}
+	if len(records) > maxImportRecords {
+		http.Error(w, fmt.Sprintf("At most %d checks can be imported at once", maxImportRecords), http.StatusUnprocessableEntity)
+		return nil, false
+	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return nil, false
+	}
+	layout := "2006-01-02"
+	titles := make(map[string]bool)
+	for _, rec := range records {
+		titles[rec.Habit] = true
+		if cnt := utf8.RuneCountInString(rec.Habit); cnt == 0 || cnt > 50 {
+			http.Error(w, fmt.Sprintf("Habit title length must be less than 50: %q", rec.Habit), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+		if utf8.RuneCountInString(rec.Unit) > 20 {
+			http.Error(w, fmt.Sprintf("Unit length must be less than 20: %q", rec.Unit), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+		if _, err := time.Parse(layout, rec.Date); err != nil {
+			http.Error(w, fmt.Sprintf("Check date format must be %q: %q", layout, rec.Date), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+		if rec.Date > today.Format(layout) {
+			http.Error(w, fmt.Sprintf("Check date must not be in the future: %s of %q", rec.Date, rec.Habit), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+		if math.IsNaN(rec.Value) || rec.Value < 0 || rec.Value > maxQuantity {
+			http.Error(w, fmt.Sprintf("Value must be between 0 and %d: %s of %q", maxQuantity, rec.Date, rec.Habit), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+	}
+
+	if len(titles) > maxImportHabits {
+		http.Error(w, fmt.Sprintf("At most %d habits can be imported at once", maxImportHabits), http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	habits, err := h.Repository.AllHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all habits: %w", err))
+		return nil, false
+	}
+	archivedHabits, err := h.Repository.AllArchivedHabits(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("all archived habits: %w", err))
+		return nil, false
+	}
+
+	var plans []*importPlan
+	dates := make(map[*importPlan]map[string]bool)
+	for _, rec := range records {
+		i := slices.IndexFunc(plans, func(p *importPlan) bool { return p.Title == rec.Habit })
+		if i < 0 {
+			p := &importPlan{Title: rec.Habit, Unit: rec.Unit, Quantitative: rec.Unit != ""}
+			dates[p] = make(map[string]bool)
+			if j := slices.IndexFunc(habits, func(hb *repository.DynamoHabit) bool { return hb.Title == rec.Habit }); j >= 0 {
+				p.Habit = habits[j]
+				p.Quantitative = p.Habit.Quantitative()
+				checks, err := h.Repository.ListChecksBetween(ctx, uid, p.Habit.ID, "", "")
+				if err != nil {
+					h.handleError(w, r, fmt.Errorf("list checks of habit [%s]: %w", p.Habit.ID, err))
+					return nil, false
+				}
+				for _, c := range checks {
+					dates[p][c.Date] = true
+				}
+			} else if slices.ContainsFunc(archivedHabits, func(hb *repository.DynamoHabit) bool { return hb.Title == rec.Habit }) {
+				p.Archived = true
+			}
+			plans = append(plans, p)
+			i = len(plans) - 1
+		}
+
+		p := plans[i]
+		if p.Archived || dates[p][rec.Date] {
+			p.Skipped++
+			continue
+		}
+		if p.Quantitative && rec.Value <= 0 {
+			http.Error(w, fmt.Sprintf("Value must be positive for a habit with a unit or a target: %s of %q", rec.Date, rec.Habit), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+		dates[p][rec.Date] = true
+		p.Checks = append(p.Checks, &repository.DynamoRepositoryImportCheck{Date: rec.Date, Value: rec.Value})
+	}
+	return plans, true
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_importChecks(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	repo := repository.NewMemoryRepository()
+	running, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running", Unit: "km"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: running.ID, Date: "2021-01-01", Value: 5})
+	require.NoError(t, err)
+	old, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Old"})
+	require.NoError(t, err)
+	require.NoError(t, repo.ArchiveHabit(ctx, uid, old.ID))
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "history.csv")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	w := upload("habit,date,value\nRunning,2021-01-01,3\nRunning,2021-01-02,4\nRead,2021-01-01\nRead,2021-01-02\nRead,2021-01-02\nOld,2021-01-01\n")
+	require.Equal(t, http.StatusOK, w.Code)
+	preview := w.Body.String()
+	require.Regexp(t, `(?s)Running.*Add to.*<td>1</td>\s*<td>1</td>`, preview)
+	require.Regexp(t, `(?s)Read.*Create a habit\s*</td>\s*<td>2</td>\s*<td>1</td>`, preview)
+	require.Regexp(t, `(?s)Old.*Skip the archived habit.*<td>0</td>\s*<td>1</td>`, preview)
+
+	// A check of a quantitative habit needs a value.
+	require.Equal(t, http.StatusUnprocessableEntity, upload("Running,2021-01-02\n").Code)
+
+	// Nothing is written by the preview.
+	habits, err := repo.AllHabits(ctx, uid)
+	require.NoError(t, err)
+	require.Len(t, habits, 1)
+
+	m := regexp.MustCompile(`name="records" value="([^"]*)"`).FindStringSubmatch(preview)
+	require.NotNil(t, m)
+	form := url.Values{"records": {html.UnescapeString(m[1])}}
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/import/commit", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.ServeHTTP(w, r.WithContext(ctx))
+	require.Equal(t, http.StatusSeeOther, w.Code)
+
+	habits, err = repo.AllHabits(ctx, uid)
+	require.NoError(t, err)
+	require.Len(t, habits, 2)
+	counts := make(map[string]int)
+	for _, habit := range habits {
+		counts[habit.Title] = habit.ChecksCount
+	}
+	require.Equal(t, map[string]int{"Running": 2, "Read": 2}, counts)
+	got, err := repo.FindHabit(ctx, uid, running.ID)
+	require.NoError(t, err)
+	require.Equal(t, 9.0, got.TotalValue)
+
+	// Importing the same file again skips every record.
+	require.Regexp(t, `(?s)Read.*Add to.*<td>0</td>\s*<td>3</td>`, upload("Read,2021-01-01\nRead,2021-01-02\nRead,2021-01-02\n").Body.String())
+
+	require.Equal(t, http.StatusUnprocessableEntity, upload("Read,2021-01-04\n").Code)
+	require.Equal(t, http.StatusUnprocessableEntity, upload("Read,01/02/2021\n").Code)
+	require.Equal(t, http.StatusUnprocessableEntity, upload("habit,date\n").Code)
+}
+
+func TestHTTPHandler_importChecks_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repository.NewMemoryRepository(),
+	})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "history.csv")
+	require.NoError(t, err)
+	_, err = fw.Write(bytes.Repeat([]byte("Read,2021-01-01\n"), maxRequestBytes/16+1))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	for name, contentLength := range map[string]int64{"known length": int64(body.Len()), "chunked": -1} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/import", bytes.NewReader(body.Bytes()))
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ContentLength = contentLength
+		h.ServeHTTP(w, r.WithContext(ctx))
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code, name)
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	t.Parallel()
+
+	// The checks of a habit are written in chunks, so the most transactions are made
+	// when every habit has one check more than a chunk.
+	perHabit := maxImportRecords / maxImportHabits
+	require.Equal(t, repository.ImportChunkSize+1, perHabit)
+	transactions := maxImportHabits * ((perHabit + repository.ImportChunkSize - 1) / repository.ImportChunkSize)
+	require.Equal(t, 40, transactions)
+}
+
+func TestHTTPHandler_importChecks_TooMany(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repository.NewMemoryRepository(),
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	records := func(habits, checks int) string {
+		var b strings.Builder
+		start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := range checks {
+			fmt.Fprintf(&b, "Habit %d,%s\n", i%habits, start.AddDate(0, 0, i/habits).Format("2006-01-02"))
+		}
+		return b.String()
+	}
+
+	for name, tc := range map[string]struct {
+		content string
+		want    int
+	}{
+		"at the limits":   {records(maxImportHabits, maxImportRecords), http.StatusOK},
+		"too many checks": {records(maxImportHabits, maxImportRecords+1), http.StatusUnprocessableEntity},
+		"too many habits": {records(maxImportHabits+1, maxImportHabits+1), http.StatusUnprocessableEntity},
+	} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "history.csv")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(tc.content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		h.ServeHTTP(w, r.WithContext(ctx))
+		require.Equal(t, tc.want, w.Code, name)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+func (h *HTTPHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	tz := r.PostFormValue("time_zone")
+	// "Local" is accepted by time.LoadLocation, but it is the zone of the server, not of the user.
+	if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+		http.Error(w, fmt.Sprintf("Unknown time zone: %q", tz), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.Repository.UpdateProfile(ctx, &repository.DynamoRepositoryUpdateProfileInput{
+		UserID:   auth.MustGetUserID(ctx),
+		TimeZone: tz,
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("update profile: %w", err))
+		return
+	}
+
+	h.redirect(w, "/")
+}
+
+// today returns the current time in the time zone of the user.
+// Every calendar date shown to or received from the user must be derived from it.
+func (h *HTTPHandler) today(ctx context.Context, uid auth.UserID) (time.Time, error) {
+	p, err := h.Repository.FindProfile(ctx, uid)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("find profile: %w", err)
+	}
+	return h.now().In(p.Location()), nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHTTPHandler_updateProfile(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	tests := []struct {
+		name       string
+		timeZone   string
+		wantInput  *repository.DynamoRepositoryUpdateProfileInput
+		wantStatus int
+	}{
+		{
+			name:       "valid",
+			timeZone:   "Asia/Tokyo",
+			wantInput:  &repository.DynamoRepositoryUpdateProfileInput{UserID: uid, TimeZone: "Asia/Tokyo"},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "unknown",
+			timeZone:   "Mars/Olympus",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "server local",
+			timeZone:   "Local",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			repo := NewMockDynamoRepository(ctrl)
+			if tt.wantInput != nil {
+				repo.EXPECT().UpdateProfile(gomock.Any(), tt.wantInput).Times(1).Return(nil)
+			}
+
+			h := NewHTTPHandler(&NewHTTPHandlerInput{
+				AuthMiddleware: noopMiddleware,
+				CSRFMiddleware: noopMiddleware,
+				Repository:     repo,
+			})
+
+			form := url.Values{"_method": {"PUT"}, "time_zone": {tt.timeZone}}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/profile", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r = r.WithContext(ctx)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/reminder"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+// maxReminderTimes is the maximum number of reminder times of a habit.
+const maxReminderTimes = 3
+
+// reminderTimes returns the reminder times of the habit, padded with empty times to maxReminderTimes
+// so that the form has an input for each.
+func (h *HTTPHandler) reminderTimes(ctx context.Context, uid auth.UserID, hid string) ([]string, error) {
+	times := make([]string, 0, maxReminderTimes)
+	rem, err := h.Repository.FindReminder(ctx, uid, hid)
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("find reminder: %w", err)
+	default:
+		times = append(times, rem.Times...)
+	}
+	for len(times) < maxReminderTimes {
+		times = append(times, "")
+	}
+	return times, nil
+}
+
+// updateReminder replaces the reminder times of the habit. Empty times are ignored, and no times turn the reminder off.
+// The times are in the time zone of the user, and reminders are sent only on the scheduled days.
+func (h *HTTPHandler) updateReminder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hid, ok := h.extractHabitID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	times, err := parseReminderTimes(r.PostForm["times"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid reminder times: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Only active habits have reminders.
+	if _, err := h.Repository.FindHabit(ctx, uid, hid); err != nil {
+		h.handleError(w, r, fmt.Errorf("find a habit: %w", err))
+		return
+	}
+	if err := h.Repository.PutReminder(ctx, &repository.DynamoRepositoryPutReminderInput{
+		UserID:  uid,
+		HabitID: hid,
+		Times:   times,
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("put reminder: %w", err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/habits/%s", hid))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// parseReminderTimes validates the times formatted as reminder.TimeLayout, and returns them sorted without
+// empty and duplicate ones.
+func parseReminderTimes(values []string) ([]string, error) {
+	var times []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(reminder.TimeLayout, v)
+		if err != nil {
+			return nil, fmt.Errorf("time %q must be formatted as %q", v, reminder.TimeLayout)
+		}
+		times = append(times, t.Format(reminder.TimeLayout))
+	}
+	slices.Sort(times)
+	times = slices.Compact(times)
+	if len(times) > maxReminderTimes {
+		return nil, fmt.Errorf("at most %d times can be set", maxReminderTimes)
+	}
+	return times, nil
+}
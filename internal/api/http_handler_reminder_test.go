@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_updateReminder(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+	repo := repository.NewMemoryRepository()
+	habit, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running"})
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Repository:     repo,
+	})
+	put := func(hid string, times ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		form := url.Values{"_method": {"PUT"}, "times": times}
+		r := httptest.NewRequest("POST", fmt.Sprintf("/habits/%s/reminder", hid), strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	w := put(habit.ID, "21:00", "", "07:30", "21:00")
+	require.Equal(t, http.StatusSeeOther, w.Code, w.Body.String())
+	require.Equal(t, "/habits/"+habit.ID, w.Header().Get("Location"))
+	rem, err := repo.FindReminder(ctx, uid, habit.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"07:30", "21:00"}, rem.Times)
+
+	require.Equal(t, http.StatusUnprocessableEntity, put(habit.ID, "25:00").Code)
+	require.Equal(t, http.StatusUnprocessableEntity, put(habit.ID, "7pm").Code)
+	require.Equal(t, http.StatusUnprocessableEntity, put(habit.ID, "06:00", "07:00", "08:00", "09:00").Code)
+	require.Equal(t, http.StatusNotFound, put("52fdfc07-2182-454f-963f-5f0f9a621d72", "07:00").Code)
+	rem, err = repo.FindReminder(ctx, uid, habit.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"07:30", "21:00"}, rem.Times)
+
+	// Clearing all times turns the reminder off.
+	require.Equal(t, http.StatusSeeOther, put(habit.ID, "", "").Code)
+	_, err = repo.FindReminder(ctx, uid, habit.ID)
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// statsDays is the number of days of the checks which the statistics are computed from.
+const statsDays = 365
+
+// statsWeeks is the number of weeks in the weekly totals chart.
+const statsWeeks = 52
+
+// completionRate is the completion rate of a period. OK is false if no day is scheduled in the period.
+type completionRate struct {
+	Days    int
+	Percent int
+	OK      bool
+}
+
+// weekdayCount is the number of checks on a weekday, and its completion rate. OK is false if the weekday is never scheduled.
+type weekdayCount struct {
+	Weekday time.Weekday
+	Count   int
+	Rate    float64
+	Percent int
+	OK      bool
+}
+
+// weeklyChart is an SVG bar chart of the weekly totals.
+type weeklyChart struct {
+	Width  int
+	Height int
+	Bars   []weeklyBar
+}
+
+type weeklyBar struct {
+	X, Y, Height int
+	Title        string
+}
+
+const (
+	weeklyChartBarWidth = 12
+	weeklyChartHeight   = 100
+)
+
+func (h *HTTPHandler) showStatsPage(w http.ResponseWriter, r *http.Request) {
+	hid, ok := h.extractHabitID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+	userRec, err := h.Authenticator.GetUser(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("get auth user: %w", err))
+		return
+	}
+
+	habit, err := h.Repository.FindHabit(ctx, uid, hid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("find a habit: %w", err))
+		return
+	}
+	today, err := h.today(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	// The checks before the window are needed to evaluate its first days.
+	start := today.AddDate(0, 0, 1-statsDays).Format("2006-01-02")
+	evalChecks, err := h.Repository.ListChecksBetween(ctx, uid, hid,
+		today.AddDate(0, 0, 1-statsDays-habit.Schedule.EvaluationDays()).Format("2006-01-02"), today.Format("2006-01-02"))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list checks: %w", err))
+		return
+	}
+	var checks []*repository.DynamoCheck
+	for _, c := range evalChecks {
+		if c.Date >= start {
+			checks = append(checks, c)
+		}
+	}
+
+	eval := schedule.NewEvaluator(habit.Schedule, habit.CreatedAt.In(today.Location()), today, scheduleChecks(habit, evalChecks))
+	var rates []completionRate
+	for _, n := range []int{7, 30, 365} {
+		rate, ok := eval.CompletionRate(n)
+		rates = append(rates, completionRate{Days: n, Percent: int(math.Round(rate * 100)), OK: ok})
+	}
+
+	weekdays := weekdayCounts(checks, eval)
+	data := map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+		"User":            userRec.UserInfo,
+		"Habit":           habit,
+		"Rates":           rates,
+		"Weekdays":        weekdays,
+		"WeeklyChart":     newWeeklyChart(habit, checks, today),
+	}
+	if best, worst := rankWeekdays(weekdays); len(checks) > 0 && best != nil {
+		data["BestWeekday"] = best
+		data["WorstWeekday"] = worst
+	}
+
+	h.writePage(w, r, http.StatusOK, TemplatePageStats, data)
+}
+
+// weekdayCounts returns the number of checks and the completion rate of the last statsDays days on each weekday,
+// from Monday to Sunday.
+func weekdayCounts(checks []*repository.DynamoCheck, eval *schedule.Evaluator) []weekdayCount {
+	rates, ok := eval.WeekdayCompletionRates(statsDays)
+	counts := make([]weekdayCount, 7)
+	for i := range counts {
+		wd := time.Weekday((i + 1) % 7)
+		counts[i] = weekdayCount{Weekday: wd, Rate: rates[wd], Percent: int(math.Round(rates[wd] * 100)), OK: ok[wd]}
+	}
+	for _, c := range checks {
+		d, err := time.Parse("2006-01-02", c.Date)
+		if err != nil {
+			continue
+		}
+		counts[(int(d.Weekday())+6)%7].Count++
+	}
+	return counts
+}
+
+// rankWeekdays returns the weekdays with the highest and the lowest completion rate, skipping the weekdays which are never scheduled.
+// The number of checks is not used, since it favors the weekdays which are scheduled more often.
+// They are nil if no weekday is scheduled.
+func rankWeekdays(weekdays []weekdayCount) (best, worst *weekdayCount) {
+	for i, c := range weekdays {
+		if !c.OK {
+			continue
+		}
+		if best == nil || c.Rate > best.Rate {
+			best = &weekdays[i]
+		}
+		if worst == nil || c.Rate < worst.Rate {
+			worst = &weekdays[i]
+		}
+	}
+	return best, worst
+}
+
+// newWeeklyChart builds the chart of the last statsWeeks weeks (Monday to Sunday) ending with the week of today.
+// A week's total is the sum of the values if the habit is quantitative, or the number of checks otherwise.
+func newWeeklyChart(habit *repository.DynamoHabit, checks []*repository.DynamoCheck, today time.Time) *weeklyChart {
+	y, m, d := schedule.WeekStart(today).AddDate(0, 0, -7*(statsWeeks-1)).Date()
+	first := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	totals := make([]float64, statsWeeks)
+	for _, c := range checks {
+		d, err := time.Parse("2006-01-02", c.Date)
+		if err != nil || d.Before(first) {
+			continue
+		}
+		i := int(d.Sub(first).Hours()/24) / 7
+		if i >= statsWeeks {
+			continue
+		}
+		if habit.Quantitative() {
+			totals[i] += c.Value
+		} else {
+			totals[i]++
+		}
+	}
+
+	maxTotal := 0.0
+	for _, t := range totals {
+		maxTotal = max(maxTotal, t)
+	}
+	chart := &weeklyChart{Width: statsWeeks * weeklyChartBarWidth, Height: weeklyChartHeight}
+	for i, t := range totals {
+		height := 0
+		if maxTotal > 0 {
+			height = int(math.Round(t / maxTotal * weeklyChartHeight))
+		}
+		title := fmt.Sprintf("Week of %s: %s", first.AddDate(0, 0, 7*i).Format("2006-01-02"), formatQuantity(t))
+		switch {
+		case habit.Unit != "":
+			title += " " + habit.Unit
+		case !habit.Quantitative():
+			title += " checks"
+		}
+		chart.Bars = append(chart.Bars, weeklyBar{
+			X:      i * weeklyChartBarWidth,
+			Y:      weeklyChartHeight - height,
+			Height: height,
+			Title:  title,
+		})
+	}
+	return chart
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/auth"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/repository/repositorytest"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHTTPHandler_showStatsPage(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	authn := NewMockAuthenticator(ctrl)
+	authn.EXPECT().GetUser(gomock.Any(), uid).Times(1).
+		Return(&firebase.UserRecord{UserInfo: &firebase.UserInfo{UID: uid.String(), DisplayName: "test"}}, nil)
+
+	repo := NewMockDynamoRepository(ctrl)
+	seeder := repositorytest.NewSeeder()
+	habit := seeder.SeedHabit(uid, func(h *repository.DynamoHabit) {
+		h.CreatedAt = time.Date(2020, 12, 20, 0, 0, 0, 0, time.UTC)
+		h.ChecksCount = 4
+		h.Unit = "km"
+		h.Target = 5
+		h.TotalValue = 17
+	})
+	repo.EXPECT().FindHabit(gomock.Any(), uid, habit.ID).Times(1).Return(habit, nil)
+	repo.EXPECT().FindProfile(gomock.Any(), uid).Times(1).Return(&repository.DynamoProfile{UserID: uid, TimeZone: "Asia/Tokyo"}, nil)
+	var checks []*repository.DynamoCheck
+	for date, v := range map[string]float64{"2020-12-21": 5, "2020-12-28": 5, "2021-01-01": 5, "2021-01-02": 2} {
+		checks = append(checks, seeder.SeedCheck(uid, habit.ID, date, func(c *repository.DynamoCheck) {
+			c.Value = v
+		}))
+	}
+	repo.EXPECT().ListChecksBetween(gomock.Any(), uid, habit.ID, "2019-12-22", "2021-01-03").Times(1).Return(checks, nil)
+
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware: noopMiddleware,
+		CSRFMiddleware: noopMiddleware,
+		Authenticator:  authn,
+		Repository:     repo,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", fmt.Sprintf("/habits/%s/stats", habit.ID), nil)
+	r = r.WithContext(ctx)
+	h.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	snapshotHTML(t, w.Result().Body)
+}
+
+func TestRankWeekdays(t *testing.T) {
+	t.Parallel()
+
+	// Monday has the most checks but is scheduled more often than Sunday, and Tuesday is never scheduled.
+	weekdays := []weekdayCount{
+		{Weekday: time.Monday, Count: 40, Rate: 0.8, OK: true},
+		{Weekday: time.Tuesday},
+		{Weekday: time.Wednesday, Count: 30, Rate: 0.6, OK: true},
+		{Weekday: time.Thursday, Count: 30, Rate: 0.6, OK: true},
+		{Weekday: time.Friday, Count: 30, Rate: 0.6, OK: true},
+		{Weekday: time.Saturday, Count: 20, Rate: 0.4, OK: true},
+		{Weekday: time.Sunday, Count: 10, Rate: 1, OK: true},
+	}
+	best, worst := rankWeekdays(weekdays)
+	require.Equal(t, time.Sunday, best.Weekday)
+	require.Equal(t, time.Saturday, worst.Weekday)
+
+	best, worst = rankWeekdays(make([]weekdayCount, 7))
+	require.Nil(t, best)
+	require.Nil(t, worst)
+}
+
+func TestNewWeeklyChart(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	checks := []*repository.DynamoCheck{{Date: "2020-12-28", Value: 2}, {Date: "2021-01-02", Value: 3}}
+	lastBar := func(chart *weeklyChart) weeklyBar {
+		return chart.Bars[len(chart.Bars)-1]
+	}
+
+	// A habit with a target but no unit sums the values of its checks.
+	chart := newWeeklyChart(&repository.DynamoHabit{Target: 4}, checks, today)
+	require.Len(t, chart.Bars, statsWeeks)
+	require.Equal(t, "Week of 2020-12-28: 5", lastBar(chart).Title)
+
+	chart = newWeeklyChart(&repository.DynamoHabit{Unit: "km"}, checks, today)
+	require.Equal(t, "Week of 2020-12-28: 5 km", lastBar(chart).Title)
+
+	chart = newWeeklyChart(&repository.DynamoHabit{}, checks, today)
+	require.Equal(t, "Week of 2020-12-28: 2 checks", lastBar(chart).Title)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gorilla/csrf"
 	"github.com/hareku/habit-tracker-app/internal/auth"
 	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
 )
 
 func (h *HTTPHandler) showTopPage(w http.ResponseWriter, r *http.Request) {
@@ -37,25 +38,48 @@ func (h *HTTPHandler) showTopPage(w http.ResponseWriter, r *http.Request) {
 	type habit2 struct {
 		*repository.DynamoHabit
 		LatestCheck *repository.DynamoCheck
+		Status      schedule.Status
+		WeekCount   float64
+		Streak      int
 	}
 	var habits2 []*habit2
 
-	checks, err := h.Repository.ListLastWeekChecksInAllHabits(ctx, uid)
+	profile, err := h.Repository.FindProfile(ctx, uid)
 	if err != nil {
-		h.handleError(w, r, fmt.Errorf("list last week checks in all habits: %w", err))
+		h.handleError(w, r, fmt.Errorf("find profile: %w", err))
+		return
+	}
+	today := h.now().In(profile.Location())
+
+	// Load the checks of as many days as the habit with the longest schedule needs.
+	days := schedule.Daily().EvaluationDays()
+	for _, habit := range habits {
+		days = max(days, habit.Schedule.EvaluationDays())
+	}
+	checks, err := h.Repository.ListChecksBetweenInAllHabits(ctx, uid, today.AddDate(0, 0, -days).Format("2006-01-02"), "")
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list checks in all habits: %w", err))
 		return
 	}
 	for _, habit := range habits {
 		h2 := &habit2{DynamoHabit: habit}
+		var habitChecks []*repository.DynamoCheck
 		for _, check := range checks {
 			if check.HabitID != habit.ID {
 				continue
 			}
+			habitChecks = append(habitChecks, check)
 
 			if h2.LatestCheck == nil || h2.LatestCheck.Date < check.Date {
 				h2.LatestCheck = check
 			}
 		}
+		eval := schedule.NewEvaluator(habit.Schedule, habit.CreatedAt.In(today.Location()), today, scheduleChecks(habit, habitChecks))
+		h2.Status = eval.Current()
+		h2.WeekCount = eval.WeekCount()
+		if eval.StreakAlive(habit.LastCheckDate) {
+			h2.Streak = habit.CurrentStreak
+		}
 		habits2 = append(habits2, h2)
 	}
 
@@ -64,5 +88,9 @@ func (h *HTTPHandler) showTopPage(w http.ResponseWriter, r *http.Request) {
 		"User":            userRec.UserInfo,
 		"Habits":          habits2,
 		"ArchivedHabits":  archivedHabits,
+		"ScheduleForm":    newScheduleForm(schedule.Daily()),
+		"NewHabit":        &repository.DynamoHabit{},
+		"Profile":         profile,
+		"Today":           today.Format("2006-01-02"),
 	})
 }
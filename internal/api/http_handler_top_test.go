@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	firebase "firebase.google.com/go/auth"
 	"github.com/hareku/habit-tracker-app/internal/auth"
@@ -40,7 +41,8 @@ func TestHTTPHandler_showTopPage(t *testing.T) {
 
 	repo.EXPECT().AllHabits(gomock.Any(), gomock.Any()).Times(1).Return(habits, nil)
 	repo.EXPECT().AllArchivedHabits(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
-	repo.EXPECT().ListLastWeekChecksInAllHabits(gomock.Any(), gomock.Any()).Times(1).Return([]*repository.DynamoCheck{
+	repo.EXPECT().FindProfile(gomock.Any(), uid).Times(1).Return(repository.NewDynamoProfile(uid), nil)
+	repo.EXPECT().ListChecksBetweenInAllHabits(gomock.Any(), uid, "2020-12-20", "").Times(1).Return([]*repository.DynamoCheck{
 		seeder.SeedCheck(uid, habits[0].ID, "2021-01-01", nil),
 	}, nil)
 
@@ -50,6 +52,7 @@ func TestHTTPHandler_showTopPage(t *testing.T) {
 		Authenticator:  authn,
 		Repository:     repo,
 	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/", nil)
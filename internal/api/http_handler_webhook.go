@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/webhook"
+)
+
+const (
+	// maxWebhooks is the maximum number of webhooks of a user.
+	maxWebhooks = 5
+	// maxWebhookURLLength is the maximum length of the URL of a webhook.
+	maxWebhookURLLength = 2000
+	// webhookDeliveriesLimit is the number of the latest deliveries shown for each webhook.
+	webhookDeliveriesLimit = 20
+)
+
+// webhookRepository publishes the events of the writes of the repository after they succeed,
+// so that the pages and the JSON API publish the same events.
+// BackfillChecks and ImportChecks are not wrapped and publish no events, since they create up to thousands of checks
+// at once, each of which would be a delivery to every webhook.
+type webhookRepository struct {
+	DynamoRepository
+	publisher WebhookPublisher
+}
+
+// webhookHabitRef is the data of the events of a habit which no longer can be read.
+type webhookHabitRef struct {
+	ID string `json:"id"`
+}
+
+// webhookCheckRef is the data of the events of a deleted check.
+type webhookCheckRef struct {
+	HabitID string `json:"habit_id"`
+	Date    string `json:"date"`
+}
+
+func (r *webhookRepository) CreateCheck(ctx context.Context, in *repository.DynamoRepositoryCreateCheckInput) (*repository.DynamoCheck, error) {
+	c, err := r.DynamoRepository.CreateCheck(ctx, in)
+	if err == nil {
+		r.publisher.Publish(ctx, in.UserID, webhook.EventCheckCreated, newAPICheck(c))
+	}
+	return c, err
+}
+
+func (r *webhookRepository) CreateChecks(ctx context.Context, in []*repository.DynamoRepositoryCreateCheckInput) ([]*repository.DynamoCheck, []error) {
+	checks, errs := r.DynamoRepository.CreateChecks(ctx, in)
+	for i, err := range errs {
+		if err == nil {
+			r.publisher.Publish(ctx, in[i].UserID, webhook.EventCheckCreated, newAPICheck(checks[i]))
+		}
+	}
+	return checks, errs
+}
+
+func (r *webhookRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error {
+	err := r.DynamoRepository.DeleteCheck(ctx, uid, hid, date)
+	if err == nil {
+		r.publisher.Publish(ctx, uid, webhook.EventCheckDeleted, &webhookCheckRef{HabitID: hid, Date: date})
+	}
+	return err
+}
+
+func (r *webhookRepository) CreateHabit(ctx context.Context, in *repository.DynamoRepositoryCreateHabitInput) (*repository.DynamoHabit, error) {
+	h, err := r.DynamoRepository.CreateHabit(ctx, in)
+	if err == nil {
+		r.publisher.Publish(ctx, in.UserID, webhook.EventHabitCreated, newAPIHabit(h, false))
+	}
+	return h, err
+}
+
+func (r *webhookRepository) ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	err := r.DynamoRepository.ArchiveHabit(ctx, uid, hid)
+	if err == nil {
+		r.publisher.Publish(ctx, uid, webhook.EventHabitArchived, &webhookHabitRef{ID: hid})
+	}
+	return err
+}
+
+func (r *webhookRepository) DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	err := r.DynamoRepository.DeleteHabit(ctx, uid, hid)
+	if err == nil {
+		r.publisher.Publish(ctx, uid, webhook.EventHabitDeleted, &webhookHabitRef{ID: hid})
+	}
+	return err
+}
+
+// webhookWithDeliveries is a webhook and its latest deliveries shown on the webhooks page.
+type webhookWithDeliveries struct {
+	*repository.DynamoWebhook
+	Deliveries []*repository.DynamoWebhookDelivery
+}
+
+func (h *HTTPHandler) showWebhooksPage(w http.ResponseWriter, r *http.Request) {
+	h.writeWebhooksPage(w, r, http.StatusOK, "")
+}
+
+// writeWebhooksPage writes the webhooks page. newSecret is shown only once right after a webhook is created.
+func (h *HTTPHandler) writeWebhooksPage(w http.ResponseWriter, r *http.Request, status int, newSecret string) {
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	webhooks, err := h.Repository.ListWebhooks(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list webhooks: %w", err))
+		return
+	}
+	slices.SortFunc(webhooks, func(a, b *repository.DynamoWebhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	items := make([]*webhookWithDeliveries, 0, len(webhooks))
+	for _, wh := range webhooks {
+		deliveries, err := h.Repository.ListWebhookDeliveries(ctx, uid, wh.ID, webhookDeliveriesLimit)
+		if err != nil {
+			h.handleError(w, r, fmt.Errorf("list deliveries of webhook [%s]: %w", wh.ID, err))
+			return
+		}
+		items = append(items, &webhookWithDeliveries{DynamoWebhook: wh, Deliveries: deliveries})
+	}
+
+	h.writePage(w, r, status, TemplatePageWebhooks, map[string]interface{}{
+		"CSRFHiddenInput": csrf.TemplateField(r),
+		"Webhooks":        items,
+		"NewSecret":       newSecret,
+		"MaxWebhooks":     maxWebhooks,
+		"CanCreate":       len(webhooks) < maxWebhooks,
+		"Events": []string{
+			webhook.EventCheckCreated,
+			webhook.EventCheckDeleted,
+			webhook.EventHabitCreated,
+			webhook.EventHabitArchived,
+			webhook.EventHabitDeleted,
+		},
+	})
+}
+
+// createWebhook registers a webhook URL with a new secret to sign the events.
+// The secret is shown only once.
+func (h *HTTPHandler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := auth.MustGetUserID(ctx)
+
+	rawURL := strings.TrimSpace(r.PostFormValue("url"))
+	if err := validateWebhookURL(rawURL); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid URL: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	webhooks, err := h.Repository.ListWebhooks(ctx, uid)
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("list webhooks: %w", err))
+		return
+	}
+	if len(webhooks) >= maxWebhooks {
+		http.Error(w, fmt.Sprintf("You can have at most %d webhooks. Delete one to create another.", maxWebhooks), http.StatusUnprocessableEntity)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		h.handleError(w, r, fmt.Errorf("generate webhook secret: %w", err))
+		return
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	if _, err := h.Repository.CreateWebhook(ctx, &repository.DynamoRepositoryCreateWebhookInput{
+		UserID: uid,
+		URL:    rawURL,
+		Secret: secret,
+	}); err != nil {
+		h.handleError(w, r, fmt.Errorf("create webhook: %w", err))
+		return
+	}
+
+	h.writeWebhooksPage(w, r, http.StatusCreated, secret)
+}
+
+// validateWebhookURL validates that the URL is an absolute HTTP or HTTPS URL.
+func validateWebhookURL(s string) error {
+	if s == "" || len(s) > maxWebhookURLLength {
+		return fmt.Errorf("must be 1 to %d characters", maxWebhookURLLength)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("host is empty")
+	}
+	if u.User != nil {
+		return fmt.Errorf("must not contain a user name or password")
+	}
+	return nil
+}
+
+// deleteWebhook deletes the webhook and its delivery log. The pending retries of its deliveries stop.
+func (h *HTTPHandler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	wid, err := uuid.Parse(chi.URLParam(r, URLParamWebhookID))
+	if err != nil {
+		h.handleError(w, r, fmt.Errorf("parse %q failed %q: %w", URLParamWebhookID, err, apperrors.ErrNotFound))
+		return
+	}
+	if err := h.Repository.DeleteWebhook(ctx, auth.MustGetUserID(ctx), wid.String()); err != nil {
+		h.handleError(w, r, fmt.Errorf("delete webhook: %w", err))
+		return
+	}
+	h.redirect(w, "/webhooks")
+}
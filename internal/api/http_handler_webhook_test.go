@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_Webhooks(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := auth.SetUserID(context.Background(), uid)
+
+	var (
+		mu     sync.Mutex
+		secret string
+		events []*webhook.Event
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		var e webhook.Event
+		if !webhook.Verify(secret, ts, body, r.Header.Get(webhook.HeaderSignature)) || json.Unmarshal(body, &e) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		events = append(events, &e)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := repository.NewMemoryRepository()
+	dispatcher := webhook.NewDispatcher(repo)
+	dispatcher.Client = receiver.Client()
+	dispatcher.Backoff = []time.Duration{time.Millisecond}
+	h := NewHTTPHandler(&NewHTTPHandlerInput{
+		AuthMiddleware:    noopMiddleware,
+		APIAuthMiddleware: noopMiddleware,
+		CSRFMiddleware:    noopMiddleware,
+		Repository:        repo,
+		Webhooks:          dispatcher,
+	})
+	h.now = func() time.Time { return time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC) }
+
+	send := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+	eventTypes := func() []string {
+		require.NoError(t, dispatcher.Wait(ctx))
+		mu.Lock()
+		defer mu.Unlock()
+		var types []string
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		events = nil
+		return types
+	}
+
+	require.Equal(t, http.StatusUnprocessableEntity, send("POST", "/webhooks", url.Values{"url": {"ftp://example.com"}}).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, send("POST", "/webhooks", url.Values{"url": {"/relative"}}).Code)
+
+	w := send("POST", "/webhooks", url.Values{"url": {receiver.URL}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	m := regexp.MustCompile(`value="([A-Za-z0-9_-]{43})"`).FindStringSubmatch(w.Body.String())
+	require.NotNil(t, m, w.Body.String())
+	mu.Lock()
+	secret = html.UnescapeString(m[1])
+	mu.Unlock()
+
+	// The pages publish events.
+	w = send("POST", "/habits", url.Values{"title": {"Running"}})
+	require.Equal(t, http.StatusFound, w.Code)
+	hid := strings.TrimPrefix(w.Header().Get("Location"), "/habits/")
+	require.Equal(t, http.StatusFound, send("POST", "/checks", url.Values{"habit_id": {hid}, "date": {"2021-01-02"}}).Code)
+	require.Equal(t, http.StatusFound, send("POST", "/bulk-checks", url.Values{"habit_id": {hid}}).Code)
+	require.Equal(t, http.StatusSeeOther, send("POST", fmt.Sprintf("/habits/%s/checks", hid), url.Values{"_method": {"DELETE"}, "date": {"2021-01-02"}}).Code)
+	require.Equal(t, []string{
+		webhook.EventHabitCreated, webhook.EventCheckCreated, webhook.EventCheckCreated, webhook.EventCheckDeleted,
+	}, sortedByPublish(eventTypes()))
+
+	// A failed write publishes nothing.
+	require.Equal(t, http.StatusConflict, send("POST", "/checks", url.Values{"habit_id": {hid}, "date": {"2021-01-03"}}).Code)
+	require.Empty(t, eventTypes())
+
+	// So does the JSON API.
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("PUT", fmt.Sprintf("/api/v1/archived-habits/%s", hid), nil)
+	h.ServeHTTP(w, r.WithContext(ctx))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, []string{webhook.EventHabitArchived}, eventTypes())
+	require.Equal(t, http.StatusFound, send("POST", "/delete-habit", url.Values{"habit_id": {hid}}).Code)
+	require.Equal(t, []string{webhook.EventHabitDeleted}, eventTypes())
+
+	// The delivery log is shown.
+	w = send("GET", "/webhooks", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), receiver.URL)
+	require.Equal(t, 6, strings.Count(w.Body.String(), "<td>succeeded</td>"))
+	require.NotContains(t, w.Body.String(), secret)
+
+	webhooks, err := repo.ListWebhooks(ctx, uid)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	w = send("DELETE", "/webhooks/"+webhooks[0].ID, nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "/webhooks", w.Header().Get("Location"))
+	require.Contains(t, send("GET", "/webhooks", nil).Body.String(), "No webhooks.")
+	require.Equal(t, http.StatusNotFound, send("DELETE", "/webhooks/unknown", nil).Code)
+}
+
+// sortedByPublish returns the event types in the order of the writes which published them.
+// The events are delivered concurrently, so they may be received in any order.
+func sortedByPublish(types []string) []string {
+	order := []string{webhook.EventHabitCreated, webhook.EventCheckCreated, webhook.EventCheckDeleted}
+	slices.SortStableFunc(types, func(a, b string) int {
+		return slices.Index(order, a) - slices.Index(order, b)
+	})
+	return types
+}
@@ -1,12 +1,36 @@
 package api
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/csrf"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
 )
 
+// maxRequestBytes is the maximum size of a request body. The largest one is an import of a file with its form fields.
+const maxRequestBytes = maxImportBytes + 1<<20
+
+// limitRequestBody rejects the requests whose bodies are larger than maxRequestBytes.
+// It must run before the middlewares which parse the forms, since a multipart form is spooled as a whole when it is parsed.
+func limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxRequestBytes {
+			http.Error(w, fmt.Sprintf("Request must be smaller than %d MB", maxRequestBytes>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func NewAuthMiddleware(authenticator *auth.FirebaseAuthenticator) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -28,6 +52,87 @@ func NewAuthMiddleware(authenticator *auth.FirebaseAuthenticator) Middleware {
 	}
 }
 
+// NewAPIAuthMiddleware authenticates the requests of the JSON API by the header "Authorization: Bearer <session>",
+// where the session is the value of the session cookie. A cookie is never used, so the API is safe from CSRF.
+func NewAPIAuthMiddleware(authenticator *auth.FirebaseAuthenticator) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", `The header "Authorization: Bearer <token>" is required.`)
+				return
+			}
+
+			ctx, err := authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "The token is invalid or expired.")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// accessTokenTouchInterval is the interval to record the last use of an access token,
+// so that a busy script does not write on every request.
+const accessTokenTouchInterval = time.Minute
+
+// NewAccessTokenMiddleware authenticates the requests of the JSON API by personal access tokens
+// in the header "Authorization: Bearer <token>". Other bearer tokens, such as session cookies, are passed to fallback.
+// A read-only token can only make GET and HEAD requests.
+func NewAccessTokenMiddleware(repo DynamoRepository, fallback Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		fallbackNext := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok || !strings.HasPrefix(token, accessTokenPrefix+".") {
+				fallbackNext.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			uid, tid, secret, ok := parseAccessToken(token)
+			if !ok {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "The token is invalid or revoked.")
+				return
+			}
+			t, err := repo.FindAccessToken(ctx, uid, tid)
+			if errors.Is(err, apperrors.ErrNotFound) ||
+				(err == nil && subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(t.TokenHash)) != 1) {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "The token is invalid or revoked.")
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, fmt.Errorf("find access token: %w", err).Error())
+				writeAPIError(w, http.StatusInternalServerError, "internal", http.StatusText(http.StatusInternalServerError))
+				return
+			}
+			if t.Scope != repository.AccessTokenScopeWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeAPIError(w, http.StatusForbidden, "insufficient_scope", "The token is read-only.")
+				return
+			}
+
+			now := time.Now()
+			if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= accessTokenTouchInterval {
+				// A failure to record the last use does not fail the request.
+				if err := repo.TouchAccessToken(ctx, uid, tid, now); err != nil {
+					slog.WarnContext(ctx, fmt.Errorf("touch access token: %w", err).Error())
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(auth.SetUserID(ctx, uid)))
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization header of the bearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
 func redirect(w http.ResponseWriter, loc string) {
 	w.Header().Set("Location", loc)
 	w.WriteHeader(http.StatusFound)
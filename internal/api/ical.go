@@ -0,0 +1,56 @@
+package api
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalLineOctets is the maximum length of a content line of iCalendar, excluding the line break.
+const icalLineOctets = 75
+
+// icalWriter builds an iCalendar (RFC 5545) document.
+type icalWriter struct {
+	b strings.Builder
+}
+
+// prop writes a content line. The value must be escaped by icalText if it is a text.
+// A line longer than icalLineOctets is folded without splitting a UTF-8 character.
+func (w *icalWriter) prop(name, value string) {
+	line := name + ":" + value
+	limit := icalLineOctets
+	for len(line) > limit {
+		n := limit
+		for !utf8.RuneStart(line[n]) {
+			n--
+		}
+		w.b.WriteString(line[:n])
+		w.b.WriteString("\r\n ")
+		line = line[n:]
+		// The leading space of a continuation line counts toward its length.
+		limit = icalLineOctets - 1
+	}
+	w.b.WriteString(line)
+	w.b.WriteString("\r\n")
+}
+
+func (w *icalWriter) String() string {
+	return w.b.String()
+}
+
+var icalTextReplacer = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icalText escapes a value of the TEXT type.
+func icalText(s string) string {
+	return icalTextReplacer.Replace(s)
+}
+
+// icalDate formats a value of the DATE type from a date of a check such as "2021-01-02".
+func icalDate(date string) string {
+	return strings.ReplaceAll(date, "-", "")
+}
+
+// icalTime formats a value of the DATE-TIME type in UTC.
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
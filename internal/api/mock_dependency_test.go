@@ -12,6 +12,7 @@ package api
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	auth "firebase.google.com/go/auth"
 	auth0 "github.com/hareku/habit-tracker-app/internal/auth"
@@ -23,6 +24,7 @@ import (
 type MockAuthenticator struct {
 	ctrl     *gomock.Controller
 	recorder *MockAuthenticatorMockRecorder
+	isgomock struct{}
 }
 
 // MockAuthenticatorMockRecorder is the mock recorder for MockAuthenticator.
@@ -105,6 +107,7 @@ func (mr *MockAuthenticatorMockRecorder) SessionCookie(ctx, idToken any) *gomock
 type MockDynamoRepository struct {
 	ctrl     *gomock.Controller
 	recorder *MockDynamoRepositoryMockRecorder
+	isgomock struct{}
 }
 
 // MockDynamoRepositoryMockRecorder is the mock recorder for MockDynamoRepository.
@@ -154,6 +157,21 @@ func (mr *MockDynamoRepositoryMockRecorder) AllHabits(ctx, uid any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllHabits", reflect.TypeOf((*MockDynamoRepository)(nil).AllHabits), ctx, uid)
 }
 
+// AllReminders mocks base method.
+func (m *MockDynamoRepository) AllReminders(ctx context.Context) ([]*repository.DynamoReminder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllReminders", ctx)
+	ret0, _ := ret[0].([]*repository.DynamoReminder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllReminders indicates an expected call of AllReminders.
+func (mr *MockDynamoRepositoryMockRecorder) AllReminders(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllReminders", reflect.TypeOf((*MockDynamoRepository)(nil).AllReminders), ctx)
+}
+
 // ArchiveHabit mocks base method.
 func (m *MockDynamoRepository) ArchiveHabit(ctx context.Context, uid auth0.UserID, hid string) error {
 	m.ctrl.T.Helper()
@@ -168,34 +186,108 @@ func (mr *MockDynamoRepositoryMockRecorder) ArchiveHabit(ctx, uid, hid any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveHabit", reflect.TypeOf((*MockDynamoRepository)(nil).ArchiveHabit), ctx, uid, hid)
 }
 
+// BackfillChecks mocks base method.
+func (m *MockDynamoRepository) BackfillChecks(ctx context.Context, in *repository.DynamoRepositoryBackfillChecksInput) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BackfillChecks", ctx, in)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BackfillChecks indicates an expected call of BackfillChecks.
+func (mr *MockDynamoRepositoryMockRecorder) BackfillChecks(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BackfillChecks", reflect.TypeOf((*MockDynamoRepository)(nil).BackfillChecks), ctx, in)
+}
+
+// CreateAccessToken mocks base method.
+func (m *MockDynamoRepository) CreateAccessToken(ctx context.Context, in *repository.DynamoRepositoryCreateAccessTokenInput) (*repository.DynamoAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccessToken", ctx, in)
+	ret0, _ := ret[0].(*repository.DynamoAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccessToken indicates an expected call of CreateAccessToken.
+func (mr *MockDynamoRepositoryMockRecorder) CreateAccessToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccessToken", reflect.TypeOf((*MockDynamoRepository)(nil).CreateAccessToken), ctx, in)
+}
+
 // CreateCheck mocks base method.
-func (m *MockDynamoRepository) CreateCheck(ctx context.Context, uid auth0.UserID, hid, date string) (*repository.DynamoCheck, error) {
+func (m *MockDynamoRepository) CreateCheck(ctx context.Context, in *repository.DynamoRepositoryCreateCheckInput) (*repository.DynamoCheck, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateCheck", ctx, uid, hid, date)
+	ret := m.ctrl.Call(m, "CreateCheck", ctx, in)
 	ret0, _ := ret[0].(*repository.DynamoCheck)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateCheck indicates an expected call of CreateCheck.
-func (mr *MockDynamoRepositoryMockRecorder) CreateCheck(ctx, uid, hid, date any) *gomock.Call {
+func (mr *MockDynamoRepositoryMockRecorder) CreateCheck(ctx, in any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheck", reflect.TypeOf((*MockDynamoRepository)(nil).CreateCheck), ctx, uid, hid, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheck", reflect.TypeOf((*MockDynamoRepository)(nil).CreateCheck), ctx, in)
+}
+
+// CreateChecks mocks base method.
+func (m *MockDynamoRepository) CreateChecks(ctx context.Context, in []*repository.DynamoRepositoryCreateCheckInput) ([]*repository.DynamoCheck, []error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChecks", ctx, in)
+	ret0, _ := ret[0].([]*repository.DynamoCheck)
+	ret1, _ := ret[1].([]error)
+	return ret0, ret1
+}
+
+// CreateChecks indicates an expected call of CreateChecks.
+func (mr *MockDynamoRepositoryMockRecorder) CreateChecks(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChecks", reflect.TypeOf((*MockDynamoRepository)(nil).CreateChecks), ctx, in)
 }
 
 // CreateHabit mocks base method.
-func (m *MockDynamoRepository) CreateHabit(ctx context.Context, uid auth0.UserID, title string) (*repository.DynamoHabit, error) {
+func (m *MockDynamoRepository) CreateHabit(ctx context.Context, in *repository.DynamoRepositoryCreateHabitInput) (*repository.DynamoHabit, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateHabit", ctx, uid, title)
+	ret := m.ctrl.Call(m, "CreateHabit", ctx, in)
 	ret0, _ := ret[0].(*repository.DynamoHabit)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateHabit indicates an expected call of CreateHabit.
-func (mr *MockDynamoRepositoryMockRecorder) CreateHabit(ctx, uid, title any) *gomock.Call {
+func (mr *MockDynamoRepositoryMockRecorder) CreateHabit(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHabit", reflect.TypeOf((*MockDynamoRepository)(nil).CreateHabit), ctx, in)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockDynamoRepository) CreateWebhook(ctx context.Context, in *repository.DynamoRepositoryCreateWebhookInput) (*repository.DynamoWebhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, in)
+	ret0, _ := ret[0].(*repository.DynamoWebhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockDynamoRepositoryMockRecorder) CreateWebhook(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockDynamoRepository)(nil).CreateWebhook), ctx, in)
+}
+
+// DeleteAccessToken mocks base method.
+func (m *MockDynamoRepository) DeleteAccessToken(ctx context.Context, uid auth0.UserID, tid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccessToken", ctx, uid, tid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccessToken indicates an expected call of DeleteAccessToken.
+func (mr *MockDynamoRepositoryMockRecorder) DeleteAccessToken(ctx, uid, tid any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHabit", reflect.TypeOf((*MockDynamoRepository)(nil).CreateHabit), ctx, uid, title)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccessToken", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteAccessToken), ctx, uid, tid)
 }
 
 // DeleteCheck mocks base method.
@@ -212,6 +304,20 @@ func (mr *MockDynamoRepositoryMockRecorder) DeleteCheck(ctx, uid, hid, date any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCheck", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteCheck), ctx, uid, hid, date)
 }
 
+// DeleteFeedToken mocks base method.
+func (m *MockDynamoRepository) DeleteFeedToken(ctx context.Context, uid auth0.UserID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFeedToken", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFeedToken indicates an expected call of DeleteFeedToken.
+func (mr *MockDynamoRepositoryMockRecorder) DeleteFeedToken(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFeedToken", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteFeedToken), ctx, uid)
+}
+
 // DeleteHabit mocks base method.
 func (m *MockDynamoRepository) DeleteHabit(ctx context.Context, uid auth0.UserID, hid string) error {
 	m.ctrl.T.Helper()
@@ -226,6 +332,49 @@ func (mr *MockDynamoRepositoryMockRecorder) DeleteHabit(ctx, uid, hid any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHabit", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteHabit), ctx, uid, hid)
 }
 
+// DeleteUserData mocks base method.
+func (m *MockDynamoRepository) DeleteUserData(ctx context.Context, uid auth0.UserID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserData", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserData indicates an expected call of DeleteUserData.
+func (mr *MockDynamoRepositoryMockRecorder) DeleteUserData(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserData", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteUserData), ctx, uid)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockDynamoRepository) DeleteWebhook(ctx context.Context, uid auth0.UserID, wid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, uid, wid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockDynamoRepositoryMockRecorder) DeleteWebhook(ctx, uid, wid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockDynamoRepository)(nil).DeleteWebhook), ctx, uid, wid)
+}
+
+// FindAccessToken mocks base method.
+func (m *MockDynamoRepository) FindAccessToken(ctx context.Context, uid auth0.UserID, tid string) (*repository.DynamoAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAccessToken", ctx, uid, tid)
+	ret0, _ := ret[0].(*repository.DynamoAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAccessToken indicates an expected call of FindAccessToken.
+func (mr *MockDynamoRepositoryMockRecorder) FindAccessToken(ctx, uid, tid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAccessToken", reflect.TypeOf((*MockDynamoRepository)(nil).FindAccessToken), ctx, uid, tid)
+}
+
 // FindArchivedHabit mocks base method.
 func (m *MockDynamoRepository) FindArchivedHabit(ctx context.Context, uid auth0.UserID, hid string) (*repository.DynamoHabit, error) {
 	m.ctrl.T.Helper()
@@ -241,6 +390,21 @@ func (mr *MockDynamoRepositoryMockRecorder) FindArchivedHabit(ctx, uid, hid any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindArchivedHabit", reflect.TypeOf((*MockDynamoRepository)(nil).FindArchivedHabit), ctx, uid, hid)
 }
 
+// FindFeedToken mocks base method.
+func (m *MockDynamoRepository) FindFeedToken(ctx context.Context, uid auth0.UserID) (*repository.DynamoFeedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFeedToken", ctx, uid)
+	ret0, _ := ret[0].(*repository.DynamoFeedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFeedToken indicates an expected call of FindFeedToken.
+func (mr *MockDynamoRepositoryMockRecorder) FindFeedToken(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFeedToken", reflect.TypeOf((*MockDynamoRepository)(nil).FindFeedToken), ctx, uid)
+}
+
 // FindHabit mocks base method.
 func (m *MockDynamoRepository) FindHabit(ctx context.Context, uid auth0.UserID, hid string) (*repository.DynamoHabit, error) {
 	m.ctrl.T.Helper()
@@ -256,34 +420,225 @@ func (mr *MockDynamoRepositoryMockRecorder) FindHabit(ctx, uid, hid any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindHabit", reflect.TypeOf((*MockDynamoRepository)(nil).FindHabit), ctx, uid, hid)
 }
 
-// ListLastWeekChecksInAllHabits mocks base method.
-func (m *MockDynamoRepository) ListLastWeekChecksInAllHabits(ctx context.Context, uid auth0.UserID) ([]*repository.DynamoCheck, error) {
+// FindProfile mocks base method.
+func (m *MockDynamoRepository) FindProfile(ctx context.Context, uid auth0.UserID) (*repository.DynamoProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindProfile", ctx, uid)
+	ret0, _ := ret[0].(*repository.DynamoProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindProfile indicates an expected call of FindProfile.
+func (mr *MockDynamoRepositoryMockRecorder) FindProfile(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindProfile", reflect.TypeOf((*MockDynamoRepository)(nil).FindProfile), ctx, uid)
+}
+
+// FindReminder mocks base method.
+func (m *MockDynamoRepository) FindReminder(ctx context.Context, uid auth0.UserID, hid string) (*repository.DynamoReminder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReminder", ctx, uid, hid)
+	ret0, _ := ret[0].(*repository.DynamoReminder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindReminder indicates an expected call of FindReminder.
+func (mr *MockDynamoRepositoryMockRecorder) FindReminder(ctx, uid, hid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReminder", reflect.TypeOf((*MockDynamoRepository)(nil).FindReminder), ctx, uid, hid)
+}
+
+// ImportChecks mocks base method.
+func (m *MockDynamoRepository) ImportChecks(ctx context.Context, in *repository.DynamoRepositoryImportChecksInput) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportChecks", ctx, in)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportChecks indicates an expected call of ImportChecks.
+func (mr *MockDynamoRepositoryMockRecorder) ImportChecks(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportChecks", reflect.TypeOf((*MockDynamoRepository)(nil).ImportChecks), ctx, in)
+}
+
+// ListAccessTokens mocks base method.
+func (m *MockDynamoRepository) ListAccessTokens(ctx context.Context, uid auth0.UserID) ([]*repository.DynamoAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccessTokens", ctx, uid)
+	ret0, _ := ret[0].([]*repository.DynamoAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccessTokens indicates an expected call of ListAccessTokens.
+func (mr *MockDynamoRepositoryMockRecorder) ListAccessTokens(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccessTokens", reflect.TypeOf((*MockDynamoRepository)(nil).ListAccessTokens), ctx, uid)
+}
+
+// ListCheckNotes mocks base method.
+func (m *MockDynamoRepository) ListCheckNotes(ctx context.Context, in *repository.DynamoRepositoryListCheckNotesInput) (*repository.DynamoRepositoryListCheckNotesOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCheckNotes", ctx, in)
+	ret0, _ := ret[0].(*repository.DynamoRepositoryListCheckNotesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCheckNotes indicates an expected call of ListCheckNotes.
+func (mr *MockDynamoRepositoryMockRecorder) ListCheckNotes(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCheckNotes", reflect.TypeOf((*MockDynamoRepository)(nil).ListCheckNotes), ctx, in)
+}
+
+// ListChecks mocks base method.
+func (m *MockDynamoRepository) ListChecks(ctx context.Context, in *repository.DynamoRepositoryListChecksInput) (*repository.DynamoRepositoryListChecksOutput, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListLastWeekChecksInAllHabits", ctx, uid)
+	ret := m.ctrl.Call(m, "ListChecks", ctx, in)
+	ret0, _ := ret[0].(*repository.DynamoRepositoryListChecksOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListChecks indicates an expected call of ListChecks.
+func (mr *MockDynamoRepositoryMockRecorder) ListChecks(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChecks", reflect.TypeOf((*MockDynamoRepository)(nil).ListChecks), ctx, in)
+}
+
+// ListChecksBetween mocks base method.
+func (m *MockDynamoRepository) ListChecksBetween(ctx context.Context, uid auth0.UserID, hid, from, to string) ([]*repository.DynamoCheck, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListChecksBetween", ctx, uid, hid, from, to)
 	ret0, _ := ret[0].([]*repository.DynamoCheck)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListLastWeekChecksInAllHabits indicates an expected call of ListLastWeekChecksInAllHabits.
-func (mr *MockDynamoRepositoryMockRecorder) ListLastWeekChecksInAllHabits(ctx, uid any) *gomock.Call {
+// ListChecksBetween indicates an expected call of ListChecksBetween.
+func (mr *MockDynamoRepositoryMockRecorder) ListChecksBetween(ctx, uid, hid, from, to any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLastWeekChecksInAllHabits", reflect.TypeOf((*MockDynamoRepository)(nil).ListLastWeekChecksInAllHabits), ctx, uid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChecksBetween", reflect.TypeOf((*MockDynamoRepository)(nil).ListChecksBetween), ctx, uid, hid, from, to)
 }
 
-// ListLatestChecksWithLimit mocks base method.
-func (m *MockDynamoRepository) ListLatestChecksWithLimit(ctx context.Context, uid auth0.UserID, hid string, limit int32) ([]*repository.DynamoCheck, error) {
+// ListChecksBetweenInAllHabits mocks base method.
+func (m *MockDynamoRepository) ListChecksBetweenInAllHabits(ctx context.Context, uid auth0.UserID, from, to string) ([]*repository.DynamoCheck, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListLatestChecksWithLimit", ctx, uid, hid, limit)
+	ret := m.ctrl.Call(m, "ListChecksBetweenInAllHabits", ctx, uid, from, to)
 	ret0, _ := ret[0].([]*repository.DynamoCheck)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListLatestChecksWithLimit indicates an expected call of ListLatestChecksWithLimit.
-func (mr *MockDynamoRepositoryMockRecorder) ListLatestChecksWithLimit(ctx, uid, hid, limit any) *gomock.Call {
+// ListChecksBetweenInAllHabits indicates an expected call of ListChecksBetweenInAllHabits.
+func (mr *MockDynamoRepositoryMockRecorder) ListChecksBetweenInAllHabits(ctx, uid, from, to any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLatestChecksWithLimit", reflect.TypeOf((*MockDynamoRepository)(nil).ListLatestChecksWithLimit), ctx, uid, hid, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChecksBetweenInAllHabits", reflect.TypeOf((*MockDynamoRepository)(nil).ListChecksBetweenInAllHabits), ctx, uid, from, to)
+}
+
+// ListDueWebhookDeliveries mocks base method.
+func (m *MockDynamoRepository) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*repository.DynamoWebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueWebhookDeliveries", ctx, now)
+	ret0, _ := ret[0].([]*repository.DynamoWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueWebhookDeliveries indicates an expected call of ListDueWebhookDeliveries.
+func (mr *MockDynamoRepositoryMockRecorder) ListDueWebhookDeliveries(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueWebhookDeliveries", reflect.TypeOf((*MockDynamoRepository)(nil).ListDueWebhookDeliveries), ctx, now)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockDynamoRepository) ListWebhookDeliveries(ctx context.Context, uid auth0.UserID, wid string, limit int32) ([]*repository.DynamoWebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", ctx, uid, wid, limit)
+	ret0, _ := ret[0].([]*repository.DynamoWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockDynamoRepositoryMockRecorder) ListWebhookDeliveries(ctx, uid, wid, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockDynamoRepository)(nil).ListWebhookDeliveries), ctx, uid, wid, limit)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockDynamoRepository) ListWebhooks(ctx context.Context, uid auth0.UserID) ([]*repository.DynamoWebhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx, uid)
+	ret0, _ := ret[0].([]*repository.DynamoWebhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockDynamoRepositoryMockRecorder) ListWebhooks(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockDynamoRepository)(nil).ListWebhooks), ctx, uid)
+}
+
+// PutFeedToken mocks base method.
+func (m *MockDynamoRepository) PutFeedToken(ctx context.Context, in *repository.DynamoRepositoryPutFeedTokenInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutFeedToken", ctx, in)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutFeedToken indicates an expected call of PutFeedToken.
+func (mr *MockDynamoRepositoryMockRecorder) PutFeedToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutFeedToken", reflect.TypeOf((*MockDynamoRepository)(nil).PutFeedToken), ctx, in)
+}
+
+// PutReminder mocks base method.
+func (m *MockDynamoRepository) PutReminder(ctx context.Context, in *repository.DynamoRepositoryPutReminderInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutReminder", ctx, in)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutReminder indicates an expected call of PutReminder.
+func (mr *MockDynamoRepositoryMockRecorder) PutReminder(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutReminder", reflect.TypeOf((*MockDynamoRepository)(nil).PutReminder), ctx, in)
+}
+
+// PutWebhookDelivery mocks base method.
+func (m *MockDynamoRepository) PutWebhookDelivery(ctx context.Context, d *repository.DynamoWebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWebhookDelivery", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutWebhookDelivery indicates an expected call of PutWebhookDelivery.
+func (mr *MockDynamoRepositoryMockRecorder) PutWebhookDelivery(ctx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWebhookDelivery", reflect.TypeOf((*MockDynamoRepository)(nil).PutWebhookDelivery), ctx, d)
+}
+
+// TouchAccessToken mocks base method.
+func (m *MockDynamoRepository) TouchAccessToken(ctx context.Context, uid auth0.UserID, tid string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAccessToken", ctx, uid, tid, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAccessToken indicates an expected call of TouchAccessToken.
+func (mr *MockDynamoRepositoryMockRecorder) TouchAccessToken(ctx, uid, tid, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAccessToken", reflect.TypeOf((*MockDynamoRepository)(nil).TouchAccessToken), ctx, uid, tid, at)
 }
 
 // UnarchiveHabit mocks base method.
@@ -300,6 +655,20 @@ func (mr *MockDynamoRepositoryMockRecorder) UnarchiveHabit(ctx, uid, hid any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnarchiveHabit", reflect.TypeOf((*MockDynamoRepository)(nil).UnarchiveHabit), ctx, uid, hid)
 }
 
+// UpdateCheckNote mocks base method.
+func (m *MockDynamoRepository) UpdateCheckNote(ctx context.Context, in *repository.DynamoRepositoryUpdateCheckNoteInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCheckNote", ctx, in)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCheckNote indicates an expected call of UpdateCheckNote.
+func (mr *MockDynamoRepositoryMockRecorder) UpdateCheckNote(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCheckNote", reflect.TypeOf((*MockDynamoRepository)(nil).UpdateCheckNote), ctx, in)
+}
+
 // UpdateHabit mocks base method.
 func (m *MockDynamoRepository) UpdateHabit(ctx context.Context, in *repository.DynamoRepositoryUpdateHabitInput) error {
 	m.ctrl.T.Helper()
@@ -313,3 +682,53 @@ func (mr *MockDynamoRepositoryMockRecorder) UpdateHabit(ctx, in any) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHabit", reflect.TypeOf((*MockDynamoRepository)(nil).UpdateHabit), ctx, in)
 }
+
+// UpdateProfile mocks base method.
+func (m *MockDynamoRepository) UpdateProfile(ctx context.Context, in *repository.DynamoRepositoryUpdateProfileInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, in)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockDynamoRepositoryMockRecorder) UpdateProfile(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockDynamoRepository)(nil).UpdateProfile), ctx, in)
+}
+
+// MockWebhookPublisher is a mock of WebhookPublisher interface.
+type MockWebhookPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookPublisherMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhookPublisherMockRecorder is the mock recorder for MockWebhookPublisher.
+type MockWebhookPublisherMockRecorder struct {
+	mock *MockWebhookPublisher
+}
+
+// NewMockWebhookPublisher creates a new mock instance.
+func NewMockWebhookPublisher(ctrl *gomock.Controller) *MockWebhookPublisher {
+	mock := &MockWebhookPublisher{ctrl: ctrl}
+	mock.recorder = &MockWebhookPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookPublisher) EXPECT() *MockWebhookPublisherMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockWebhookPublisher) Publish(ctx context.Context, uid auth0.UserID, eventType string, data any) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", ctx, uid, eventType, data)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockWebhookPublisherMockRecorder) Publish(ctx, uid, eventType, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockWebhookPublisher)(nil).Publish), ctx, uid, eventType, data)
+}
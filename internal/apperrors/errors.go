@@ -5,4 +5,8 @@ import "fmt"
 var (
 	ErrNotFound = fmt.Errorf("not found")
 	ErrConflict = fmt.Errorf("conflict")
+	// ErrArchived is returned when an archived habit is written as if it were active.
+	ErrArchived = fmt.Errorf("archived")
+	// ErrValueRequired is returned when a check of a quantitative habit has no value.
+	ErrValueRequired = fmt.Errorf("value required")
 )
@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// Format is the format of an imported file.
+type Format string
+
+const (
+	// FormatCSV is a CSV of the columns habit, date and optionally value, with or without a header.
+	FormatCSV Format = "csv"
+	// FormatLoopCSV is Checkmarks.csv exported by Loop Habit Tracker, or the ZIP file which contains it.
+	FormatLoopCSV Format = "loop_csv"
+	// FormatLoopDB is a SQLite backup of Loop Habit Tracker.
+	FormatLoopDB Format = "loop_db"
+)
+
+// Record is a check of a habit to import.
+type Record struct {
+	// Habit is the title of the habit.
+	Habit string `json:"habit"`
+	// Unit is the unit of the habit, which is known only in FormatLoopDB.
+	Unit  string  `json:"unit,omitempty"`
+	Date  string  `json:"date"`
+	Value float64 `json:"value,omitempty"`
+}
+
+const dateLayout = "2006-01-02"
+
+// Loop Habit Tracker stores the values of numerical habits multiplied by 1000.
+const loopValueScale = 1000
+
+// Loop Habit Tracker's codes of an entry of a yes-or-no habit.
+const (
+	loopYesAuto   = 1
+	loopYesManual = 2
+)
+
+// Detect detects the format of the file from its content.
+func Detect(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, []byte("SQLite format 3\x00")):
+		return FormatLoopDB
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return FormatLoopCSV
+	case strings.HasPrefix(strings.ToLower(string(trimBOM(data))), "date,"):
+		return FormatLoopCSV
+	default:
+		return FormatCSV
+	}
+}
+
+// Parse parses the file in the detected format.
+func Parse(ctx context.Context, data []byte) (Format, []*Record, error) {
+	format := Detect(data)
+	var (
+		records []*Record
+		err     error
+	)
+	switch format {
+	case FormatLoopDB:
+		records, err = ParseLoopDB(ctx, data)
+	case FormatLoopCSV:
+		if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+			records, err = ParseLoopZIP(data)
+		} else {
+			records, err = ParseLoopCSV(bytes.NewReader(data))
+		}
+	default:
+		records, err = ParseCSV(bytes.NewReader(data))
+	}
+	return format, records, err
+}
+
+// ParseCSV parses a CSV of the columns habit, date (YYYY-MM-DD) and optionally value.
+// The first row is skipped if it is a header which starts with "habit,date".
+func ParseCSV(r io.Reader) ([]*Record, error) {
+	cr := csv.NewReader(bomReader(r))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var records []*Record
+	for line := 1; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		if line == 1 && len(row) >= 2 && strings.EqualFold(row[0], "habit") && strings.EqualFold(row[1], "date") {
+			continue
+		}
+		if len(row) < 2 || len(row) > 3 {
+			return nil, fmt.Errorf("line %d: want 2 or 3 columns, got %d", line, len(row))
+		}
+
+		rec := &Record{Habit: strings.TrimSpace(row[0]), Date: row[1]}
+		if rec.Habit == "" {
+			return nil, fmt.Errorf("line %d: habit is empty", line)
+		}
+		if _, err := time.Parse(dateLayout, rec.Date); err != nil {
+			return nil, fmt.Errorf("line %d: date format must be %q", line, dateLayout)
+		}
+		if len(row) == 3 && row[2] != "" {
+			v, err := strconv.ParseFloat(row[2], 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+				return nil, fmt.Errorf("line %d: value %q is not a non-negative number", line, row[2])
+			}
+			rec.Value = v
+		}
+		records = append(records, rec)
+	}
+}
+
+// ParseLoopCSV parses Checkmarks.csv exported by Loop Habit Tracker,
+// which has a column of the date and a column for each habit.
+// Only the entries which are checked by hand are imported, and the others such as skips are ignored.
+// A number with a decimal point is a value of a numerical habit.
+func ParseLoopCSV(r io.Reader) ([]*Record, error) {
+	cr := csv.NewReader(bomReader(r))
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if len(header) < 2 || !strings.EqualFold(header[0], "date") {
+		return nil, errors.New(`header must start with "Date"`)
+	}
+
+	var records []*Record
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		date := row[0]
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("line %d: date format must be %q", line, dateLayout)
+		}
+		for i, cell := range row[1:] {
+			habit := ""
+			if i+1 < len(header) {
+				habit = strings.TrimSpace(header[i+1])
+			}
+			// Loop ends each row with a comma, which makes an empty trailing column.
+			if habit == "" || cell == "" {
+				continue
+			}
+			if strings.Contains(cell, ".") {
+				v, err := strconv.ParseFloat(cell, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid value %q", line, cell)
+				}
+				if v > 0 {
+					records = append(records, &Record{Habit: habit, Date: date, Value: v})
+				}
+				continue
+			}
+			code, err := strconv.Atoi(cell)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid entry %q", line, cell)
+			}
+			if code == loopYesManual {
+				records = append(records, &Record{Habit: habit, Date: date})
+			}
+		}
+	}
+	// Loop lists the dates from the newest.
+	slices.SortStableFunc(records, func(a, b *Record) int { return strings.Compare(a.Date, b.Date) })
+	return records, nil
+}
+
+// ParseLoopZIP parses Checkmarks.csv at the root of the ZIP file exported by Loop Habit Tracker.
+func ParseLoopZIP(data []byte) ([]*Record, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Clean(f.Name) != "Checkmarks.csv" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return ParseLoopCSV(rc)
+	}
+	return nil, errors.New("zip does not contain Checkmarks.csv")
+}
+
+// ParseLoopDB parses a SQLite backup of Loop Habit Tracker.
+// Only the entries which are checked are imported, and the values of numerical habits are scaled back.
+func ParseLoopDB(ctx context.Context, data []byte) ([]*Record, error) {
+	// The driver opens only files, so the backup is written to a temporary file.
+	f, err := os.CreateTemp("", "loop-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close temp file: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+f.Name()+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	defer db.Close()
+
+	// Old versions of Loop have neither the type nor the unit of a habit.
+	columns, err := loopHabitColumns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	typeColumn, unitColumn := "0", "''"
+	if slices.Contains(columns, "type") {
+		typeColumn = "COALESCE(h.type, 0)"
+	}
+	if slices.Contains(columns, "unit") {
+		unitColumn = "COALESCE(h.unit, '')"
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT h.name, %s, %s, r.timestamp, r.value FROM Repetitions r JOIN Habits h ON h.id = r.habit ORDER BY r.timestamp, h.position`,
+		typeColumn, unitColumn))
+	if err != nil {
+		return nil, fmt.Errorf("query repetitions: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		var (
+			name, unit string
+			typ        int
+			ts, value  int64
+		)
+		if err := rows.Scan(&name, &typ, &unit, &ts, &value); err != nil {
+			return nil, fmt.Errorf("scan repetition: %w", err)
+		}
+		rec := &Record{Habit: strings.TrimSpace(name), Unit: unit, Date: time.UnixMilli(ts).UTC().Format(dateLayout)}
+		if typ == 1 {
+			if value <= 0 {
+				continue
+			}
+			rec.Value = float64(value) / loopValueScale
+		} else if value != loopYesManual && value != loopYesAuto {
+			// Old versions of Loop store 1 for a check, and new versions store 2 for a check by hand.
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate repetitions: %w", err)
+	}
+	return records, nil
+}
+
+func loopHabitColumns(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info('Habits')`)
+	if err != nil {
+		return nil, fmt.Errorf("query columns of habits: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+		columns = append(columns, strings.ToLower(c))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("backup does not contain the table Habits")
+	}
+	return columns, nil
+}
+
+func trimBOM(b []byte) []byte {
+	return bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+}
+
+// bomReader skips the byte order mark which spreadsheet apps put at the head of a CSV.
+func bomReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(3); err == nil && bytes.Equal(b, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	return br
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		in      string
+		want    []*Record
+		wantErr bool
+	}{
+		{
+			name: "with header",
+			in:   "\xef\xbb\xbfhabit,date,value\nRunning,2021-01-01,3.5\nRead,2021-01-02\n",
+			want: []*Record{
+				{Habit: "Running", Date: "2021-01-01", Value: 3.5},
+				{Habit: "Read", Date: "2021-01-02"},
+			},
+		},
+		{
+			name: "without header",
+			in:   "\"Read, books\",2021-01-02,\n",
+			want: []*Record{{Habit: "Read, books", Date: "2021-01-02"}},
+		},
+		{name: "invalid date", in: "Read,2021/01/02\n", wantErr: true},
+		{name: "negative value", in: "Read,2021-01-02,-1\n", wantErr: true},
+		{name: "empty habit", in: ",2021-01-02\n", wantErr: true},
+		{name: "too many columns", in: "Read,2021-01-02,1,note\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseCSV(strings.NewReader(tt.in))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+const loopCheckmarks = "Date,Meditate,Run,\n2021-01-02,2,1.500,\n2021-01-01,1,0.000,\n2020-12-31,2,-1,\n2020-12-30,3,2.000,\n"
+
+var loopCheckmarksRecords = []*Record{
+	{Habit: "Run", Date: "2020-12-30", Value: 2},
+	{Habit: "Meditate", Date: "2020-12-31"},
+	{Habit: "Meditate", Date: "2021-01-02"},
+	{Habit: "Run", Date: "2021-01-02", Value: 1.5},
+}
+
+func TestParseLoopCSV(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseLoopCSV(strings.NewReader(loopCheckmarks))
+	require.NoError(t, err)
+	assert.Equal(t, loopCheckmarksRecords, got)
+
+	_, err = ParseLoopCSV(strings.NewReader("Habit,Date\n"))
+	require.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for name, content := range map[string]string{
+		"Habits.csv":                  "Position,Name\n001,Meditate\n002,Run\n",
+		"001 Meditate/Checkmarks.csv": "Date,Checkmark\n2021-01-02,2\n",
+		"Checkmarks.csv":              loopCheckmarks,
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat Format
+		want       []*Record
+	}{
+		{
+			name:       "csv",
+			data:       []byte("habit,date\nRead,2021-01-02\n"),
+			wantFormat: FormatCSV,
+			want:       []*Record{{Habit: "Read", Date: "2021-01-02"}},
+		},
+		{
+			name:       "loop csv",
+			data:       []byte(loopCheckmarks),
+			wantFormat: FormatLoopCSV,
+			want:       loopCheckmarksRecords,
+		},
+		{
+			name:       "loop zip",
+			data:       zipped.Bytes(),
+			wantFormat: FormatLoopCSV,
+			want:       loopCheckmarksRecords,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			format, got, err := Parse(t.Context(), tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFormat, format)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseLoopDB(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "Loop Habits Backup.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		`CREATE TABLE Habits (id INTEGER PRIMARY KEY, name TEXT, position INTEGER, type INTEGER, unit TEXT)`,
+		`CREATE TABLE Repetitions (id INTEGER PRIMARY KEY, habit INTEGER, timestamp INTEGER, value INTEGER)`,
+		`INSERT INTO Habits VALUES (1, 'Meditate', 0, 0, ''), (2, 'Run', 1, 1, 'km')`,
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	day := func(date string) int64 {
+		d, err := time.Parse("2006-01-02", date)
+		require.NoError(t, err)
+		return d.UnixMilli()
+	}
+	for _, r := range []struct {
+		habit int
+		date  string
+		value int
+	}{
+		{1, "2021-01-01", 2},
+		{1, "2021-01-02", 3}, // skipped
+		{1, "2021-01-03", 1}, // checked by an old version
+		{2, "2021-01-01", 1500},
+		{2, "2021-01-02", 0},
+	} {
+		_, err := db.Exec(`INSERT INTO Repetitions (habit, timestamp, value) VALUES (?, ?, ?)`, r.habit, day(r.date), r.value)
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	format, got, err := Parse(t.Context(), data)
+	require.NoError(t, err)
+	assert.Equal(t, FormatLoopDB, format)
+	assert.Equal(t, []*Record{
+		{Habit: "Meditate", Date: "2021-01-01"},
+		{Habit: "Run", Unit: "km", Date: "2021-01-01", Value: 1.5},
+		{Habit: "Meditate", Date: "2021-01-03"},
+	}, got)
+}
@@ -0,0 +1,61 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// LogNotifier writes the notifications to a logger, for local runs.
+type LogNotifier struct {
+	// Logger is slog.Default() if nil.
+	Logger *slog.Logger
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification *Notification) error {
+	logger := n.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "reminder",
+		slog.String("user_id", string(notification.UserID)),
+		slog.String("habit_id", notification.HabitID),
+		slog.String("habit_title", notification.HabitTitle),
+		slog.String("date", notification.Date),
+		slog.String("time", notification.Time),
+	)
+	return nil
+}
+
+// FileNotifier appends the notifications to a file as JSON lines, for local runs and tests.
+type FileNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, notification *Notification) error {
+	b, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write notification: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package reminder finds the reminders of habits which are due and hands them to a notifier.
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// TimeLayout is the layout of a reminder time, in the time zone of the user.
+const TimeLayout = "15:04"
+
+const dateLayout = "2006-01-02"
+
+// DefaultWindow is the interval of the schedule which runs the reminders.
+const DefaultWindow = 5 * time.Minute
+
+// Notification is a reminder of a habit which is due.
+type Notification struct {
+	UserID     auth.UserID `json:"user_id"`
+	HabitID    string      `json:"habit_id"`
+	HabitTitle string      `json:"habit_title"`
+	// Date is the date of the user which the habit is due on, formatted as "2006-01-02".
+	Date string `json:"date"`
+	// Time is the reminder time, formatted as TimeLayout.
+	Time string `json:"time"`
+	// At is the moment of the reminder time.
+	At time.Time `json:"at"`
+}
+
+// Notifier sends notifications to users.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+type Repository interface {
+	AllReminders(ctx context.Context) ([]*repository.DynamoReminder, error)
+	FindHabit(ctx context.Context, uid auth.UserID, hid string) (*repository.DynamoHabit, error)
+	FindProfile(ctx context.Context, uid auth.UserID) (*repository.DynamoProfile, error)
+	ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*repository.DynamoCheck, error)
+}
+
+// Runner sends the reminders whose times have come since the last run.
+//
+// It is meant to run on a fixed schedule whose interval is Window, so that each reminder time
+// falls in exactly one run. A late or repeated run may skip or repeat a reminder.
+type Runner struct {
+	Repository Repository
+	Notifier   Notifier
+	// Window is the interval of the runs. A reminder time in (now-Window, now] is due.
+	Window time.Duration
+}
+
+func NewRunner(repo Repository, notifier Notifier) *Runner {
+	return &Runner{
+		Repository: repo,
+		Notifier:   notifier,
+		Window:     DefaultWindow,
+	}
+}
+
+// Run sends the reminders which are due at now, and returns the number of the sent notifications.
+// now is truncated to the minute, because the reminder times have no seconds.
+// A reminder is due if its time has come in the time zone of the user, and its habit is active,
+// scheduled on the day and not checked yet.
+// A failure of a reminder does not stop the others, and all failures are returned together.
+func (r *Runner) Run(ctx context.Context, now time.Time) (int, error) {
+	now = now.Truncate(time.Minute)
+	reminders, err := r.Repository.AllReminders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("all reminders: %w", err)
+	}
+
+	locations := map[auth.UserID]*time.Location{}
+	sent := 0
+	var errs []error
+	for _, rem := range reminders {
+		loc, ok := locations[rem.UserID]
+		if !ok {
+			p, err := r.Repository.FindProfile(ctx, rem.UserID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("find profile of user [%s]: %w", rem.UserID, err))
+				continue
+			}
+			loc = p.Location()
+			locations[rem.UserID] = loc
+		}
+
+		for _, n := range r.dueNotifications(rem, now, loc) {
+			ok, err := r.notify(ctx, n)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reminder of habit [%s] at %s on %s: %w", rem.HabitID, n.Time, n.Date, err))
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// dueNotifications returns the notifications of the reminder times in (now-Window, now].
+// The window may span two dates of the user, so both are considered.
+func (r *Runner) dueNotifications(rem *repository.DynamoReminder, now time.Time, loc *time.Location) []*Notification {
+	from := now.Add(-r.Window)
+	days := []time.Time{now.In(loc)}
+	if from.In(loc).Format(dateLayout) != now.In(loc).Format(dateLayout) {
+		days = append([]time.Time{from.In(loc)}, days...)
+	}
+
+	var res []*Notification
+	for _, day := range days {
+		y, m, d := day.Date()
+		for _, s := range rem.Times {
+			t, err := time.Parse(TimeLayout, s)
+			if err != nil {
+				// The times are validated when they are saved.
+				continue
+			}
+			at := time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+			if !at.After(from) || at.After(now) {
+				continue
+			}
+			res = append(res, &Notification{
+				UserID:  rem.UserID,
+				HabitID: rem.HabitID,
+				Date:    day.Format(dateLayout),
+				Time:    s,
+				At:      at,
+			})
+		}
+	}
+	return res
+}
+
+// notify sends the notification if the habit is due on its date. It returns false if the habit is not due.
+func (r *Runner) notify(ctx context.Context, n *Notification) (bool, error) {
+	habit, err := r.Repository.FindHabit(ctx, n.UserID, n.HabitID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// The habit is archived. Its reminder is kept for when it is unarchived.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("find habit: %w", err)
+	}
+	if habit.DeletingAt != nil {
+		return false, nil
+	}
+
+	from := n.At.AddDate(0, 0, -habit.Schedule.EvaluationDays()).Format(dateLayout)
+	checks, err := r.Repository.ListChecksBetween(ctx, n.UserID, n.HabitID, from, n.Date)
+	if err != nil {
+		return false, fmt.Errorf("list checks: %w", err)
+	}
+	sc := make([]schedule.Check, 0, len(checks))
+	for _, c := range checks {
+		sc = append(sc, schedule.Check{Date: c.Date, Ratio: schedule.Ratio(c.Value, habit.Target)})
+	}
+	if schedule.NewEvaluator(habit.Schedule, habit.CreatedAt.In(n.At.Location()), n.At, sc).Current() != schedule.StatusDue {
+		return false, nil
+	}
+
+	n.HabitTitle = habit.Title
+	if err := r.Notifier.Notify(ctx, n); err != nil {
+		return false, fmt.Errorf("notify: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,129 @@
+package reminder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRepository returns the habits as if they were created long ago,
+// because MemoryRepository creates them at the current time.
+type testRepository struct {
+	*repository.MemoryRepository
+}
+
+func (r *testRepository) FindHabit(ctx context.Context, uid auth.UserID, hid string) (*repository.DynamoHabit, error) {
+	h, err := r.MemoryRepository.FindHabit(ctx, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	h.CreatedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return h, nil
+}
+
+func TestRunner_Run(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tokyo := auth.UserID("tokyo")
+	utc := auth.UserID("utc")
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.UpdateProfile(ctx, &repository.DynamoRepositoryUpdateProfileInput{UserID: tokyo, TimeZone: "Asia/Tokyo"}))
+
+	createHabit := func(uid auth.UserID, title string, s schedule.Schedule, times ...string) string {
+		h, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: title, Schedule: s})
+		require.NoError(t, err)
+		require.NoError(t, repo.PutReminder(ctx, &repository.DynamoRepositoryPutReminderInput{UserID: uid, HabitID: h.ID, Times: times}))
+		return h.ID
+	}
+	running := createHabit(tokyo, "Running", schedule.Daily(), "07:00", "18:00")
+	reading := createHabit(tokyo, "Reading", schedule.Daily(), "18:00")
+	_, err := repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: tokyo, HabitID: reading, Date: "2021-01-03"})
+	require.NoError(t, err)
+	createHabit(tokyo, "Gym", schedule.Schedule{Kind: schedule.KindWeekdays, Weekdays: []time.Weekday{time.Monday}}, "18:00")
+	archived := createHabit(tokyo, "Piano", schedule.Daily(), "18:00")
+	require.NoError(t, repo.ArchiveHabit(ctx, tokyo, archived))
+	stretch := createHabit(utc, "Stretch", schedule.Daily(), "08:55", "08:56", "09:00")
+
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	r := NewRunner(&testRepository{repo}, &FileNotifier{Path: path})
+
+	// It is Sunday 18:00 in Tokyo.
+	now := time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC)
+	sent, err := r.Run(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+
+	got := readNotifications(t, path)
+	require.Len(t, got, 3)
+	assert.Equal(t, running, got[0].HabitID)
+	assert.Equal(t, "Running", got[0].HabitTitle)
+	assert.Equal(t, tokyo, got[0].UserID)
+	assert.Equal(t, "2021-01-03", got[0].Date)
+	assert.Equal(t, "18:00", got[0].Time)
+	assert.True(t, now.Equal(got[0].At))
+	for i, want := range []string{"08:56", "09:00"} {
+		assert.Equal(t, stretch, got[i+1].HabitID)
+		assert.Equal(t, want, got[i+1].Time)
+	}
+}
+
+func TestRunner_Run_Midnight(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uid := auth.UserID("123")
+	repo := repository.NewMemoryRepository()
+	h, err := repo.CreateHabit(ctx, &repository.DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Journal", Schedule: schedule.Daily()})
+	require.NoError(t, err)
+	require.NoError(t, repo.PutReminder(ctx, &repository.DynamoRepositoryPutReminderInput{UserID: uid, HabitID: h.ID, Times: []string{"00:01", "23:59"}}))
+
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	r := NewRunner(&testRepository{repo}, &FileNotifier{Path: path})
+
+	// The window spans the previous day.
+	sent, err := r.Run(ctx, time.Date(2021, 1, 4, 0, 2, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	got := readNotifications(t, path)
+	require.Len(t, got, 2)
+	assert.Equal(t, "2021-01-03", got[0].Date)
+	assert.Equal(t, "23:59", got[0].Time)
+	assert.Equal(t, "2021-01-04", got[1].Date)
+	assert.Equal(t, "00:01", got[1].Time)
+
+	// A reminder of the previous day is not sent once the habit is checked on the day.
+	_, err = repo.CreateCheck(ctx, &repository.DynamoRepositoryCreateCheckInput{UserID: uid, HabitID: h.ID, Date: "2021-01-03"})
+	require.NoError(t, err)
+	sent, err = r.Run(ctx, time.Date(2021, 1, 4, 0, 2, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func readNotifications(t *testing.T, path string) []*Notification {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var res []*Notification
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var n Notification
+		require.NoError(t, json.Unmarshal(s.Bytes(), &n))
+		res = append(res, &n)
+	}
+	require.NoError(t, s.Err())
+	return res
+}
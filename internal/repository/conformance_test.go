@@ -0,0 +1,816 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conformanceRepository is the set of operations which every backend implements.
+type conformanceRepository interface {
+	AllArchivedHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error)
+	AllHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error)
+	AllReminders(ctx context.Context) ([]*DynamoReminder, error)
+	ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error
+	BackfillChecks(ctx context.Context, in *DynamoRepositoryBackfillChecksInput) ([]string, error)
+	CreateAccessToken(ctx context.Context, in *DynamoRepositoryCreateAccessTokenInput) (*DynamoAccessToken, error)
+	CreateCheck(ctx context.Context, in *DynamoRepositoryCreateCheckInput) (*DynamoCheck, error)
+	CreateChecks(ctx context.Context, in []*DynamoRepositoryCreateCheckInput) ([]*DynamoCheck, []error)
+	CreateHabit(ctx context.Context, in *DynamoRepositoryCreateHabitInput) (*DynamoHabit, error)
+	CreateWebhook(ctx context.Context, in *DynamoRepositoryCreateWebhookInput) (*DynamoWebhook, error)
+	DeleteAccessToken(ctx context.Context, uid auth.UserID, tid string) error
+	DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error
+	DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error
+	DeleteUserData(ctx context.Context, uid auth.UserID) error
+	DeleteFeedToken(ctx context.Context, uid auth.UserID) error
+	DeleteWebhook(ctx context.Context, uid auth.UserID, wid string) error
+	FindAccessToken(ctx context.Context, uid auth.UserID, tid string) (*DynamoAccessToken, error)
+	FindArchivedHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error)
+	FindHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error)
+	FindFeedToken(ctx context.Context, uid auth.UserID) (*DynamoFeedToken, error)
+	FindProfile(ctx context.Context, uid auth.UserID) (*DynamoProfile, error)
+	FindReminder(ctx context.Context, uid auth.UserID, hid string) (*DynamoReminder, error)
+	ImportChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput) ([]string, error)
+	ListAccessTokens(ctx context.Context, uid auth.UserID) ([]*DynamoAccessToken, error)
+	ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*DynamoCheck, error)
+	ListChecksBetweenInAllHabits(ctx context.Context, uid auth.UserID, from, to string) ([]*DynamoCheck, error)
+	ListChecks(ctx context.Context, in *DynamoRepositoryListChecksInput) (*DynamoRepositoryListChecksOutput, error)
+	ListCheckNotes(ctx context.Context, in *DynamoRepositoryListCheckNotesInput) (*DynamoRepositoryListCheckNotesOutput, error)
+	ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID, today time.Time) ([]*DynamoCheck, error)
+	ListLatestChecksWithLimit(ctx context.Context, uid auth.UserID, hid string, limit int32) ([]*DynamoCheck, error)
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*DynamoWebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, uid auth.UserID, wid string, limit int32) ([]*DynamoWebhookDelivery, error)
+	ListWebhooks(ctx context.Context, uid auth.UserID) ([]*DynamoWebhook, error)
+	PutFeedToken(ctx context.Context, in *DynamoRepositoryPutFeedTokenInput) error
+	PutReminder(ctx context.Context, in *DynamoRepositoryPutReminderInput) error
+	PutWebhookDelivery(ctx context.Context, d *DynamoWebhookDelivery) error
+	TouchAccessToken(ctx context.Context, uid auth.UserID, tid string, at time.Time) error
+	UnarchiveHabit(ctx context.Context, uid auth.UserID, hid string) error
+	UpdateCheckNote(ctx context.Context, in *DynamoRepositoryUpdateCheckNoteInput) error
+	UpdateHabit(ctx context.Context, in *DynamoRepositoryUpdateHabitInput) error
+	UpdateProfile(ctx context.Context, in *DynamoRepositoryUpdateProfileInput) error
+}
+
+// testConformance runs the tests which every backend must pass.
+// newRepo must return an empty repository.
+func testConformance(t *testing.T, newRepo func(t *testing.T) conformanceRepository) {
+	myUserID := auth.UserID("MyUserID")
+	unknownHabitID := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	t.Run("habits", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{
+			UserID:   myUserID,
+			Title:    "Habit1",
+			Schedule: schedule.Schedule{Kind: schedule.KindTimesPerWeek, TimesPerWeek: 3},
+		})
+		require.NoError(t, err)
+		_, err = repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: auth.UserID("OtherUserID"), Title: "Other"})
+		require.NoError(t, err)
+
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, h1, got)
+		all, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Equal(t, []*DynamoHabit{h1}, all)
+
+		_, err = repo.FindHabit(ctx, myUserID, unknownHabitID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		require.NoError(t, repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{
+			UserID:  myUserID,
+			HabitID: h1.ID,
+			Version: h1.Version,
+			Title:   "Renamed",
+			Unit:    "km",
+			Target:  5,
+		}))
+		got, err = repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", got.Title)
+		assert.Equal(t, "km", got.Unit)
+		assert.Equal(t, h1.Version+1, got.Version)
+
+		err = repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{UserID: myUserID, HabitID: h1.ID, Version: h1.Version, Title: "Stale"})
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		err = repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{UserID: myUserID, HabitID: unknownHabitID, Title: "Phantom"})
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1", Unit: "km", Target: 5})
+		require.NoError(t, err)
+
+		c1, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Value: 5})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02", Value: 6, Note: "fast"})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Value: 1})
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-03"})
+		require.ErrorIs(t, err, apperrors.ErrValueRequired)
+		_, err = repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{UserID: myUserID, HabitID: h1.ID, From: "2000-01-03", To: "2000-01-04"})
+		require.ErrorIs(t, err, apperrors.ErrValueRequired)
+
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.ChecksCount)
+		assert.Equal(t, 11.0, got.TotalValue)
+		assert.Equal(t, 2, got.CurrentStreak)
+		assert.Equal(t, 2, got.LongestStreak)
+		assert.Equal(t, "2000-01-02", got.LastCheckDate)
+
+		checks, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 1)
+		require.NoError(t, err)
+		require.Len(t, checks, 1)
+		assert.Equal(t, "2000-01-02", checks[0].Date)
+		checks, err = repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, checks, 2)
+		assert.Equal(t, c1, checks[1])
+
+		require.NoError(t, repo.DeleteCheck(ctx, myUserID, h1.ID, "2000-01-02"))
+		err = repo.DeleteCheck(ctx, myUserID, h1.ID, "2000-01-02")
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		got, err = repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.ChecksCount)
+		assert.Equal(t, "2000-01-01", got.LastCheckDate)
+
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: unknownHabitID, Date: "2000-01-01"})
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		all, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("create checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+		require.NoError(t, err)
+		h3, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit3"})
+		require.NoError(t, err)
+		require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h3.ID))
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h2.ID, Date: "2000-01-01"})
+		require.NoError(t, err)
+
+		checks, errs := repo.CreateChecks(ctx, []*DynamoRepositoryCreateCheckInput{
+			{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Value: 2},
+			{UserID: myUserID, HabitID: h2.ID, Date: "2000-01-01"},
+			{UserID: myUserID, HabitID: h3.ID, Date: "2000-01-01"},
+			{UserID: myUserID, HabitID: "unknown", Date: "2000-01-01"},
+		})
+		require.Len(t, errs, 4)
+		require.Len(t, checks, 4)
+		assert.NoError(t, errs[0])
+		assert.Equal(t, h1.ID, checks[0].HabitID)
+		assert.Equal(t, 2.0, checks[0].Value)
+		assert.False(t, checks[0].CreatedAt.IsZero())
+		assert.Nil(t, checks[1])
+		assert.ErrorIs(t, errs[1], apperrors.ErrConflict)
+		assert.ErrorIs(t, errs[2], apperrors.ErrArchived)
+		assert.ErrorIs(t, errs[3], apperrors.ErrNotFound)
+
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.ChecksCount)
+		assert.Equal(t, 2.0, got.TotalValue)
+		got, err = repo.FindHabit(ctx, myUserID, h2.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.ChecksCount)
+	})
+
+	t.Run("backfill checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-05", Value: 9})
+		require.NoError(t, err)
+
+		// 2000-01-03 is Monday.
+		created, err := repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{
+			UserID:   myUserID,
+			HabitID:  h1.ID,
+			From:     "2000-01-01",
+			To:       "2000-01-10",
+			Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday},
+			Value:    1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000-01-03", "2000-01-07", "2000-01-10"}, created)
+
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, got.ChecksCount)
+		assert.Equal(t, 12.0, got.TotalValue)
+
+		// Backfilling again creates nothing.
+		created, err = repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{UserID: myUserID, HabitID: h1.ID, From: "2000-01-03", To: "2000-01-03"})
+		require.NoError(t, err)
+		assert.Empty(t, created)
+
+		// More checks than a transaction can hold.
+		created, err = repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{UserID: myUserID, HabitID: h1.ID, From: "2000-01-01", To: "2000-12-31"})
+		require.NoError(t, err)
+		assert.Len(t, created, 366-4)
+		got, err = repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 366, got.ChecksCount)
+		assert.Equal(t, "2000-12-31", got.LastCheckDate)
+
+		require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
+		_, err = repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{UserID: myUserID, HabitID: h1.ID, From: "2000-01-01", To: "2000-01-01"})
+		require.ErrorIs(t, err, apperrors.ErrArchived)
+		_, err = repo.BackfillChecks(ctx, &DynamoRepositoryBackfillChecksInput{UserID: myUserID, HabitID: "unknown", From: "2000-01-01", To: "2000-01-01"})
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("import checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02", Value: 9})
+		require.NoError(t, err)
+
+		created, err := repo.ImportChecks(ctx, &DynamoRepositoryImportChecksInput{
+			UserID:  myUserID,
+			HabitID: h1.ID,
+			Checks: []*DynamoRepositoryImportCheck{
+				{Date: "2000-01-03", Value: 3},
+				{Date: "2000-01-02", Value: 2},
+				{Date: "2000-01-01", Value: 1},
+				{Date: "2000-01-03", Value: 4},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000-01-01", "2000-01-03"}, created)
+
+		checks, err := repo.ListChecksBetween(ctx, myUserID, h1.ID, "", "")
+		require.NoError(t, err)
+		values := make(map[string]float64)
+		for _, c := range checks {
+			values[c.Date] = c.Value
+		}
+		assert.Equal(t, map[string]float64{"2000-01-01": 1, "2000-01-02": 9, "2000-01-03": 3}, values)
+
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, got.ChecksCount)
+		assert.Equal(t, 13.0, got.TotalValue)
+		assert.Equal(t, "2000-01-03", got.LastCheckDate)
+
+		_, err = repo.ImportChecks(ctx, &DynamoRepositoryImportChecksInput{
+			UserID:  myUserID,
+			HabitID: h1.ID,
+			Checks:  []*DynamoRepositoryImportCheck{{Date: "2000/01/04"}},
+		})
+		require.Error(t, err)
+
+		// A check of a quantitative habit needs a value, and none of the checks is imported without it.
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2", Target: 2})
+		require.NoError(t, err)
+		_, err = repo.ImportChecks(ctx, &DynamoRepositoryImportChecksInput{
+			UserID:  myUserID,
+			HabitID: h2.ID,
+			Checks:  []*DynamoRepositoryImportCheck{{Date: "2000-01-01", Value: 2}, {Date: "2000-01-02"}},
+		})
+		require.ErrorIs(t, err, apperrors.ErrValueRequired)
+		got, err = repo.FindHabit(ctx, myUserID, h2.ID)
+		require.NoError(t, err)
+		assert.Zero(t, got.ChecksCount)
+	})
+
+	t.Run("last week checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+		require.NoError(t, err)
+		for _, date := range []string{"2000-01-01", "2000-01-03", "2000-01-10"} {
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: date})
+			require.NoError(t, err)
+		}
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h2.ID, Date: "2000-01-05"})
+		require.NoError(t, err)
+
+		checks, err := repo.ListLastWeekChecksInAllHabits(ctx, myUserID, time.Date(2000, 1, 10, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		dates := make([]string, 0, len(checks))
+		for _, c := range checks {
+			dates = append(dates, c.Date)
+		}
+		assert.Equal(t, []string{"2000-01-03", "2000-01-05", "2000-01-10"}, dates)
+	})
+
+	t.Run("checks between", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+		require.NoError(t, err)
+		for _, date := range []string{"2000-01-01", "2000-01-03", "2000-01-05"} {
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: date})
+			require.NoError(t, err)
+		}
+		for _, date := range []string{"2000-01-02", "2000-01-05", "2000-01-06"} {
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h2.ID, Date: date})
+			require.NoError(t, err)
+		}
+
+		keys := func(checks []*DynamoCheck) []string {
+			keys := make([]string, 0, len(checks))
+			for _, c := range checks {
+				name := "h1"
+				if c.HabitID == h2.ID {
+					name = "h2"
+				}
+				keys = append(keys, c.Date+" "+name)
+			}
+			return keys
+		}
+
+		checks, err := repo.ListChecksBetween(ctx, myUserID, h1.ID, "2000-01-03", "2000-01-05")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000-01-03 h1", "2000-01-05 h1"}, keys(checks))
+		checks, err = repo.ListChecksBetween(ctx, myUserID, h1.ID, "", "2000-01-02")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000-01-01 h1"}, keys(checks))
+
+		checks, err = repo.ListChecksBetweenInAllHabits(ctx, myUserID, "2000-01-02", "2000-01-05")
+		require.NoError(t, err)
+		want := []string{"2000-01-02 h2", "2000-01-03 h1", "2000-01-05 h1", "2000-01-05 h2"}
+		// The checks of the same date are in the order of the habit ID.
+		if h2.ID < h1.ID {
+			want[2], want[3] = want[3], want[2]
+		}
+		assert.Equal(t, want, keys(checks))
+		checks, err = repo.ListChecksBetweenInAllHabits(ctx, myUserID, "2000-01-06", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000-01-06 h2"}, keys(checks))
+	})
+
+	t.Run("list checks", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+		require.NoError(t, err)
+		for _, date := range []string{"2000-01-01", "2000-01-02", "2000-01-03", "2000-01-04", "2000-01-05"} {
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: date})
+			require.NoError(t, err)
+		}
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h2.ID, Date: "2000-01-03"})
+		require.NoError(t, err)
+
+		list := func(in DynamoRepositoryListChecksInput) ([]string, string) {
+			t.Helper()
+			in.UserID = myUserID
+			in.HabitID = h1.ID
+			out, err := repo.ListChecks(ctx, &in)
+			require.NoError(t, err)
+			dates := make([]string, 0, len(out.Checks))
+			for _, c := range out.Checks {
+				dates = append(dates, c.Date)
+			}
+			return dates, out.LastEvaluatedDate
+		}
+
+		dates, last := list(DynamoRepositoryListChecksInput{Limit: 2})
+		assert.Equal(t, []string{"2000-01-05", "2000-01-04"}, dates)
+		assert.Equal(t, "2000-01-04", last)
+		dates, last = list(DynamoRepositoryListChecksInput{Limit: 2, After: last})
+		assert.Equal(t, []string{"2000-01-03", "2000-01-02"}, dates)
+		dates, last = list(DynamoRepositoryListChecksInput{Limit: 2, After: last})
+		assert.Equal(t, []string{"2000-01-01"}, dates)
+		assert.Empty(t, last)
+
+		dates, last = list(DynamoRepositoryListChecksInput{Limit: 2, After: "2000-01-02", Ascending: true})
+		assert.Equal(t, []string{"2000-01-03", "2000-01-04"}, dates)
+		assert.Equal(t, "2000-01-04", last)
+
+		dates, last = list(DynamoRepositoryListChecksInput{Limit: 10, From: "2000-01-02", To: "2000-01-04"})
+		assert.Equal(t, []string{"2000-01-04", "2000-01-03", "2000-01-02"}, dates)
+		assert.Empty(t, last)
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Note: "note"}))
+		notes, err := repo.ListCheckNotes(ctx, &DynamoRepositoryListCheckNotesInput{UserID: myUserID, HabitID: h1.ID, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, notes.Checks, 1)
+		assert.Equal(t, "note", notes.Checks[0].Note)
+		assert.Empty(t, notes.LastEvaluatedDate)
+
+		require.NoError(t, repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"}))
+		notes, err = repo.ListCheckNotes(ctx, &DynamoRepositoryListCheckNotesInput{UserID: myUserID, HabitID: h1.ID, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, notes.Checks)
+
+		// The notes are paged from the latest, skipping the checks without a note.
+		for _, d := range []string{"2000-01-02", "2000-01-03", "2000-01-04", "2000-01-05"} {
+			_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: d, Note: "note " + d})
+			require.NoError(t, err)
+		}
+		require.NoError(t, repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-04"}))
+		list := func(in DynamoRepositoryListCheckNotesInput) ([]string, string) {
+			in.UserID, in.HabitID = myUserID, h1.ID
+			out, err := repo.ListCheckNotes(ctx, &in)
+			require.NoError(t, err)
+			dates := []string{}
+			for _, c := range out.Checks {
+				dates = append(dates, c.Date)
+			}
+			return dates, out.LastEvaluatedDate
+		}
+		dates, last := list(DynamoRepositoryListCheckNotesInput{Limit: 2})
+		assert.Equal(t, []string{"2000-01-05", "2000-01-03"}, dates)
+		assert.Equal(t, "2000-01-03", last)
+
+		dates, last = list(DynamoRepositoryListCheckNotesInput{Limit: 2, Before: last})
+		assert.Equal(t, []string{"2000-01-02"}, dates)
+		assert.Empty(t, last)
+
+		err = repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-06", Note: "note"})
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
+
+		_, err = repo.FindHabit(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		archived, err := repo.FindArchivedHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Habit1", archived.Title)
+		all, err := repo.AllArchivedHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		err = repo.ArchiveHabit(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+		require.ErrorIs(t, err, apperrors.ErrArchived)
+
+		require.NoError(t, repo.UnarchiveHabit(ctx, myUserID, h1.ID))
+		err = repo.UnarchiveHabit(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = repo.FindArchivedHabit(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Habit1", got.Title)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+		require.NoError(t, err)
+		for _, h := range []*DynamoHabit{h1, h2} {
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h.ID, Date: "2000-01-01"})
+			require.NoError(t, err)
+		}
+		require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h2.ID))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: h1.ID, Times: []string{"07:00"}}))
+
+		require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+		require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+		_, err = repo.FindHabit(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = repo.FindReminder(ctx, myUserID, h1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		checks, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, checks)
+
+		require.NoError(t, repo.DeleteHabit(ctx, myUserID, h2.ID))
+		archived, err := repo.AllArchivedHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, archived)
+		checks, err = repo.ListLatestChecksWithLimit(ctx, myUserID, h2.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, checks)
+	})
+
+	t.Run("user data", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		p, err := repo.FindProfile(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Equal(t, "", p.TimeZone)
+		require.NoError(t, repo.UpdateProfile(ctx, &DynamoRepositoryUpdateProfileInput{UserID: myUserID, TimeZone: "Asia/Tokyo"}))
+		p, err = repo.FindProfile(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tokyo", p.TimeZone)
+
+		h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+		require.NoError(t, err)
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+		require.NoError(t, err)
+		other, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: auth.UserID("OtherUserID"), Title: "Other"})
+		require.NoError(t, err)
+		require.NoError(t, repo.PutFeedToken(ctx, &DynamoRepositoryPutFeedTokenInput{UserID: myUserID, TokenHash: "hash"}))
+		_, err = repo.CreateAccessToken(ctx, &DynamoRepositoryCreateAccessTokenInput{UserID: myUserID, Name: "CLI", Scope: AccessTokenScopeRead, TokenHash: "hash"})
+		require.NoError(t, err)
+		wh, err := repo.CreateWebhook(ctx, &DynamoRepositoryCreateWebhookInput{UserID: myUserID, URL: "https://example.com", Secret: "secret"})
+		require.NoError(t, err)
+		d := NewDynamoWebhookDelivery(myUserID, wh.ID, uuid.Must(uuid.NewV7()).String())
+		d.Status = WebhookDeliveryPending
+		d.CreatedAt = time.Now().UTC()
+		d.NextAttemptAt = d.CreatedAt
+		require.NoError(t, repo.PutWebhookDelivery(ctx, d))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: h1.ID, Times: []string{"07:00"}}))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: other.UserID, HabitID: other.ID, Times: []string{"07:00"}}))
+
+		require.NoError(t, repo.DeleteUserData(ctx, myUserID))
+		require.NoError(t, repo.DeleteUserData(ctx, myUserID))
+
+		habits, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, habits)
+		checks, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, checks)
+		p, err = repo.FindProfile(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Equal(t, "", p.TimeZone)
+		_, err = repo.FindFeedToken(ctx, myUserID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		tokens, err := repo.ListAccessTokens(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+		webhooks, err := repo.ListWebhooks(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+		due, err := repo.ListDueWebhookDeliveries(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, due)
+		_, err = repo.FindHabit(ctx, auth.UserID("OtherUserID"), other.ID)
+		require.NoError(t, err)
+		reminders, err := repo.AllReminders(ctx)
+		require.NoError(t, err)
+		require.Len(t, reminders, 1)
+		assert.Equal(t, other.ID, reminders[0].HabitID)
+	})
+
+	t.Run("reminder", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		_, err := repo.FindReminder(ctx, myUserID, unknownHabitID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: "h1", Times: []string{"07:00"}}))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: "h1", Times: []string{"07:30", "21:00"}}))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: "h2", Times: []string{"12:00"}}))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: auth.UserID("OtherUserID"), HabitID: "h3", Times: []string{"08:00"}}))
+
+		got, err := repo.FindReminder(ctx, myUserID, "h1")
+		require.NoError(t, err)
+		assert.Equal(t, myUserID, got.UserID)
+		assert.Equal(t, "h1", got.HabitID)
+		assert.Equal(t, []string{"07:30", "21:00"}, got.Times)
+		assert.False(t, got.UpdatedAt.IsZero())
+		_, err = repo.FindReminder(ctx, auth.UserID("OtherUserID"), "h1")
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		reminders, err := repo.AllReminders(ctx)
+		require.NoError(t, err)
+		var keys []string
+		for _, rem := range reminders {
+			keys = append(keys, fmt.Sprintf("%s/%s", rem.UserID, rem.HabitID))
+		}
+		assert.Equal(t, []string{"MyUserID/h1", "MyUserID/h2", "OtherUserID/h3"}, keys)
+
+		// The reminders are not listed as habits.
+		habits, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, habits)
+
+		// No times deletes the reminder.
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: "h1"}))
+		require.NoError(t, repo.PutReminder(ctx, &DynamoRepositoryPutReminderInput{UserID: myUserID, HabitID: "h1"}))
+		_, err = repo.FindReminder(ctx, myUserID, "h1")
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		reminders, err = repo.AllReminders(ctx)
+		require.NoError(t, err)
+		assert.Len(t, reminders, 2)
+	})
+
+	t.Run("feed token", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		_, err := repo.FindFeedToken(ctx, myUserID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		require.NoError(t, repo.PutFeedToken(ctx, &DynamoRepositoryPutFeedTokenInput{UserID: myUserID, TokenHash: "hash1"}))
+		require.NoError(t, repo.PutFeedToken(ctx, &DynamoRepositoryPutFeedTokenInput{UserID: myUserID, TokenHash: "hash2"}))
+		got, err := repo.FindFeedToken(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Equal(t, "hash2", got.TokenHash)
+		assert.False(t, got.CreatedAt.IsZero())
+
+		// The token is not listed as a habit.
+		habits, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, habits)
+
+		require.NoError(t, repo.DeleteFeedToken(ctx, myUserID))
+		require.NoError(t, repo.DeleteFeedToken(ctx, myUserID))
+		_, err = repo.FindFeedToken(ctx, myUserID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("access token", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		tokens, err := repo.ListAccessTokens(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+
+		t1, err := repo.CreateAccessToken(ctx, &DynamoRepositoryCreateAccessTokenInput{UserID: myUserID, Name: "CLI", Scope: AccessTokenScopeRead, TokenHash: "hash1"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, t1.ID)
+		assert.Nil(t, t1.LastUsedAt)
+		assert.False(t, t1.CreatedAt.IsZero())
+		t2, err := repo.CreateAccessToken(ctx, &DynamoRepositoryCreateAccessTokenInput{UserID: myUserID, Name: "Sync", Scope: AccessTokenScopeWrite, TokenHash: "hash2"})
+		require.NoError(t, err)
+
+		got, err := repo.FindAccessToken(ctx, myUserID, t1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, t1, got)
+		_, err = repo.FindAccessToken(ctx, auth.UserID("OtherUserID"), t1.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		tokens, err = repo.ListAccessTokens(ctx, myUserID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"CLI", "Sync"}, []string{tokens[0].Name, tokens[1].Name})
+
+		at := time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.TouchAccessToken(ctx, myUserID, t2.ID, at))
+		got, err = repo.FindAccessToken(ctx, myUserID, t2.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.LastUsedAt)
+		assert.True(t, at.Equal(*got.LastUsedAt))
+		assert.Equal(t, AccessTokenScopeWrite, got.Scope)
+		assert.Equal(t, "hash2", got.TokenHash)
+
+		// The token is not listed as a habit.
+		habits, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, habits)
+
+		require.NoError(t, repo.DeleteAccessToken(ctx, myUserID, t2.ID))
+		require.NoError(t, repo.DeleteAccessToken(ctx, myUserID, t2.ID))
+		_, err = repo.FindAccessToken(ctx, myUserID, t2.ID)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		// A revoked token is not recreated by touching it.
+		require.ErrorIs(t, repo.TouchAccessToken(ctx, myUserID, t2.ID, at), apperrors.ErrNotFound)
+		tokens, err = repo.ListAccessTokens(ctx, myUserID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, t1.ID, tokens[0].ID)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := t.Context()
+
+		wh, err := repo.CreateWebhook(ctx, &DynamoRepositoryCreateWebhookInput{UserID: myUserID, URL: "https://example.com/hook", Secret: "secret"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, wh.ID)
+		webhooks, err := repo.ListWebhooks(ctx, myUserID)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "https://example.com/hook", webhooks[0].URL)
+		assert.Equal(t, "secret", webhooks[0].Secret)
+		assert.True(t, wh.CreatedAt.Equal(webhooks[0].CreatedAt))
+
+		// The deliveries are recent, so that they are not expired.
+		at := time.Now().UTC().Truncate(time.Second)
+		var deliveries []*DynamoWebhookDelivery
+		for i := range 3 {
+			d := NewDynamoWebhookDelivery(myUserID, wh.ID, uuid.Must(uuid.NewV7()).String())
+			d.EventID = fmt.Sprintf("event-%d", i)
+			d.EventType = "check.created"
+			d.Payload = "{}"
+			d.Status = WebhookDeliveryPending
+			d.Attempts = 1
+			d.Error = "connection refused"
+			d.CreatedAt = at
+			d.UpdatedAt = at
+			d.NextAttemptAt = at.Add(time.Duration(i) * 2 * time.Minute)
+			require.NoError(t, repo.PutWebhookDelivery(ctx, d))
+			deliveries = append(deliveries, d)
+		}
+		// A delivery is updated on every attempt.
+		deliveries[2].Status = WebhookDeliverySucceeded
+		deliveries[2].Attempts = 2
+		deliveries[2].StatusCode = 204
+		deliveries[2].Error = ""
+		deliveries[2].UpdatedAt = at.Add(time.Minute)
+		deliveries[2].NextAttemptAt = time.Time{}
+		require.NoError(t, repo.PutWebhookDelivery(ctx, deliveries[2]))
+
+		// Only the pending deliveries whose next attempt is due are listed.
+		due, err := repo.ListDueWebhookDeliveries(ctx, at.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, deliveries[:1], due)
+		due, err = repo.ListDueWebhookDeliveries(ctx, at.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, deliveries[:2], due)
+
+		got, err := repo.ListWebhookDeliveries(ctx, myUserID, wh.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []*DynamoWebhookDelivery{deliveries[2], deliveries[1]}, got)
+		assert.Equal(t, at.Add(WebhookDeliveryRetention).Unix(), got[0].ExpiresAt)
+
+		// The webhooks and the deliveries are not listed as habits.
+		habits, err := repo.AllHabits(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, habits)
+
+		require.NoError(t, repo.DeleteWebhook(ctx, myUserID, wh.ID))
+		require.NoError(t, repo.DeleteWebhook(ctx, myUserID, wh.ID))
+		webhooks, err = repo.ListWebhooks(ctx, myUserID)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+		got, err = repo.ListWebhookDeliveries(ctx, myUserID, wh.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		due, err = repo.ListDueWebhookDeliveries(ctx, at.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, due)
+		// No delivery of a deleted webhook is logged.
+		require.ErrorIs(t, repo.PutWebhookDelivery(ctx, deliveries[0]), apperrors.ErrNotFound)
+	})
+}
+
+func TestDynamoRepository_Conformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) conformanceRepository {
+		return newDynamoRepositoryTest(t)
+	})
+}
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) conformanceRepository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestSQLiteRepository_Conformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) conformanceRepository {
+		repo, err := OpenSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "habits.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
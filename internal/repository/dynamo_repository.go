@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,6 +17,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
 )
 
 type DynamoRepository struct {
@@ -22,14 +26,41 @@ type DynamoRepository struct {
 }
 
 type DynamoHabit struct {
-	PK          string
-	SK          string
-	ID          string `dynamodbav:"UUID"`
-	UserID      auth.UserID
-	Title       string
+	PK       string
+	SK       string
+	ID       string `dynamodbav:"UUID"`
+	UserID   auth.UserID
+	Title    string
+	Schedule schedule.Schedule
+	// Unit and Target are set if the habit is quantitative, such as "run 5 km".
+	Unit        string
+	Target      float64
 	ChecksCount int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// TotalValue is the sum of the values of all checks.
+	TotalValue float64
+	// CurrentStreak is the streak ending on LastCheckDate, which may be already broken today.
+	CurrentStreak int
+	LongestStreak int
+	LastCheckDate string
+	// Version is incremented on every write of the habit and its checks.
+	Version int
+	// DeletingAt is set when the deletion of the habit is started. See DeleteHabit.
+	DeletingAt *time.Time `dynamodbav:",omitempty"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Quantitative reports whether the checks of the habit need a value, which is when it has a unit or a target.
+func (h *DynamoHabit) Quantitative() bool {
+	return h.Unit != "" || h.Target > 0
+}
+
+// validateCheckValue returns apperrors.ErrValueRequired if the habit is quantitative and the value is not positive.
+func validateCheckValue(h *DynamoHabit, v float64) error {
+	if h.Quantitative() && v <= 0 {
+		return fmt.Errorf("habit [%s] needs a positive value: %w", h.ID, apperrors.ErrValueRequired)
+	}
+	return nil
 }
 
 func NewDynamoHabit(userID auth.UserID, habitID string) *DynamoHabit {
@@ -61,8 +92,12 @@ type DynamoCheck struct {
 	CheckDateLSISK string
 	HabitID        string `dynamodbav:"HabitUUID"`
 	Date           string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// Value is the quantity of a check of a quantitative habit.
+	Value float64 `dynamodbav:",omitempty"`
+	// Note is a journal text written about the check.
+	Note      string `dynamodbav:",omitempty"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func NewDynamoCheck(userID auth.UserID, habitID, date string) *DynamoCheck {
@@ -160,9 +195,20 @@ func (r *DynamoRepository) FindHabit(ctx context.Context, uid auth.UserID, hid s
 	return nil, apperrors.ErrNotFound
 }
 
-func (r *DynamoRepository) CreateHabit(ctx context.Context, uid auth.UserID, title string) (*DynamoHabit, error) {
-	h := NewDynamoHabit(uid, uuid.New().String())
-	h.Title = title
+type DynamoRepositoryCreateHabitInput struct {
+	UserID   auth.UserID
+	Title    string
+	Schedule schedule.Schedule
+	Unit     string
+	Target   float64
+}
+
+func (r *DynamoRepository) CreateHabit(ctx context.Context, in *DynamoRepositoryCreateHabitInput) (*DynamoHabit, error) {
+	h := NewDynamoHabit(in.UserID, uuid.New().String())
+	h.Title = in.Title
+	h.Schedule = in.Schedule
+	h.Unit = in.Unit
+	h.Target = in.Target
 	h.CreatedAt = time.Now().Round(time.Nanosecond)
 	h.UpdatedAt = h.CreatedAt
 
@@ -185,43 +231,31 @@ func (r *DynamoRepository) CreateHabit(ctx context.Context, uid auth.UserID, tit
 	return h, nil
 }
 
-func (r *DynamoRepository) DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error {
-	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: &r.TableName,
-		Key:       NewDynamoHabit(uid, hid).GetKey(),
-	})
-	if err != nil {
-		return fmt.Errorf("delete item: %w", err)
-	}
-	return nil
-}
-
 type DynamoRepositoryUpdateHabitInput struct {
 	UserID  auth.UserID
 	HabitID string
-	Title   string
+	// Version is the version of the habit which the update is based on.
+	// The update fails with apperrors.ErrConflict if the habit has been written since then.
+	Version  int
+	Title    string
+	Schedule schedule.Schedule
+	Unit     string
+	Target   float64
 }
 
+// UpdateHabit updates the habit and recomputes its aggregates, which depend on the schedule and the target.
 func (r *DynamoRepository) UpdateHabit(ctx context.Context, in *DynamoRepositoryUpdateHabitInput) error {
-	h := NewDynamoHabit(in.UserID, in.HabitID)
-
-	update := expression.Set(expression.Name("Title"), expression.Value(in.Title))
-	expr, err := expression.NewBuilder().WithUpdate(update).Build()
-	if err != nil {
-		return fmt.Errorf("build expression: %w", err)
-	}
-	_, err = r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName:                 &r.TableName,
-		Key:                       h.GetKey(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		UpdateExpression:          expr.Update(),
-		ReturnValues:              types.ReturnValueNone,
+	return r.writeHabit(ctx, in.UserID, in.HabitID, "", nil, nil, func(h *DynamoHabit, checks []*DynamoCheck) ([]*DynamoCheck, error) {
+		if h.Version != in.Version {
+			return nil, fmt.Errorf("habit [%s] is version %d, not %d: %w", h.ID, h.Version, in.Version, apperrors.ErrConflict)
+		}
+		h.Title = in.Title
+		h.Schedule = in.Schedule
+		h.Unit = in.Unit
+		h.Target = in.Target
+		h.UpdatedAt = time.Now().Round(time.Nanosecond)
+		return checks, nil
 	})
-	if err != nil {
-		return fmt.Errorf("update item: %w", err)
-	}
-	return nil
 }
 
 func (r *DynamoRepository) ListLatestChecksWithLimit(ctx context.Context, uid auth.UserID, hid string, limit int32) ([]*DynamoCheck, error) {
@@ -256,30 +290,144 @@ func (r *DynamoRepository) ListLatestChecksWithLimit(ctx context.Context, uid au
 	return pageItems, nil
 }
 
-func (r *DynamoRepository) ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID) ([]*DynamoCheck, error) {
-	minTime := time.Now().Add(time.Hour * 24 * 7 * -1).Format("2006-01-02")
+type DynamoRepositoryListChecksInput struct {
+	UserID  auth.UserID
+	HabitID string
+	// From and To are the inclusive range of the dates. Empty means unbounded.
+	From string
+	To   string
+	// After is the date which the page starts after, exclusively. It is LastEvaluatedDate of the previous page.
+	After string
+	// Ascending lists the checks from the oldest. By default, they are listed from the latest.
+	Ascending bool
+	Limit     int32
+}
+
+type DynamoRepositoryListChecksOutput struct {
+	Checks []*DynamoCheck
+	// LastEvaluatedDate is the date of LastEvaluatedKey, which is empty if there are no more checks.
+	// As on DynamoDB, it may be set even if the next page turns out to be empty.
+	LastEvaluatedDate string
+}
+
+// ListChecks returns a page of the checks of the habit in the range of the dates.
+func (r *DynamoRepository) ListChecks(ctx context.Context, in *DynamoRepositoryListChecksInput) (*DynamoRepositoryListChecksOutput, error) {
+	pk := fmt.Sprintf("USER#%s", in.UserID)
+	prefix := fmt.Sprintf("HABIT#%s__CHECK_DATE#", in.HabitID)
+	from, to := checkDateRange(in.From, in.To)
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(pk)).
+				And(expression.Key("SK").Between(expression.Value(prefix+from), expression.Value(prefix+to))),
+		).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	q := &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		Limit:                     &in.Limit,
+		ScanIndexForward:          aws.Bool(in.Ascending),
+	}
+	if in.After != "" {
+		q.ExclusiveStartKey = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: prefix + in.After},
+		}
+	}
+
+	resp, err := r.Client.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	out := &DynamoRepositoryListChecksOutput{}
+	if err := attributevalue.UnmarshalListOfMapsWithOptions(resp.Items, &out.Checks); err != nil {
+		return nil, fmt.Errorf("unmarshal items: %w", err)
+	}
+	if sk, ok := resp.LastEvaluatedKey["SK"].(*types.AttributeValueMemberS); ok {
+		out.LastEvaluatedDate = strings.TrimPrefix(sk.Value, prefix)
+	}
+	return out, nil
+}
+
+// checkDateRange fills the unbounded ends of the inclusive range of the dates.
+func checkDateRange(from, to string) (string, string) {
+	if from == "" {
+		from = "0000-01-01"
+	}
+	if to == "" {
+		to = "9999-12-31"
+	}
+	return from, to
+}
+
+// ListLastWeekChecksInAllHabits returns the checks of the last 7 days before today in all habits.
+// today should be in the time zone of the user, so that the window starts on the user's calendar date.
+func (r *DynamoRepository) ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID, today time.Time) ([]*DynamoCheck, error) {
+	return r.ListChecksBetweenInAllHabits(ctx, uid, today.AddDate(0, 0, -7).Format("2006-01-02"), "")
+}
+
+// ListChecksBetween returns the checks of the habit between the dates from and to, inclusive, in ascending order of the date.
+// An empty date means unbounded.
+func (r *DynamoRepository) ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*DynamoCheck, error) {
+	prefix := fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid)
+	from, to = checkDateRange(from, to)
 
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
-				And(expression.Key("CheckDateLSISK").
-					GreaterThanEqual(expression.Value(fmt.Sprintf("CHECK_DATE#%s", minTime))),
-				),
+				And(expression.Key("SK").Between(expression.Value(prefix+from), expression.Value(prefix+to))),
 		).
 		Build()
 	if err != nil {
 		return nil, fmt.Errorf("build expression: %w", err)
 	}
 
-	var checks []*DynamoCheck
-	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+	return r.queryAllChecks(ctx, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+}
+
+// ListChecksBetweenInAllHabits returns the checks of all habits between the dates from and to, inclusive,
+// in ascending order of the date and then the habit ID. An empty date means unbounded.
+func (r *DynamoRepository) ListChecksBetweenInAllHabits(ctx context.Context, uid auth.UserID, from, to string) ([]*DynamoCheck, error) {
+	from, to = checkDateRange(from, to)
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("CheckDateLSISK").Between(
+					expression.Value(fmt.Sprintf("CHECK_DATE#%s", from)),
+					// "~" sorts after "__HABIT#", so that the checks of the date to are included.
+					expression.Value(fmt.Sprintf("CHECK_DATE#%s~", to)),
+				)),
+		).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	return r.queryAllChecks(ctx, &dynamodb.QueryInput{
 		TableName:                 &r.TableName,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
-		ProjectionExpression:      expr.Projection(),
 		IndexName:                 aws.String("CheckDateLSI"),
 	})
+}
+
+// queryAllChecks returns the checks of all pages of the query.
+func (r *DynamoRepository) queryAllChecks(ctx context.Context, in *dynamodb.QueryInput) ([]*DynamoCheck, error) {
+	var checks []*DynamoCheck
+	paginator := dynamodb.NewQueryPaginator(r.Client, in)
 	for paginator.HasMorePages() {
 		resp, err := paginator.NextPage(ctx)
 		if err != nil {
@@ -296,8 +444,18 @@ func (r *DynamoRepository) ListLastWeekChecksInAllHabits(ctx context.Context, ui
 	return checks, nil
 }
 
-func (r *DynamoRepository) CreateCheck(ctx context.Context, uid auth.UserID, hid, date string) (*DynamoCheck, error) {
-	c := NewDynamoCheck(uid, hid, date)
+type DynamoRepositoryCreateCheckInput struct {
+	UserID  auth.UserID
+	HabitID string
+	Date    string
+	Value   float64
+	Note    string
+}
+
+func (r *DynamoRepository) CreateCheck(ctx context.Context, in *DynamoRepositoryCreateCheckInput) (*DynamoCheck, error) {
+	c := NewDynamoCheck(in.UserID, in.HabitID, in.Date)
+	c.Value = in.Value
+	c.Note = in.Note
 	c.CreatedAt = time.Now().Round(time.Nanosecond)
 	c.UpdatedAt = c.CreatedAt
 
@@ -306,14 +464,6 @@ func (r *DynamoRepository) CreateCheck(ctx context.Context, uid auth.UserID, hid
 		return nil, fmt.Errorf("marshal check: %w", err)
 	}
 
-	h := NewDynamoHabit(uid, hid)
-
-	update := expression.Set(expression.Name("ChecksCount"), expression.Name("ChecksCount").Plus(expression.Value(1)))
-	updateExpr, err := expression.NewBuilder().WithUpdate(update).Build()
-	if err != nil {
-		return nil, fmt.Errorf("build update expression: %w", err)
-	}
-
 	condition := expression.Not(
 		expression.AttributeExists(expression.Name("PK")).
 			And(expression.AttributeExists(expression.Name("SK"))),
@@ -323,37 +473,132 @@ func (r *DynamoRepository) CreateCheck(ctx context.Context, uid auth.UserID, hid
 		return nil, fmt.Errorf("build condition expression: %w", err)
 	}
 
-	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
-		TransactItems: []types.TransactWriteItem{
-			{
-				Put: &types.Put{
-					TableName:                 &r.TableName,
-					Item:                      item,
-					ConditionExpression:       conditionExpr.Condition(),
-					ExpressionAttributeNames:  conditionExpr.Names(),
-					ExpressionAttributeValues: conditionExpr.Values(),
-				},
-			},
-			{
-				Update: &types.Update{
-					TableName:                 &r.TableName,
-					Key:                       h.GetKey(),
-					ExpressionAttributeNames:  updateExpr.Names(),
-					ExpressionAttributeValues: updateExpr.Values(),
-					UpdateExpression:          updateExpr.Update(),
-				},
-			},
+	put := types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:                 &r.TableName,
+			Item:                      item,
+			ConditionExpression:       conditionExpr.Condition(),
+			ExpressionAttributeNames:  conditionExpr.Names(),
+			ExpressionAttributeValues: conditionExpr.Values(),
 		},
+	}
+	items := []types.TransactWriteItem{put}
+	if err := r.writeHabit(ctx, in.UserID, in.HabitID, c.Date, items, apperrors.ErrConflict, func(h *DynamoHabit, checks []*DynamoCheck) ([]*DynamoCheck, error) {
+		if err := validateCheckValue(h, c.Value); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(checks, func(v *DynamoCheck) bool { return v.Date == c.Date }) {
+			return nil, fmt.Errorf("check [%s] already exists: %w", c.Date, apperrors.ErrConflict)
+		}
+		return append(checks, c), nil
 	}); err != nil {
-		var tce *types.TransactionCanceledException
-		if errors.As(err, &tce) && *tce.CancellationReasons[0].Code == string(types.BatchStatementErrorCodeEnumConditionalCheckFailed) {
-			return nil, fmt.Errorf("condition check failed %w: %w", apperrors.ErrConflict, tce)
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// maxConcurrentCheckWrites is the number of checks written concurrently by CreateChecks.
+const maxConcurrentCheckWrites = 5
+
+// maxBatchGetItems is the maximum number of keys in a BatchGetItem call.
+const maxBatchGetItems = 100
+
+// CreateChecks creates the checks of several habits, and returns the created checks and the error of each check in the order of in.
+// The checks which already exist are found by batched reads first, so that they fail without a transaction.
+// Each of the others is written in a transaction with its habit as in CreateCheck, since the aggregates of a habit
+// are updated from its checks, and a transaction across the habits would fail every check when one of them fails.
+func (r *DynamoRepository) CreateChecks(ctx context.Context, in []*DynamoRepositoryCreateCheckInput) ([]*DynamoCheck, []error) {
+	checks := make([]*DynamoCheck, len(in))
+	errs := make([]error, len(in))
+	existing, err := r.existingChecks(ctx, in)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
 		}
+		return checks, errs
+	}
 
-		return nil, fmt.Errorf("transact write items: %w", err)
+	sem := make(chan struct{}, maxConcurrentCheckWrites)
+	var wg sync.WaitGroup
+	for i, c := range in {
+		if existing[NewDynamoCheck(c.UserID, c.HabitID, c.Date).SK] {
+			errs[i] = fmt.Errorf("check [%s] already exists: %w", c.Date, apperrors.ErrConflict)
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			checks[i], errs[i] = r.CreateCheck(ctx, c)
+		}()
 	}
+	wg.Wait()
+	return checks, errs
+}
 
-	return c, nil
+// existingChecks returns the set of the sort keys of the checks of in which already exist, reading them in batches.
+// in must be the checks of a user.
+func (r *DynamoRepository) existingChecks(ctx context.Context, in []*DynamoRepositoryCreateCheckInput) (map[string]bool, error) {
+	var keys []map[string]types.AttributeValue
+	seen := make(map[string]bool, len(in))
+	for _, c := range in {
+		check := NewDynamoCheck(c.UserID, c.HabitID, c.Date)
+		// BatchGetItem rejects duplicate keys.
+		if !seen[check.SK] {
+			seen[check.SK] = true
+			keys = append(keys, check.GetKey())
+		}
+	}
+
+	expr, err := expression.NewBuilder().
+		WithProjection(expression.NamesList(expression.Name("PK"), expression.Name("SK"))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for len(keys) > 0 {
+		n := min(len(keys), maxBatchGetItems)
+		req := map[string]types.KeysAndAttributes{r.TableName: {
+			Keys:                     keys[:n],
+			ProjectionExpression:     expr.Projection(),
+			ExpressionAttributeNames: expr.Names(),
+		}}
+		backoff := 50 * time.Millisecond
+		for attempt := 0; len(req) > 0; attempt++ {
+			if attempt == maxBatchWriteAttempts {
+				return nil, fmt.Errorf("%d keys are not processed after %d attempts", len(req[r.TableName].Keys), attempt)
+			}
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+
+			resp, err := r.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: req})
+			if err != nil {
+				return nil, fmt.Errorf("batch get item: %w", err)
+			}
+			var items []*DynamoCheck
+			if err := attributevalue.UnmarshalListOfMapsWithOptions(resp.Responses[r.TableName], &items); err != nil {
+				return nil, fmt.Errorf("unmarshal items: %w", err)
+			}
+			for _, c := range items {
+				existing[c.SK] = true
+			}
+			req = resp.UnprocessedKeys
+		}
+		keys = keys[n:]
+	}
+	return existing, nil
 }
 
 func (r *DynamoRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error {
@@ -361,13 +606,6 @@ func (r *DynamoRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid
 		PK: fmt.Sprintf("USER#%s", uid),
 		SK: fmt.Sprintf("HABIT#%s__CHECK_DATE#%s", hid, date),
 	}
-	h := NewDynamoHabit(uid, hid)
-
-	update := expression.Set(expression.Name("ChecksCount"), expression.Name("ChecksCount").Plus(expression.Value(-1)))
-	updateExpr, err := expression.NewBuilder().WithUpdate(update).Build()
-	if err != nil {
-		return fmt.Errorf("build update expression: %w", err)
-	}
 
 	condition := expression.AttributeExists(expression.Name("PK")).
 		And(expression.AttributeExists(expression.Name("SK")))
@@ -376,34 +614,150 @@ func (r *DynamoRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid
 		return fmt.Errorf("build condition expression: %w", err)
 	}
 
-	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
-		TransactItems: []types.TransactWriteItem{
-			{
-				Delete: &types.Delete{
-					TableName:                 &r.TableName,
-					Key:                       c.GetKey(),
-					ConditionExpression:       conditionExpr.Condition(),
-					ExpressionAttributeNames:  conditionExpr.Names(),
-					ExpressionAttributeValues: conditionExpr.Values(),
-				},
-			},
-			{
-				Update: &types.Update{
-					TableName:                 &r.TableName,
-					Key:                       h.GetKey(),
-					ExpressionAttributeNames:  updateExpr.Names(),
-					ExpressionAttributeValues: updateExpr.Values(),
-					UpdateExpression:          updateExpr.Update(),
-				},
-			},
+	del := types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName:                 &r.TableName,
+			Key:                       c.GetKey(),
+			ConditionExpression:       conditionExpr.Condition(),
+			ExpressionAttributeNames:  conditionExpr.Names(),
+			ExpressionAttributeValues: conditionExpr.Values(),
 		},
-	}); err != nil {
-		var tce *types.TransactionCanceledException
-		if errors.As(err, &tce) && *tce.CancellationReasons[0].Code == string(types.BatchStatementErrorCodeEnumConditionalCheckFailed) {
-			return fmt.Errorf("condition check failed: %w: %w", apperrors.ErrNotFound, tce)
+	}
+	items := []types.TransactWriteItem{del}
+	return r.writeHabit(ctx, uid, hid, date, items, apperrors.ErrNotFound, func(_ *DynamoHabit, checks []*DynamoCheck) ([]*DynamoCheck, error) {
+		i := slices.IndexFunc(checks, func(v *DynamoCheck) bool { return v.Date == date })
+		if i < 0 {
+			return nil, fmt.Errorf("check [%s] does not exist: %w", date, apperrors.ErrNotFound)
 		}
+		return slices.Delete(checks, i, i+1), nil
+	})
+}
+
+type DynamoRepositoryUpdateCheckNoteInput struct {
+	UserID  auth.UserID
+	HabitID string
+	Date    string
+	Note    string
+}
+
+// UpdateCheckNote replaces the note of the check. An empty note removes it.
+func (r *DynamoRepository) UpdateCheckNote(ctx context.Context, in *DynamoRepositoryUpdateCheckNoteInput) error {
+	c := NewDynamoCheck(in.UserID, in.HabitID, in.Date)
+
+	var update expression.UpdateBuilder
+	if in.Note == "" {
+		update = expression.Remove(expression.Name("Note"))
+	} else {
+		update = expression.Set(expression.Name("Note"), expression.Value(in.Note))
+	}
+	update = update.Set(expression.Name("UpdatedAt"), expression.Value(time.Now().Round(time.Nanosecond)))
+
+	condition := expression.AttributeExists(expression.Name("PK")).
+		And(expression.AttributeExists(expression.Name("SK")))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
 
-		return fmt.Errorf("transact write items: %w", err)
+	if _, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.TableName,
+		Key:                       c.GetKey(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("condition check failed: %w: %w", apperrors.ErrNotFound, ccf)
+		}
+		return fmt.Errorf("update item: %w", err)
 	}
 	return nil
 }
+
+// maxNoteScanChecks is the maximum number of checks which ListCheckNotes reads in a call,
+// so that a page of a habit with many checks but few notes is still bounded.
+const maxNoteScanChecks = 500
+
+type DynamoRepositoryListCheckNotesInput struct {
+	UserID  auth.UserID
+	HabitID string
+	// Before is the date which the page starts before, exclusively. It is LastEvaluatedDate of the previous page.
+	Before string
+	Limit  int32
+}
+
+type DynamoRepositoryListCheckNotesOutput struct {
+	// Checks are the checks which have a note, from the latest.
+	Checks []*DynamoCheck
+	// LastEvaluatedDate is the date which the next page starts before, which is empty if there are no more checks.
+	// As on ListChecks, it may be set even if the next page turns out to be empty.
+	LastEvaluatedDate string
+}
+
+// ListCheckNotes returns a page of the checks of the habit which have a note, from the latest.
+// It reads at most maxNoteScanChecks checks, so the page may have fewer notes than the limit even if there are older ones.
+func (r *DynamoRepository) ListCheckNotes(ctx context.Context, in *DynamoRepositoryListCheckNotesInput) (*DynamoRepositoryListCheckNotesOutput, error) {
+	pk := fmt.Sprintf("USER#%s", in.UserID)
+	prefix := fmt.Sprintf("HABIT#%s__CHECK_DATE#", in.HabitID)
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(pk)).
+				And(expression.Key("SK").BeginsWith(prefix)),
+		).
+		WithFilter(expression.AttributeExists(expression.Name("Note"))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	q := &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		Limit:                     aws.Int32(maxNoteScanChecks),
+		ScanIndexForward:          aws.Bool(false),
+	}
+	if in.Before != "" {
+		q.ExclusiveStartKey = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: prefix + in.Before},
+		}
+	}
+
+	out := &DynamoRepositoryListCheckNotesOutput{}
+	scanned := int32(0)
+	for {
+		resp, err := r.Client.Query(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+		var pageItems []*DynamoCheck
+		if err := attributevalue.UnmarshalListOfMapsWithOptions(resp.Items, &pageItems); err != nil {
+			return nil, fmt.Errorf("unmarshal items: %w", err)
+		}
+		for _, c := range pageItems {
+			if len(out.Checks) == int(in.Limit) {
+				out.LastEvaluatedDate = out.Checks[len(out.Checks)-1].Date
+				return out, nil
+			}
+			out.Checks = append(out.Checks, c)
+		}
+
+		sk, ok := resp.LastEvaluatedKey["SK"].(*types.AttributeValueMemberS)
+		if !ok {
+			return out, nil
+		}
+		scanned += resp.ScannedCount
+		if scanned >= maxNoteScanChecks {
+			out.LastEvaluatedDate = strings.TrimPrefix(sk.Value, prefix)
+			return out, nil
+		}
+		q.ExclusiveStartKey = resp.LastEvaluatedKey
+		q.Limit = aws.Int32(maxNoteScanChecks - scanned)
+	}
+}
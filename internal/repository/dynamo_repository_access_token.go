@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// AccessTokenScope is what a personal access token is allowed to do.
+type AccessTokenScope string
+
+const (
+	// AccessTokenScopeRead allows only reading.
+	AccessTokenScopeRead AccessTokenScope = "read"
+	// AccessTokenScopeWrite allows reading and writing.
+	AccessTokenScopeWrite AccessTokenScope = "write"
+)
+
+// DynamoAccessToken is a personal access token of a user for the JSON API.
+// Only the hash of the secret of the token is stored.
+type DynamoAccessToken struct {
+	PK        string
+	SK        string
+	ID        string `dynamodbav:"UUID"`
+	UserID    auth.UserID
+	Name      string
+	Scope     AccessTokenScope
+	TokenHash string
+	// LastUsedAt is nil if the token has never been used.
+	LastUsedAt *time.Time `dynamodbav:",omitempty"`
+	CreatedAt  time.Time
+}
+
+func NewDynamoAccessToken(userID auth.UserID, tokenID string) *DynamoAccessToken {
+	return &DynamoAccessToken{
+		PK:     fmt.Sprintf("USER#%s", userID),
+		SK:     fmt.Sprintf("ACCESS_TOKEN#%s", tokenID),
+		ID:     tokenID,
+		UserID: userID,
+	}
+}
+
+// GetKey returns the composite primary key of the access token in a format that can be
+// sent to DynamoDB.
+func (t *DynamoAccessToken) GetKey() map[string]types.AttributeValue {
+	pk, err := attributevalue.Marshal(t.PK)
+	if err != nil {
+		panic(fmt.Errorf("marshal PK: %w", err))
+	}
+	sk, err := attributevalue.Marshal(t.SK)
+	if err != nil {
+		panic(fmt.Errorf("marshal SK: %w", err))
+	}
+	return map[string]types.AttributeValue{"PK": pk, "SK": sk}
+}
+
+type DynamoRepositoryCreateAccessTokenInput struct {
+	UserID    auth.UserID
+	Name      string
+	Scope     AccessTokenScope
+	TokenHash string
+}
+
+func (r *DynamoRepository) CreateAccessToken(ctx context.Context, in *DynamoRepositoryCreateAccessTokenInput) (*DynamoAccessToken, error) {
+	t := NewDynamoAccessToken(in.UserID, uuid.New().String())
+	t.Name = in.Name
+	t.Scope = in.Scope
+	t.TokenHash = in.TokenHash
+	t.CreatedAt = time.Now().Round(time.Nanosecond)
+
+	item, err := attributevalue.MarshalMap(t)
+	if err != nil {
+		return nil, fmt.Errorf("marshal access token: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.TableName,
+		Item:      item,
+	}); err != nil {
+		return nil, fmt.Errorf("put item: %w", err)
+	}
+	return t, nil
+}
+
+// ListAccessTokens returns the access tokens of the user in the order of their IDs.
+func (r *DynamoRepository) ListAccessTokens(ctx context.Context, uid auth.UserID) ([]*DynamoAccessToken, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").BeginsWith("ACCESS_TOKEN#")),
+		).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	var tokens []*DynamoAccessToken
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query paginator: %w", err)
+		}
+
+		var pageItems []*DynamoAccessToken
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &pageItems); err != nil {
+			return nil, fmt.Errorf("unmarshal items: %w", err)
+		}
+		tokens = append(tokens, pageItems...)
+	}
+	return tokens, nil
+}
+
+// FindAccessToken returns the access token, or apperrors.ErrNotFound if it does not exist or is revoked.
+func (r *DynamoRepository) FindAccessToken(ctx context.Context, uid auth.UserID, tid string) (*DynamoAccessToken, error) {
+	t := NewDynamoAccessToken(uid, tid)
+	resp, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.TableName,
+		Key:            t.GetKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+	if resp.Item == nil {
+		return nil, fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+	}
+	if err := attributevalue.UnmarshalMap(resp.Item, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal item: %w", err)
+	}
+	return t, nil
+}
+
+// TouchAccessToken sets LastUsedAt of the access token. It does not recreate a revoked token.
+func (r *DynamoRepository) TouchAccessToken(ctx context.Context, uid auth.UserID, tid string, at time.Time) error {
+	expr, err := expression.NewBuilder().
+		WithUpdate(expression.Set(expression.Name("LastUsedAt"), expression.Value(at.Round(time.Nanosecond)))).
+		WithCondition(expression.AttributeExists(expression.Name("PK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	if _, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.TableName,
+		Key:                       NewDynamoAccessToken(uid, tid).GetKey(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}); err != nil {
+		var ccfe *types.ConditionalCheckFailedException
+		if errors.As(err, &ccfe) {
+			return fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+		}
+		return fmt.Errorf("update item: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccessToken revokes the access token. Deleting a token which does not exist is not an error.
+func (r *DynamoRepository) DeleteAccessToken(ctx context.Context, uid auth.UserID, tid string) error {
+	if _, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.TableName,
+		Key:       NewDynamoAccessToken(uid, tid).GetKey(),
+	}); err != nil {
+		return fmt.Errorf("delete item: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+)
+
+// maxHabitWriteAttempts is the number of attempts to write a habit while it is updated concurrently.
+const maxHabitWriteAttempts = 5
+
+// errHabitChanged is returned when the habit is updated after it was read.
+var errHabitChanged = errors.New("habit changed")
+
+// writeHabit updates the habit in a transaction together with the given items.
+// mutate changes the habit and returns its checks since a date as they are after the items are written,
+// and then the aggregates of the habit are updated from the changed checks.
+// since is the first date of the checks which the items change, or empty if the aggregates must be
+// recomputed from all checks, such as when the schedule is changed.
+// The habit is written only if its Version is unchanged since it was read,
+// and a concurrent write is retried from the read.
+// itemErr is returned when the condition of one of the items fails.
+func (r *DynamoRepository) writeHabit(
+	ctx context.Context,
+	uid auth.UserID,
+	hid string,
+	since string,
+	items []types.TransactWriteItem,
+	itemErr error,
+	mutate func(h *DynamoHabit, checks []*DynamoCheck) ([]*DynamoCheck, error),
+) error {
+	for range maxHabitWriteAttempts {
+		err := r.tryWriteHabit(ctx, uid, hid, since, items, itemErr, mutate)
+		if errors.Is(err, errHabitChanged) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("habit [%s] is updated concurrently: %w", hid, apperrors.ErrConflict)
+}
+
+func (r *DynamoRepository) tryWriteHabit(
+	ctx context.Context,
+	uid auth.UserID,
+	hid string,
+	since string,
+	items []types.TransactWriteItem,
+	itemErr error,
+	mutate func(h *DynamoHabit, checks []*DynamoCheck) ([]*DynamoCheck, error),
+) error {
+	h, err := r.activeHabit(ctx, uid, hid)
+	if err != nil {
+		return err
+	}
+	version := h.Version
+
+	// The streaks are evaluated until the last check, so moving it changes the streak ending on it.
+	if since != "" && h.LastCheckDate != "" {
+		since = min(since, h.LastCheckDate)
+	}
+	from, before, err := r.recentCheckValues(ctx, uid, h, since)
+	if err != nil {
+		return fmt.Errorf("list recent check values: %w", err)
+	}
+	after, err := mutate(h, slices.Clone(before))
+	if err != nil {
+		return err
+	}
+
+	if from == "" {
+		aggregate(h, after)
+	} else if !aggregateRecent(h, before, after) {
+		all, err := r.listCheckValues(ctx, uid, hid, "", "")
+		if err != nil {
+			return fmt.Errorf("list check values: %w", err)
+		}
+		older := slices.DeleteFunc(all, func(c *DynamoCheck) bool { return c.Date >= from })
+		aggregate(h, append(older, after...))
+	}
+
+	update := expression.Set(expression.Name("Title"), expression.Value(h.Title)).
+		Set(expression.Name("Schedule"), expression.Value(h.Schedule)).
+		Set(expression.Name("Unit"), expression.Value(h.Unit)).
+		Set(expression.Name("Target"), expression.Value(h.Target)).
+		Set(expression.Name("UpdatedAt"), expression.Value(h.UpdatedAt)).
+		Set(expression.Name("ChecksCount"), expression.Value(h.ChecksCount)).
+		Set(expression.Name("TotalValue"), expression.Value(h.TotalValue)).
+		Set(expression.Name("CurrentStreak"), expression.Value(h.CurrentStreak)).
+		Set(expression.Name("LongestStreak"), expression.Value(h.LongestStreak)).
+		Set(expression.Name("LastCheckDate"), expression.Value(h.LastCheckDate)).
+		Set(expression.Name("Version"), expression.Value(version+1))
+
+	condition := expression.AttributeExists(expression.Name("PK")).And(versionCondition(version))
+
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append(slices.Clip(items), types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                 &r.TableName,
+				Key:                       h.GetKey(),
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+				UpdateExpression:          expr.Update(),
+			},
+		}),
+	}); err != nil {
+		var tce *types.TransactionCanceledException
+		if errors.As(err, &tce) {
+			if cerr := cancellationError(tce, len(items), itemErr); cerr != nil {
+				return cerr
+			}
+		}
+
+		return fmt.Errorf("transact write items: %w", err)
+	}
+	return nil
+}
+
+// aggregate recomputes the aggregates of the habit from all of its checks.
+func aggregate(h *DynamoHabit, checks []*DynamoCheck) {
+	h.ChecksCount = len(checks)
+	h.TotalValue = totalValue(checks)
+	h.CurrentStreak, h.LongestStreak = streaks(h, checks)
+	h.LastCheckDate = lastCheckDate(checks)
+}
+
+// aggregateRecent updates the aggregates of the habit for a change of its recent checks from before to after,
+// which are the checks since a date before which no check is changed and a streak is broken. See recentCheckValues.
+// The streaks which include the changed checks and the last check are in the recent checks, and the others are unchanged.
+// It returns false if the aggregates can not be updated from the recent checks, which is when the longest streak
+// may be among the recent ones and is shortened, or when no recent check is left but older ones.
+func aggregateRecent(h *DynamoHabit, before, after []*DynamoCheck) bool {
+	if len(after) == 0 && h.ChecksCount > len(before) {
+		return false
+	}
+	_, recentLongest := streaks(h, before)
+	current, longest := streaks(h, after)
+	switch {
+	case recentLongest < h.LongestStreak:
+		// The longest streak is an older one, which is unchanged.
+		longest = max(longest, h.LongestStreak)
+	case longest < h.LongestStreak:
+		return false
+	}
+
+	h.ChecksCount += len(after) - len(before)
+	h.TotalValue += totalValue(after) - totalValue(before)
+	h.CurrentStreak = current
+	h.LongestStreak = longest
+	h.LastCheckDate = lastCheckDate(after)
+	return true
+}
+
+func streaks(h *DynamoHabit, checks []*DynamoCheck) (current, longest int) {
+	return schedule.Streaks(h.Schedule, checkRatios(h, checks))
+}
+
+// checkRatios returns the checks for evaluating the schedule of the habit.
+func checkRatios(h *DynamoHabit, checks []*DynamoCheck) []schedule.Check {
+	ratios := make([]schedule.Check, 0, len(checks))
+	for _, c := range checks {
+		ratios = append(ratios, schedule.Check{Date: c.Date, Ratio: schedule.Ratio(c.Value, h.Target)})
+	}
+	return ratios
+}
+
+func totalValue(checks []*DynamoCheck) float64 {
+	total := 0.0
+	for _, c := range checks {
+		total += c.Value
+	}
+	return total
+}
+
+func lastCheckDate(checks []*DynamoCheck) string {
+	last := ""
+	for _, c := range checks {
+		last = max(last, c.Date)
+	}
+	return last
+}
+
+// versionCondition returns a condition that the habit is still the given version.
+func versionCondition(version int) expression.ConditionBuilder {
+	cond := expression.Name("Version").Equal(expression.Value(version))
+	if version == 0 {
+		// Habits created before versioning have no Version attribute.
+		cond = cond.Or(expression.AttributeNotExists(expression.Name("Version")))
+	}
+	return cond
+}
+
+// cancellationError maps the cancellation reasons of a transaction written by tryWriteHabit to an error.
+// The first n items are the given items and the last one is the habit.
+// It returns nil if no reason is known, such as a throttled request.
+func cancellationError(tce *types.TransactionCanceledException, n int, itemErr error) error {
+	for i, reason := range tce.CancellationReasons {
+		if reason.Code == nil {
+			continue
+		}
+		switch types.BatchStatementErrorCodeEnum(*reason.Code) {
+		case types.BatchStatementErrorCodeEnumConditionalCheckFailed:
+			if i < n {
+				return fmt.Errorf("condition check failed: %w: %w", itemErr, tce)
+			}
+			// The habit is written, archived or deleted after it was read.
+			return errHabitChanged
+		case types.BatchStatementErrorCodeEnumTransactionConflict:
+			return errHabitChanged
+		}
+	}
+	return nil
+}
+
+// activeHabit returns the habit which can be written, with a strongly consistent read.
+// It returns apperrors.ErrArchived if the habit is archived, and apperrors.ErrNotFound if it is missing or being deleted.
+func (r *DynamoRepository) activeHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	h, err := r.getHabit(ctx, uid, hid)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		if _, aerr := r.FindArchivedHabit(ctx, uid, hid); aerr == nil {
+			return nil, fmt.Errorf("habit [%s]: %w", hid, apperrors.ErrArchived)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get a habit [%s]: %w", hid, err)
+	}
+	if h.DeletingAt != nil {
+		return nil, fmt.Errorf("habit [%s] is being deleted: %w", hid, apperrors.ErrNotFound)
+	}
+	return h, nil
+}
+
+// getHabit returns the habit with a strongly consistent read.
+func (r *DynamoRepository) getHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	h := NewDynamoHabit(uid, hid)
+	resp, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.TableName,
+		Key:            h.GetKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+	if resp.Item == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if err := attributevalue.UnmarshalMap(resp.Item, &h); err != nil {
+		return nil, fmt.Errorf("unmarshal item: %w", err)
+	}
+	return h, nil
+}
+
+// recentCheckValues returns the date from which the checks of the habit are needed to update its aggregates
+// for a change of the checks since the given date, and the checks since then. See recentChecks.
+// An empty since returns all checks with an empty date.
+func (r *DynamoRepository) recentCheckValues(ctx context.Context, uid auth.UserID, h *DynamoHabit, since string) (string, []*DynamoCheck, error) {
+	if since == "" {
+		checks, err := r.listCheckValues(ctx, uid, h.ID, "", "")
+		return "", checks, err
+	}
+	return recentChecks(h, since,
+		func(from string) ([]*DynamoCheck, error) { return r.listCheckValues(ctx, uid, h.ID, from, "") },
+		func() (string, error) { return r.firstCheckDate(ctx, uid, h.ID) },
+	)
+}
+
+// recentChecks returns a date early enough that a streak of the habit is broken between it and the given date,
+// or that no check is before it, and the checks since then. The date is moved back while neither is true.
+// list returns the checks since a date, and first returns the date of the first check, which is empty if none.
+func recentChecks(
+	h *DynamoHabit,
+	since string,
+	list func(from string) ([]*DynamoCheck, error),
+	first func() (string, error),
+) (string, []*DynamoCheck, error) {
+	to, err := time.Parse("2006-01-02", since)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse date: %w", err)
+	}
+
+	firstDate, firstLoaded := "", false
+	for days := 2 * h.Schedule.EvaluationDays(); ; days *= 2 {
+		from := to.AddDate(0, 0, -days)
+		checks, err := list(from.Format("2006-01-02"))
+		if err != nil {
+			return "", nil, err
+		}
+		if schedule.Broken(h.Schedule, checkRatios(h, checks), from, to) {
+			return from.Format("2006-01-02"), checks, nil
+		}
+		if !firstLoaded {
+			if firstDate, err = first(); err != nil {
+				return "", nil, err
+			}
+			firstLoaded = true
+		}
+		if firstDate == "" || firstDate >= from.Format("2006-01-02") {
+			return from.Format("2006-01-02"), checks, nil
+		}
+	}
+}
+
+// listCheckValues returns the dates and the values of the checks of the habit between the dates from and to, inclusive,
+// with strongly consistent reads. An empty date means unbounded.
+func (r *DynamoRepository) listCheckValues(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*DynamoCheck, error) {
+	prefix := fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid)
+	from, to = checkDateRange(from, to)
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").Between(expression.Value(prefix+from), expression.Value(prefix+to))),
+		).
+		WithProjection(expression.NamesList(expression.Name("Date"), expression.Name("Value"))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	checks, err := r.queryAllChecks(ctx, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+		ConsistentRead:            aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query checks: %w", err)
+	}
+	return checks, nil
+}
+
+// firstCheckDate returns the date of the first check of the habit with a strongly consistent read,
+// or an empty string if the habit has no checks.
+func (r *DynamoRepository) firstCheckDate(ctx context.Context, uid auth.UserID, hid string) (string, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").BeginsWith(fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid))),
+		).
+		WithProjection(expression.NamesList(expression.Name("Date"))).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("build expression: %w", err)
+	}
+
+	resp, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+		ConsistentRead:            aws.Bool(true),
+		Limit:                     aws.Int32(1),
+	})
+	if err != nil {
+		return "", fmt.Errorf("query: %w", err)
+	}
+	if len(resp.Items) == 0 {
+		return "", nil
+	}
+	var c DynamoCheck
+	if err := attributevalue.UnmarshalMap(resp.Items[0], &c); err != nil {
+		return "", fmt.Errorf("unmarshal item: %w", err)
+	}
+	return c.Date, nil
+}
+
+// isConcurrentWrite reports whether the transaction is canceled because one of its items is written concurrently,
+// which is a failed condition on a version or a conflict with another transaction.
+func isConcurrentWrite(tce *types.TransactionCanceledException) bool {
+	for _, reason := range tce.CancellationReasons {
+		if reason.Code == nil {
+			continue
+		}
+		switch types.BatchStatementErrorCodeEnum(*reason.Code) {
+		case types.BatchStatementErrorCodeEnumConditionalCheckFailed, types.BatchStatementErrorCodeEnumTransactionConflict:
+			return true
+		}
+	}
+	return false
+}
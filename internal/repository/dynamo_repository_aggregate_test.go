@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAggregateRecent checks that updating the aggregates from the recent checks, as tryWriteHabit does,
+// gives the same aggregates as recomputing them from all checks.
+func TestAggregateRecent(t *testing.T) {
+	t.Parallel()
+
+	schedules := []schedule.Schedule{
+		schedule.Daily(),
+		{Kind: schedule.KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Thursday}},
+		{Kind: schedule.KindTimesPerWeek, TimesPerWeek: 3},
+		{Kind: schedule.KindInterval, IntervalDays: 10},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	for i := range 2000 {
+		h := &DynamoHabit{Schedule: schedules[i%len(schedules)]}
+		if i%3 == 0 {
+			h.Target = 2
+		}
+		// Long runs of checks with a few gaps, so that the streaks are longer than the first window.
+		var all []*DynamoCheck
+		for d := range 200 {
+			if rnd.IntN(2+i%30) > 0 {
+				all = append(all, &DynamoCheck{Date: start.AddDate(0, 0, d).Format("2006-01-02"), Value: float64(rnd.IntN(3))})
+			}
+		}
+		aggregate(h, all)
+
+		date := start.AddDate(0, 0, rnd.IntN(210)).Format("2006-01-02")
+		mutate := func(checks []*DynamoCheck) []*DynamoCheck {
+			if i := slices.IndexFunc(checks, func(c *DynamoCheck) bool { return c.Date == date }); i >= 0 {
+				return slices.Delete(checks, i, i+1)
+			}
+			return append(checks, &DynamoCheck{Date: date, Value: 1})
+		}
+
+		since := func(from string) []*DynamoCheck {
+			return slices.DeleteFunc(slices.Clone(all), func(c *DynamoCheck) bool { return c.Date < from })
+		}
+		first := ""
+		if len(all) > 0 {
+			first = all[0].Date
+		}
+		// As tryWriteHabit does, the recent checks include the last check.
+		from, before, err := recentChecks(h, min(date, h.LastCheckDate),
+			func(from string) ([]*DynamoCheck, error) { return since(from), nil },
+			func() (string, error) { return first, nil },
+		)
+		require.NoError(t, err)
+		after := mutate(slices.Clone(before))
+
+		got := *h
+		if !aggregateRecent(&got, before, after) {
+			older := slices.DeleteFunc(slices.Clone(all), func(c *DynamoCheck) bool { return c.Date >= from })
+			aggregate(&got, append(older, after...))
+		}
+		want := *h
+		aggregate(&want, mutate(slices.Clone(all)))
+
+		require.Equal(t, want.ChecksCount, got.ChecksCount, "case %d", i)
+		require.InDelta(t, want.TotalValue, got.TotalValue, 1e-9, "case %d", i)
+		require.Equal(t, want.CurrentStreak, got.CurrentStreak, "case %d", i)
+		require.Equal(t, want.LongestStreak, got.LongestStreak, "case %d", i)
+		require.Equal(t, want.LastCheckDate, got.LastCheckDate, "case %d", i)
+	}
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
 )
 
@@ -62,6 +64,9 @@ func (r *DynamoRepository) FindArchivedHabit(ctx context.Context, uid auth.UserI
 	if err != nil {
 		return nil, fmt.Errorf("get item: %w", err)
 	}
+	if resp.Item == nil {
+		return nil, apperrors.ErrNotFound
+	}
 	if err := attributevalue.UnmarshalMap(resp.Item, &h); err != nil {
 		return nil, fmt.Errorf("unmarshal item: %w", err)
 	}
@@ -69,38 +74,11 @@ func (r *DynamoRepository) FindArchivedHabit(ctx context.Context, uid auth.UserI
 }
 
 func (r *DynamoRepository) ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
-	h, err := r.FindHabit(ctx, uid, hid)
+	h, err := r.getHabit(ctx, uid, hid)
 	if err != nil {
-		return fmt.Errorf("find a habit [%s]: %w", hid, err)
-	}
-	deleteKey := h.GetKey()
-
-	h.SK = fmt.Sprintf("ARCHIVED_HABITS#%s", hid)
-	item, err := attributevalue.MarshalMap(h)
-	if err != nil {
-		return fmt.Errorf("marshal habit: %w", err)
-	}
-
-	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
-		TransactItems: []types.TransactWriteItem{
-			{
-				Delete: &types.Delete{
-					TableName: &r.TableName,
-					Key:       deleteKey,
-				},
-			},
-			{
-				Put: &types.Put{
-					TableName: &r.TableName,
-					Item:      item,
-				},
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("transact write items: %w", err)
+		return fmt.Errorf("get a habit [%s]: %w", hid, err)
 	}
-
-	return nil
+	return r.moveHabit(ctx, h, fmt.Sprintf("ARCHIVED_HABITS#%s", hid))
 }
 
 func (r *DynamoRepository) UnarchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
@@ -108,30 +86,65 @@ func (r *DynamoRepository) UnarchiveHabit(ctx context.Context, uid auth.UserID,
 	if err != nil {
 		return fmt.Errorf("find a habit [%s]: %w", hid, err)
 	}
+	return r.moveHabit(ctx, h, fmt.Sprintf("HABITS#%s", hid))
+}
+
+// moveHabit moves the habit item to the sort key, which archives or unarchives it.
+// The move fails with apperrors.ErrConflict if the habit has been written since it was read,
+// so that concurrent moves can not leave both an active and an archived copy.
+func (r *DynamoRepository) moveHabit(ctx context.Context, h *DynamoHabit, sk string) error {
+	if h.DeletingAt != nil {
+		return fmt.Errorf("habit [%s] is being deleted: %w", h.ID, apperrors.ErrNotFound)
+	}
 	deleteKey := h.GetKey()
+	version := h.Version
 
-	h.SK = fmt.Sprintf("HABITS#%s", hid)
+	h.SK = sk
+	h.Version = version + 1
 	item, err := attributevalue.MarshalMap(h)
 	if err != nil {
 		return fmt.Errorf("marshal habit: %w", err)
 	}
 
+	deleteExpr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name("PK")).And(versionCondition(version))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build delete expression: %w", err)
+	}
+	putExpr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name("PK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build put expression: %w", err)
+	}
+
 	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
 		TransactItems: []types.TransactWriteItem{
 			{
 				Delete: &types.Delete{
-					TableName: &r.TableName,
-					Key:       deleteKey,
+					TableName:                 &r.TableName,
+					Key:                       deleteKey,
+					ConditionExpression:       deleteExpr.Condition(),
+					ExpressionAttributeNames:  deleteExpr.Names(),
+					ExpressionAttributeValues: deleteExpr.Values(),
 				},
 			},
 			{
 				Put: &types.Put{
-					TableName: &r.TableName,
-					Item:      item,
+					TableName:                 &r.TableName,
+					Item:                      item,
+					ConditionExpression:       putExpr.Condition(),
+					ExpressionAttributeNames:  putExpr.Names(),
+					ExpressionAttributeValues: putExpr.Values(),
 				},
 			},
 		},
 	}); err != nil {
+		var tce *types.TransactionCanceledException
+		if errors.As(err, &tce) && isConcurrentWrite(tce) {
+			return fmt.Errorf("habit [%s] is changed concurrently: %w: %w", h.ID, apperrors.ErrConflict, tce)
+		}
 		return fmt.Errorf("transact write items: %w", err)
 	}
 
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/hareku/habit-tracker-app/internal/apperrors"
@@ -14,10 +15,10 @@ func TestDynamoRepository_AllArchivedHabits(t *testing.T) {
 	ctx := context.Background()
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
-	h2, err := repo.CreateHabit(ctx, myUserID, "Habit2")
+	h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
 	require.NoError(t, err)
 	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h2.ID))
 
@@ -31,7 +32,7 @@ func TestDynamoRepository_ArchiveHabit(t *testing.T) {
 	ctx := context.Background()
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
 	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
@@ -49,7 +50,7 @@ func TestDynamoRepository_UnarchiveHabit(t *testing.T) {
 	ctx := context.Background()
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
 	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
@@ -60,3 +61,70 @@ func TestDynamoRepository_UnarchiveHabit(t *testing.T) {
 
 	require.Equal(t, h1, h2)
 }
+
+func TestDynamoRepository_DeleteHabit_Archived(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := context.Background()
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+	require.NoError(t, err)
+	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
+
+	require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+
+	got, err := repo.AllArchivedHabits(ctx, myUserID)
+	require.NoError(t, err)
+	require.Empty(t, got)
+	checks, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 10)
+	require.NoError(t, err)
+	require.Empty(t, checks)
+}
+
+func TestDynamoRepository_ArchiveHabit_Concurrently(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := context.Background()
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+
+	// Both requests read the same version of the habit.
+	a, err := repo.getHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	b, err := repo.getHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.moveHabit(ctx, a, fmt.Sprintf("ARCHIVED_HABITS#%s", h1.ID)))
+	err = repo.moveHabit(ctx, b, fmt.Sprintf("ARCHIVED_HABITS#%s", h1.ID))
+	require.ErrorIs(t, err, apperrors.ErrConflict)
+
+	// Unarchiving with the stale version conflicts as well.
+	stale, err := repo.FindArchivedHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	require.NoError(t, repo.UnarchiveHabit(ctx, myUserID, h1.ID))
+	err = repo.moveHabit(ctx, stale, fmt.Sprintf("HABITS#%s", h1.ID))
+	require.ErrorIs(t, err, apperrors.ErrConflict)
+
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	require.Len(t, habits, 1)
+	archived, err := repo.AllArchivedHabits(ctx, myUserID)
+	require.NoError(t, err)
+	require.Empty(t, archived)
+}
+
+func TestDynamoRepository_UnarchiveHabit_NotFound(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := context.Background()
+	myUserID := auth.UserID("MyUserID")
+
+	err := repo.UnarchiveHabit(ctx, myUserID, "52fdfc07-2182-454f-963f-5f0f9a621d72")
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	require.Empty(t, habits)
+}
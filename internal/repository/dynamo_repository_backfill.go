@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// maxTransactItems is the maximum number of items in a transaction of DynamoDB.
+const maxTransactItems = 100
+
+// ImportChunkSize is the number of checks which ImportChecks writes in a transaction together with the habit.
+const ImportChunkSize = maxTransactItems - 1
+
+// errCheckExists is returned when a check to be created has been created concurrently.
+var errCheckExists = errors.New("check exists")
+
+type DynamoRepositoryBackfillChecksInput struct {
+	UserID  auth.UserID
+	HabitID string
+	// From and To are the inclusive range of the dates to check.
+	From string
+	To   string
+	// Weekdays limits the dates to the weekdays. Empty means every day.
+	Weekdays []time.Weekday
+	Value    float64
+}
+
+type DynamoRepositoryImportChecksInput struct {
+	UserID  auth.UserID
+	HabitID string
+	Checks  []*DynamoRepositoryImportCheck
+}
+
+type DynamoRepositoryImportCheck struct {
+	Date  string
+	Value float64
+}
+
+// uniqueImportChecks validates the dates of the checks and drops the later checks on the same date.
+func uniqueImportChecks(in []*DynamoRepositoryImportCheck) ([]*DynamoRepositoryImportCheck, error) {
+	checks := make([]*DynamoRepositoryImportCheck, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, c := range in {
+		if _, err := time.Parse("2006-01-02", c.Date); err != nil {
+			return nil, fmt.Errorf("parse date: %w", err)
+		}
+		if !seen[c.Date] {
+			seen[c.Date] = true
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+// validateImportValues returns apperrors.ErrValueRequired if the habit is quantitative and any of the checks has no positive value.
+func validateImportValues(h *DynamoHabit, checks []*DynamoRepositoryImportCheck) error {
+	for _, c := range checks {
+		if err := validateCheckValue(h, c.Value); err != nil {
+			return fmt.Errorf("check [%s]: %w", c.Date, err)
+		}
+	}
+	return nil
+}
+
+func compareImportChecks(a, b *DynamoRepositoryImportCheck) int {
+	return strings.Compare(a.Date, b.Date)
+}
+
+// backfillChecks returns the checks on the dates in the range of the input.
+func backfillChecks(in *DynamoRepositoryBackfillChecksInput) ([]*DynamoRepositoryImportCheck, error) {
+	from, err := time.Parse("2006-01-02", in.From)
+	if err != nil {
+		return nil, fmt.Errorf("parse from: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", in.To)
+	if err != nil {
+		return nil, fmt.Errorf("parse to: %w", err)
+	}
+
+	var checks []*DynamoRepositoryImportCheck
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if len(in.Weekdays) == 0 || slices.Contains(in.Weekdays, d.Weekday()) {
+			checks = append(checks, &DynamoRepositoryImportCheck{Date: d.Format("2006-01-02"), Value: in.Value})
+		}
+	}
+	return checks, nil
+}
+
+// BackfillChecks creates the checks of the habit on the dates in the range which are not checked yet,
+// and returns the dates of the created checks. The checks which already exist are kept as they are.
+// The value must be positive if the habit is quantitative.
+func (r *DynamoRepository) BackfillChecks(ctx context.Context, in *DynamoRepositoryBackfillChecksInput) ([]string, error) {
+	checks, err := backfillChecks(in)
+	if err != nil {
+		return nil, err
+	}
+	return r.importChecks(ctx, &DynamoRepositoryImportChecksInput{UserID: in.UserID, HabitID: in.HabitID, Checks: checks},
+		func(h *DynamoHabit) error { return validateCheckValue(h, in.Value) })
+}
+
+// ImportChecks creates the checks of the habit on the dates which are not checked yet,
+// and returns the dates of the created checks. The checks which already exist are kept as they are.
+// The checks are written in transactions of up to 99 checks together with the habit.
+// The values must be positive if the habit is quantitative, and no check is written otherwise.
+func (r *DynamoRepository) ImportChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput) ([]string, error) {
+	return r.importChecks(ctx, in, func(h *DynamoHabit) error { return validateImportValues(h, in.Checks) })
+}
+
+// importChecks imports the checks like ImportChecks, after validating the habit with validate if it is not nil.
+func (r *DynamoRepository) importChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput, validate func(h *DynamoHabit) error) ([]string, error) {
+	checks, err := uniqueImportChecks(in.Checks)
+	if err != nil {
+		return nil, err
+	}
+
+	// The habit is written only if a check is missing, so it is read here to fail in the same way as CreateCheck.
+	h, err := r.activeHabit(ctx, in.UserID, in.HabitID)
+	if err != nil {
+		return nil, err
+	}
+	if validate != nil {
+		if err := validate(h); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(checks) == 0 {
+		return nil, nil
+	}
+	first := slices.MinFunc(checks, compareImportChecks).Date
+	last := slices.MaxFunc(checks, compareImportChecks).Date
+
+	var created []string
+	for range maxHabitWriteAttempts {
+		existing, err := r.listCheckValues(ctx, in.UserID, in.HabitID, first, last)
+		if err != nil {
+			return nil, fmt.Errorf("list check values: %w", err)
+		}
+		missing := slices.DeleteFunc(slices.Clone(checks), func(ic *DynamoRepositoryImportCheck) bool {
+			return slices.ContainsFunc(existing, func(c *DynamoCheck) bool { return c.Date == ic.Date })
+		})
+
+		err = nil
+		for chunk := range slices.Chunk(missing, ImportChunkSize) {
+			if err = r.importChunk(ctx, in.UserID, in.HabitID, chunk); err != nil {
+				break
+			}
+			for _, c := range chunk {
+				created = append(created, c.Date)
+			}
+		}
+		if errors.Is(err, errCheckExists) {
+			// Some of the dates are checked concurrently, so the missing dates are listed again.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(created)
+		return created, nil
+	}
+	return nil, fmt.Errorf("habit [%s] is checked concurrently: %w", in.HabitID, apperrors.ErrConflict)
+}
+
+// importChunk creates the checks in a transaction.
+// It returns errCheckExists if any of them exists.
+func (r *DynamoRepository) importChunk(ctx context.Context, uid auth.UserID, hid string, in []*DynamoRepositoryImportCheck) error {
+	condition, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name("PK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build condition expression: %w", err)
+	}
+
+	now := time.Now().Round(time.Nanosecond)
+	dates := make([]string, 0, len(in))
+	checks := make([]*DynamoCheck, 0, len(in))
+	items := make([]types.TransactWriteItem, 0, len(in))
+	for _, ic := range in {
+		c := NewDynamoCheck(uid, hid, ic.Date)
+		c.Value = ic.Value
+		c.CreatedAt = now
+		c.UpdatedAt = now
+		item, err := attributevalue.MarshalMap(c)
+		if err != nil {
+			return fmt.Errorf("marshal check: %w", err)
+		}
+		dates = append(dates, ic.Date)
+		checks = append(checks, c)
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:                 &r.TableName,
+				Item:                      item,
+				ConditionExpression:       condition.Condition(),
+				ExpressionAttributeNames:  condition.Names(),
+				ExpressionAttributeValues: condition.Values(),
+			},
+		})
+	}
+
+	return r.writeHabit(ctx, uid, hid, slices.Min(dates), items, errCheckExists, func(_ *DynamoHabit, existing []*DynamoCheck) ([]*DynamoCheck, error) {
+		for _, c := range existing {
+			if slices.Contains(dates, c.Date) {
+				return nil, errCheckExists
+			}
+		}
+		return append(existing, checks...), nil
+	})
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// maxBatchWriteItems is the maximum number of requests in a BatchWriteItem call.
+const maxBatchWriteItems = 25
+
+// maxBatchWriteAttempts is the number of attempts to write the unprocessed items of a batch.
+const maxBatchWriteAttempts = 5
+
+// DeleteHabit deletes the habit, active or archived, and all of its checks and its reminder.
+//
+// The habit is marked as deleting first, which makes further writes of its checks fail,
+// then its checks are deleted page by page, and the habit itself is deleted at last.
+// So if the deletion is interrupted, calling DeleteHabit again resumes it.
+// Deleting a habit which does not exist is not an error, and removes its orphaned checks if any.
+func (r *DynamoRepository) DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	habits := []*DynamoHabit{NewDynamoHabit(uid, hid), NewArchivedDynamoHabit(uid, hid)}
+	for _, h := range habits {
+		if err := r.markHabitDeleting(ctx, h); err != nil {
+			return fmt.Errorf("mark habit [%s] as deleting: %w", h.SK, err)
+		}
+	}
+
+	if err := r.deleteChecks(ctx, uid, hid); err != nil {
+		return fmt.Errorf("delete checks: %w", err)
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(habits)+1)
+	keys = append(keys, NewDynamoReminder(uid, hid).GetKey())
+	for _, h := range habits {
+		keys = append(keys, h.GetKey())
+	}
+	if err := r.deleteItems(ctx, keys); err != nil {
+		return fmt.Errorf("delete habit: %w", err)
+	}
+	return nil
+}
+
+// markHabitDeleting sets DeletingAt of the habit if it exists.
+// The Version is incremented as well, so that a concurrent write of a check fails and sees the mark on retry.
+func (r *DynamoRepository) markHabitDeleting(ctx context.Context, h *DynamoHabit) error {
+	expr, err := expression.NewBuilder().
+		WithUpdate(
+			expression.Set(expression.Name("DeletingAt"), expression.IfNotExists(expression.Name("DeletingAt"), expression.Value(time.Now().Round(time.Nanosecond)))).
+				Add(expression.Name("Version"), expression.Value(1)),
+		).
+		WithCondition(expression.AttributeExists(expression.Name("PK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	if _, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.TableName,
+		Key:                       h.GetKey(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}); err != nil {
+		var ccfe *types.ConditionalCheckFailedException
+		if errors.As(err, &ccfe) {
+			return nil
+		}
+		return fmt.Errorf("update item: %w", err)
+	}
+	return nil
+}
+
+// deleteChecks deletes all checks of the habit.
+func (r *DynamoRepository) deleteChecks(ctx context.Context, uid auth.UserID, hid string) error {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").BeginsWith(fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid))),
+		).
+		WithProjection(expression.NamesList(expression.Name("PK"), expression.Name("SK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("query paginator: %w", err)
+		}
+		if err := r.deleteItems(ctx, resp.Items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteItems deletes the items of the keys in batches.
+// Deleting an item which does not exist succeeds, so it is safe to call again after a failure.
+func (r *DynamoRepository) deleteItems(ctx context.Context, keys []map[string]types.AttributeValue) error {
+	for len(keys) > 0 {
+		n := min(len(keys), maxBatchWriteItems)
+		reqs := make([]types.WriteRequest, 0, n)
+		for _, key := range keys[:n] {
+			reqs = append(reqs, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+		if err := r.batchWrite(ctx, reqs); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// batchWrite writes the requests, retrying the unprocessed ones with backoff.
+func (r *DynamoRepository) batchWrite(ctx context.Context, reqs []types.WriteRequest) error {
+	backoff := 50 * time.Millisecond
+	for range maxBatchWriteAttempts {
+		resp, err := r.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{r.TableName: reqs},
+		})
+		if err != nil {
+			return fmt.Errorf("batch write item: %w", err)
+		}
+		reqs = resp.UnprocessedItems[r.TableName]
+		if len(reqs) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("%d items are not processed after %d attempts", len(reqs), maxBatchWriteAttempts)
+}
+
+// DeleteUserData deletes every item of the user: habits, archived habits, checks and the profile,
+// and the reminders and the pending webhook deliveries, which are in other partitions.
+// It is safe to call again after a failure, and deleting the data of an unknown user is not an error.
+func (r *DynamoRepository) DeleteUserData(ctx context.Context, uid auth.UserID) error {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid)))).
+		WithProjection(expression.NamesList(expression.Name("PK"), expression.Name("SK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("query paginator: %w", err)
+		}
+		if err := r.deleteItems(ctx, resp.Items); err != nil {
+			return err
+		}
+	}
+	if err := r.deleteReminders(ctx, uid); err != nil {
+		return fmt.Errorf("delete reminders: %w", err)
+	}
+	if err := r.deletePrefix(ctx, webhookRetriesPK, webhookRetryPrefix(uid, "")); err != nil {
+		return fmt.Errorf("delete pending webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// deletePrefix deletes the items in the partition whose sort keys begin with the prefix.
+func (r *DynamoRepository) deletePrefix(ctx context.Context, pk, prefix string) error {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(pk)).
+				And(expression.Key("SK").BeginsWith(prefix)),
+		).
+		WithProjection(expression.NamesList(expression.Name("PK"), expression.Name("SK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("query paginator: %w", err)
+		}
+		if err := r.deleteItems(ctx, resp.Items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
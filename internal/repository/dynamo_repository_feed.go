@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// DynamoFeedToken is the secret token of the calendar feed of a user.
+// Only the hash of the token is stored, so the token itself can not be shown again.
+type DynamoFeedToken struct {
+	PK        string
+	SK        string
+	UserID    auth.UserID
+	TokenHash string
+	CreatedAt time.Time
+}
+
+func NewDynamoFeedToken(userID auth.UserID) *DynamoFeedToken {
+	return &DynamoFeedToken{
+		PK:     fmt.Sprintf("USER#%s", userID),
+		SK:     "FEED_TOKEN",
+		UserID: userID,
+	}
+}
+
+// GetKey returns the composite primary key of the feed token in a format that can be
+// sent to DynamoDB.
+func (t *DynamoFeedToken) GetKey() map[string]types.AttributeValue {
+	pk, err := attributevalue.Marshal(t.PK)
+	if err != nil {
+		panic(fmt.Errorf("marshal PK: %w", err))
+	}
+	sk, err := attributevalue.Marshal(t.SK)
+	if err != nil {
+		panic(fmt.Errorf("marshal SK: %w", err))
+	}
+	return map[string]types.AttributeValue{"PK": pk, "SK": sk}
+}
+
+// FindFeedToken returns the feed token of the user, or apperrors.ErrNotFound if the feed is not enabled.
+func (r *DynamoRepository) FindFeedToken(ctx context.Context, uid auth.UserID) (*DynamoFeedToken, error) {
+	t := NewDynamoFeedToken(uid)
+	resp, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.TableName,
+		Key:            t.GetKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+	if resp.Item == nil {
+		return nil, fmt.Errorf("feed token of user [%s]: %w", uid, apperrors.ErrNotFound)
+	}
+	if err := attributevalue.UnmarshalMap(resp.Item, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal item: %w", err)
+	}
+	return t, nil
+}
+
+type DynamoRepositoryPutFeedTokenInput struct {
+	UserID    auth.UserID
+	TokenHash string
+}
+
+// PutFeedToken saves the feed token of the user, which replaces and so revokes the previous one.
+func (r *DynamoRepository) PutFeedToken(ctx context.Context, in *DynamoRepositoryPutFeedTokenInput) error {
+	t := NewDynamoFeedToken(in.UserID)
+	t.TokenHash = in.TokenHash
+	t.CreatedAt = time.Now().Round(time.Nanosecond)
+	item, err := attributevalue.MarshalMap(t)
+	if err != nil {
+		return fmt.Errorf("marshal feed token: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.TableName,
+		Item:      item,
+	}); err != nil {
+		return fmt.Errorf("put item: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeedToken revokes the feed token of the user. Deleting a token which does not exist is not an error.
+func (r *DynamoRepository) DeleteFeedToken(ctx context.Context, uid auth.UserID) error {
+	if _, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.TableName,
+		Key:       NewDynamoFeedToken(uid).GetKey(),
+	}); err != nil {
+		return fmt.Errorf("delete item: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// DynamoProfile is the settings of a user.
+type DynamoProfile struct {
+	PK     string
+	SK     string
+	UserID auth.UserID
+	// TimeZone is an IANA time zone name such as "Asia/Tokyo", which decides the day boundary of the user.
+	// An empty time zone means UTC.
+	TimeZone  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func NewDynamoProfile(userID auth.UserID) *DynamoProfile {
+	return &DynamoProfile{
+		PK:     fmt.Sprintf("USER#%s", userID),
+		SK:     "PROFILE",
+		UserID: userID,
+	}
+}
+
+// GetKey returns the composite primary key of the profile in a format that can be
+// sent to DynamoDB.
+func (p *DynamoProfile) GetKey() map[string]types.AttributeValue {
+	pk, err := attributevalue.Marshal(p.PK)
+	if err != nil {
+		panic(fmt.Errorf("marshal PK: %w", err))
+	}
+	sk, err := attributevalue.Marshal(p.SK)
+	if err != nil {
+		panic(fmt.Errorf("marshal SK: %w", err))
+	}
+	return map[string]types.AttributeValue{"PK": pk, "SK": sk}
+}
+
+// Location returns the time zone of the user.
+// It falls back to UTC if the time zone is empty or no longer known.
+func (p *DynamoProfile) Location() *time.Location {
+	if p.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FindProfile returns the profile of the user.
+// A user who has never saved the profile gets the default profile.
+func (r *DynamoRepository) FindProfile(ctx context.Context, uid auth.UserID) (*DynamoProfile, error) {
+	p := NewDynamoProfile(uid)
+	resp, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.TableName,
+		Key:       p.GetKey(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+	if resp.Item == nil {
+		return p, nil
+	}
+	if err := attributevalue.UnmarshalMap(resp.Item, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal item: %w", err)
+	}
+	return p, nil
+}
+
+type DynamoRepositoryUpdateProfileInput struct {
+	UserID   auth.UserID
+	TimeZone string
+}
+
+// UpdateProfile saves the profile of the user, creating it if it does not exist yet.
+func (r *DynamoRepository) UpdateProfile(ctx context.Context, in *DynamoRepositoryUpdateProfileInput) error {
+	p := NewDynamoProfile(in.UserID)
+	now := time.Now().Round(time.Nanosecond)
+
+	expr, err := expression.NewBuilder().
+		WithUpdate(
+			expression.Set(expression.Name("UserID"), expression.Value(p.UserID)).
+				Set(expression.Name("TimeZone"), expression.Value(in.TimeZone)).
+				Set(expression.Name("CreatedAt"), expression.IfNotExists(expression.Name("CreatedAt"), expression.Value(now))).
+				Set(expression.Name("UpdatedAt"), expression.Value(now)),
+		).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	if _, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.TableName,
+		Key:                       p.GetKey(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}); err != nil {
+		return fmt.Errorf("update item: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoRepository_UpdateProfile(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := context.Background()
+	myUserID := auth.UserID("MyUserID")
+
+	p, err := repo.FindProfile(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "", p.TimeZone)
+	assert.Equal(t, time.UTC, p.Location())
+
+	require.NoError(t, repo.UpdateProfile(ctx, &DynamoRepositoryUpdateProfileInput{UserID: myUserID, TimeZone: "Asia/Tokyo"}))
+	p, err = repo.FindProfile(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", p.TimeZone)
+	assert.Equal(t, "Asia/Tokyo", p.Location().String())
+	createdAt := p.CreatedAt
+
+	require.NoError(t, repo.UpdateProfile(ctx, &DynamoRepositoryUpdateProfileInput{UserID: myUserID, TimeZone: "Europe/Paris"}))
+	p, err = repo.FindProfile(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", p.TimeZone)
+	assert.Equal(t, createdAt, p.CreatedAt)
+
+	// The profile is not listed as a habit.
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Empty(t, habits)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// remindersPK is the partition key of all reminders.
+// They are in one partition, so that the reminders of all users are read by a query instead of a scan.
+const remindersPK = "REMINDERS"
+
+// DynamoReminder is the times of day to remind the user of a habit.
+type DynamoReminder struct {
+	PK      string
+	SK      string
+	UserID  auth.UserID
+	HabitID string
+	// Times are "15:04" in the time zone of the user, in ascending order.
+	Times     []string
+	UpdatedAt time.Time
+}
+
+func NewDynamoReminder(userID auth.UserID, habitID string) *DynamoReminder {
+	return &DynamoReminder{
+		PK:      remindersPK,
+		SK:      fmt.Sprintf("%s#HABIT#%s", reminderUserPrefix(userID), habitID),
+		UserID:  userID,
+		HabitID: habitID,
+	}
+}
+
+// reminderUserPrefix is the prefix of the sort keys of the reminders of the user.
+func reminderUserPrefix(uid auth.UserID) string {
+	return fmt.Sprintf("USER#%s", uid)
+}
+
+// GetKey returns the composite primary key of the reminder in a format that can be
+// sent to DynamoDB.
+func (rem *DynamoReminder) GetKey() map[string]types.AttributeValue {
+	pk, err := attributevalue.Marshal(rem.PK)
+	if err != nil {
+		panic(fmt.Errorf("marshal PK: %w", err))
+	}
+	sk, err := attributevalue.Marshal(rem.SK)
+	if err != nil {
+		panic(fmt.Errorf("marshal SK: %w", err))
+	}
+	return map[string]types.AttributeValue{"PK": pk, "SK": sk}
+}
+
+type DynamoRepositoryPutReminderInput struct {
+	UserID  auth.UserID
+	HabitID string
+	// Times are "15:04" in the time zone of the user. No times deletes the reminder.
+	Times []string
+}
+
+// PutReminder saves the reminder times of the habit, replacing the previous ones.
+func (r *DynamoRepository) PutReminder(ctx context.Context, in *DynamoRepositoryPutReminderInput) error {
+	rem := NewDynamoReminder(in.UserID, in.HabitID)
+	if len(in.Times) == 0 {
+		return r.deleteItems(ctx, []map[string]types.AttributeValue{rem.GetKey()})
+	}
+	rem.Times = in.Times
+	rem.UpdatedAt = time.Now().Round(time.Nanosecond)
+
+	item, err := attributevalue.MarshalMap(rem)
+	if err != nil {
+		return fmt.Errorf("marshal reminder: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.TableName,
+		Item:      item,
+	}); err != nil {
+		return fmt.Errorf("put item: %w", err)
+	}
+	return nil
+}
+
+// FindReminder returns the reminder of the habit, or apperrors.ErrNotFound if it has no reminder times.
+func (r *DynamoRepository) FindReminder(ctx context.Context, uid auth.UserID, hid string) (*DynamoReminder, error) {
+	resp, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.TableName,
+		Key:            NewDynamoReminder(uid, hid).GetKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+	if resp.Item == nil {
+		return nil, fmt.Errorf("reminder of habit [%s]: %w", hid, apperrors.ErrNotFound)
+	}
+
+	var rem DynamoReminder
+	if err := attributevalue.UnmarshalMap(resp.Item, &rem); err != nil {
+		return nil, fmt.Errorf("unmarshal item: %w", err)
+	}
+	return &rem, nil
+}
+
+// AllReminders returns the reminders of all users, in the order of users and habits.
+func (r *DynamoRepository) AllReminders(ctx context.Context) ([]*DynamoReminder, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("PK").Equal(expression.Value(remindersPK))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	var reminders []*DynamoReminder
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query paginator: %w", err)
+		}
+
+		var pageItems []*DynamoReminder
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &pageItems); err != nil {
+			return nil, fmt.Errorf("unmarshal items: %w", err)
+		}
+		reminders = append(reminders, pageItems...)
+	}
+	return reminders, nil
+}
+
+// deleteReminders deletes all reminders of the user.
+func (r *DynamoRepository) deleteReminders(ctx context.Context, uid auth.UserID) error {
+	return r.deletePrefix(ctx, remindersPK, reminderUserPrefix(uid)+"#HABIT#")
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/hareku/habit-tracker-app/dynamoconf"
 	"github.com/hareku/habit-tracker-app/internal/apperrors"
 	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/schedule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,12 +62,12 @@ func Test_AllHabits(t *testing.T) {
 	myUserID := auth.UserID("MyUserID")
 	otherUserID := auth.UserID("OtherUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
-	h2, err := repo.CreateHabit(ctx, myUserID, "Habit2")
+	h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
 	require.NoError(t, err)
 
-	_, err = repo.CreateHabit(ctx, otherUserID, "Habit3")
+	_, err = repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: otherUserID, Title: "Habit3"})
 	require.NoError(t, err)
 
 	got, err := repo.AllHabits(ctx, myUserID)
@@ -83,9 +86,9 @@ func Test_FindHabit(t *testing.T) {
 
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
-	_, err = repo.CreateHabit(ctx, myUserID, "Habit2")
+	_, err = repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
 	require.NoError(t, err)
 
 	got, err := repo.FindHabit(ctx, myUserID, h1.ID)
@@ -99,10 +102,10 @@ func Test_DeleteHabit(t *testing.T) {
 
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
-	h2, err := repo.CreateHabit(ctx, myUserID, "Habit2")
+	h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
 	require.NoError(t, err)
 
 	require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
@@ -116,18 +119,133 @@ func Test_DeleteHabit(t *testing.T) {
 	assert.Equal(t, h2, got2)
 }
 
+func Test_DeleteHabit_Checks(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+	today := time.Now()
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+	h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+	require.NoError(t, err)
+
+	// More checks than a batch.
+	for i := range maxBatchWriteItems + 5 {
+		_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{
+			UserID:  myUserID,
+			HabitID: h1.ID,
+			Date:    today.AddDate(0, 0, -i).Format("2006-01-02"),
+		})
+		require.NoError(t, err)
+	}
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h2.ID, Date: today.Format("2006-01-02")})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+
+	got, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 100)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = repo.ListLastWeekChecksInAllHabits(ctx, myUserID, today)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, h2.ID, got[0].HabitID)
+
+	// Deleting again succeeds.
+	require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+}
+
+func Test_DeleteHabit_Resume(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+	require.NoError(t, err)
+
+	// Interrupted right after the habit is marked.
+	require.NoError(t, repo.markHabitDeleting(ctx, h1))
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02"})
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	require.NoError(t, repo.DeleteHabit(ctx, myUserID, h1.ID))
+	_, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+	got, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_UpdateHabit(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{
+		UserID:   myUserID,
+		Title:    "Habit1",
+		Schedule: schedule.Schedule{Kind: schedule.KindTimesPerWeek, TimesPerWeek: 3},
+	})
+	require.NoError(t, err)
+
+	got, err := repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, h1, got)
+
+	weekdays := schedule.Schedule{Kind: schedule.KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Friday}}
+	require.NoError(t, repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{
+		UserID:   myUserID,
+		HabitID:  h1.ID,
+		Title:    "Renamed",
+		Schedule: weekdays,
+	}))
+
+	got, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Title)
+	assert.Equal(t, weekdays, got.Schedule)
+	assert.Equal(t, 1, got.Version)
+
+	// An update based on the stale version conflicts.
+	err = repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{
+		UserID:  myUserID,
+		HabitID: h1.ID,
+		Version: 0,
+		Title:   "Stale",
+	})
+	require.ErrorIs(t, err, apperrors.ErrConflict)
+
+	// No habit is created by an update.
+	err = repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{
+		UserID:  myUserID,
+		HabitID: "52fdfc07-2182-454f-963f-5f0f9a621d72",
+		Title:   "Phantom",
+	})
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Len(t, habits, 1)
+}
+
 func Test_CreateCheck_Twice(t *testing.T) {
 	repo := newDynamoRepositoryTest(t)
 	ctx := t.Context()
 
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
-	c1, err := repo.CreateCheck(ctx, myUserID, h1.ID, "2000-01-01")
+	c1, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
 	require.NoError(t, err)
-	_, err = repo.CreateCheck(ctx, myUserID, h1.ID, c1.Date)
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: c1.Date})
 	require.Error(t, err)
 	require.ErrorIs(t, err, apperrors.ErrConflict)
 
@@ -136,16 +254,56 @@ func Test_CreateCheck_Twice(t *testing.T) {
 	require.Equal(t, 1, h1.ChecksCount)
 }
 
+func Test_CreateCheck_UnknownHabit(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+	hid := "52fdfc07-2182-454f-963f-5f0f9a621d72"
+
+	_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: hid, Date: "2000-01-01"})
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	// No phantom habit is created.
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Empty(t, habits)
+	checks, err := repo.ListLatestChecksWithLimit(ctx, myUserID, hid, 10)
+	require.NoError(t, err)
+	assert.Empty(t, checks)
+}
+
+func Test_CreateCheck_ArchivedHabit(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h1.ID))
+
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
+	require.ErrorIs(t, err, apperrors.ErrArchived)
+
+	habits, err := repo.AllHabits(ctx, myUserID)
+	require.NoError(t, err)
+	assert.Empty(t, habits)
+	archived, err := repo.FindArchivedHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, archived.ChecksCount)
+}
+
 func Test_DeleteCheck_Twice(t *testing.T) {
 	repo := newDynamoRepositoryTest(t)
 	ctx := t.Context()
 
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
-	c1, err := repo.CreateCheck(ctx, myUserID, h1.ID, "2000-01-01")
+	c1, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
 	require.NoError(t, err)
 	require.NoError(t, repo.DeleteCheck(ctx, myUserID, h1.ID, c1.Date))
 
@@ -166,12 +324,12 @@ func Test_ListLatestChecksWithLimit(t *testing.T) {
 
 	myUserID := auth.UserID("MyUserID")
 
-	h1, err := repo.CreateHabit(ctx, myUserID, "Habit1")
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
 	require.NoError(t, err)
 
-	c1, err := repo.CreateCheck(ctx, myUserID, h1.ID, "2000-01-01")
+	c1, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01"})
 	require.NoError(t, err)
-	c2, err := repo.CreateCheck(ctx, myUserID, h1.ID, "2000-01-02")
+	c2, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02"})
 	require.NoError(t, err)
 
 	got1, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 1)
@@ -184,3 +342,199 @@ func Test_ListLatestChecksWithLimit(t *testing.T) {
 	require.Len(t, got2, 2)
 	require.Equal(t, []*DynamoCheck{c2, c1}, got2)
 }
+
+func Test_CreateCheck_Streak(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+
+	for _, date := range []string{"2000-01-01", "2000-01-02", "2000-01-04", "2000-01-05"} {
+		_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: date})
+		require.NoError(t, err)
+	}
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, h1.CurrentStreak)
+	assert.Equal(t, 2, h1.LongestStreak)
+	assert.Equal(t, "2000-01-05", h1.LastCheckDate)
+
+	// Backfill the gap.
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-03"})
+	require.NoError(t, err)
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, h1.CurrentStreak)
+	assert.Equal(t, 5, h1.LongestStreak)
+
+	// Delete from the middle of the streak.
+	require.NoError(t, repo.DeleteCheck(ctx, myUserID, h1.ID, "2000-01-02"))
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, h1.CurrentStreak)
+	assert.Equal(t, 3, h1.LongestStreak)
+	assert.Equal(t, 4, h1.ChecksCount)
+
+	// Delete the latest check.
+	require.NoError(t, repo.DeleteCheck(ctx, myUserID, h1.ID, "2000-01-05"))
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, h1.CurrentStreak)
+	assert.Equal(t, "2000-01-04", h1.LastCheckDate)
+}
+
+func Test_CreateCheck_Concurrently(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+
+	dates := []string{"2000-01-01", "2000-01-02", "2000-01-03"}
+	var wg sync.WaitGroup
+	for _, date := range dates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: date})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, h1.ChecksCount)
+	assert.Equal(t, 3, h1.CurrentStreak)
+	assert.Equal(t, 3, h1.LongestStreak)
+}
+
+func Test_CreateCheck_Value(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{
+		UserID: myUserID,
+		Title:  "Run",
+		Unit:   "km",
+		Target: 5,
+	})
+	require.NoError(t, err)
+
+	c1, err := repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Value: 5})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02", Value: 2.5})
+	require.NoError(t, err)
+
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, h1.TotalValue)
+	// The partial check on 2000-01-02 does not extend the streak.
+	assert.Equal(t, 1, h1.CurrentStreak)
+
+	got, err := repo.ListLatestChecksWithLimit(ctx, myUserID, h1.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, c1, got[1])
+	assert.Equal(t, 2.5, got[0].Value)
+
+	// Lowering the target recomputes the streaks.
+	require.NoError(t, repo.UpdateHabit(ctx, &DynamoRepositoryUpdateHabitInput{
+		UserID:  myUserID,
+		HabitID: h1.ID,
+		Version: h1.Version,
+		Title:   h1.Title,
+		Unit:    "km",
+		Target:  2,
+	}))
+	h1, err = repo.FindHabit(ctx, myUserID, h1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, h1.CurrentStreak)
+	assert.Equal(t, 7.5, h1.TotalValue)
+}
+
+func Test_UpdateCheckNote(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Read"})
+	require.NoError(t, err)
+
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01", Note: "first"})
+	require.NoError(t, err)
+	_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{
+		UserID: myUserID, HabitID: h1.ID, Date: "2000-01-02", Note: "second",
+	}))
+	got, err := repo.ListCheckNotes(ctx, &DynamoRepositoryListCheckNotesInput{UserID: myUserID, HabitID: h1.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got.Checks, 2)
+	assert.Equal(t, "second", got.Checks[0].Note)
+	assert.Equal(t, "first", got.Checks[1].Note)
+
+	// An empty note removes the note.
+	require.NoError(t, repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{
+		UserID: myUserID, HabitID: h1.ID, Date: "2000-01-01",
+	}))
+	got, err = repo.ListCheckNotes(ctx, &DynamoRepositoryListCheckNotesInput{UserID: myUserID, HabitID: h1.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got.Checks, 1)
+	assert.Equal(t, "2000-01-02", got.Checks[0].Date)
+
+	err = repo.UpdateCheckNote(ctx, &DynamoRepositoryUpdateCheckNoteInput{
+		UserID: myUserID, HabitID: h1.ID, Date: "2000-01-03", Note: "missing",
+	})
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func Test_DeleteUserData(t *testing.T) {
+	repo := newDynamoRepositoryTest(t)
+	ctx := t.Context()
+
+	myUserID := auth.UserID("MyUserID")
+	otherUserID := auth.UserID("OtherUserID")
+
+	h1, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit1"})
+	require.NoError(t, err)
+	h2, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: myUserID, Title: "Habit2"})
+	require.NoError(t, err)
+	for _, h := range []*DynamoHabit{h1, h2} {
+		_, err = repo.CreateCheck(ctx, &DynamoRepositoryCreateCheckInput{UserID: myUserID, HabitID: h.ID, Date: "2000-01-01"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.ArchiveHabit(ctx, myUserID, h2.ID))
+	require.NoError(t, repo.UpdateProfile(ctx, &DynamoRepositoryUpdateProfileInput{UserID: myUserID, TimeZone: "Asia/Tokyo"}))
+
+	other, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: otherUserID, Title: "Other"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteUserData(ctx, myUserID))
+
+	resp, err := repo.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &repo.TableName,
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: "USER#MyUserID"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Items)
+
+	got, err := repo.FindHabit(ctx, otherUserID, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other, got)
+
+	// Deleting again succeeds.
+	require.NoError(t, repo.DeleteUserData(ctx, myUserID))
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// DynamoWebhook is a URL which receives the events of a user.
+// The secret is stored as it is, because it signs the events.
+type DynamoWebhook struct {
+	PK        string
+	SK        string
+	ID        string `dynamodbav:"UUID"`
+	UserID    auth.UserID
+	URL       string
+	Secret    string
+	CreatedAt time.Time
+}
+
+func NewDynamoWebhook(userID auth.UserID, webhookID string) *DynamoWebhook {
+	return &DynamoWebhook{
+		PK:     fmt.Sprintf("USER#%s", userID),
+		SK:     fmt.Sprintf("WEBHOOK#%s", webhookID),
+		ID:     webhookID,
+		UserID: userID,
+	}
+}
+
+// GetKey returns the composite primary key of the webhook in a format that can be
+// sent to DynamoDB.
+func (wh *DynamoWebhook) GetKey() map[string]types.AttributeValue {
+	pk, err := attributevalue.Marshal(wh.PK)
+	if err != nil {
+		panic(fmt.Errorf("marshal PK: %w", err))
+	}
+	sk, err := attributevalue.Marshal(wh.SK)
+	if err != nil {
+		panic(fmt.Errorf("marshal SK: %w", err))
+	}
+	return map[string]types.AttributeValue{"PK": pk, "SK": sk}
+}
+
+// WebhookDeliveryStatus is the status of a delivery of an event to a webhook.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending means the delivery is not succeeded yet and will be attempted at NextAttemptAt.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded means the webhook responded with a 2xx status.
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed means all attempts failed.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// DynamoWebhookDelivery is an entry of the delivery log of a webhook. It is updated on every attempt.
+type DynamoWebhookDelivery struct {
+	PK string
+	SK string
+	// ID is a UUIDv7, so the sort keys are in the order of the deliveries.
+	ID        string `dynamodbav:"UUID"`
+	UserID    auth.UserID
+	WebhookID string
+	EventID   string
+	EventType string
+	Payload   string
+	Status    WebhookDeliveryStatus
+	Attempts  int
+	// StatusCode and Error are of the last attempt. StatusCode is 0 if no response is received.
+	StatusCode int
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// NextAttemptAt is the time of the next attempt of a pending delivery, which is zero otherwise.
+	NextAttemptAt time.Time
+	// ExpiresAt is the Unix time in seconds after which DynamoDB deletes the entry by TTL. It is set by PutWebhookDelivery.
+	ExpiresAt int64
+}
+
+// webhookRetriesPK is the partition key of the copies of the pending deliveries of all users.
+// They are in one partition, so that the pending deliveries are read by a query instead of a scan.
+const webhookRetriesPK = "WEBHOOK_RETRIES"
+
+// webhookRetryPrefix is the prefix of the sort keys of the copies of the pending deliveries of the user,
+// or only of the webhook if wid is not empty.
+func webhookRetryPrefix(uid auth.UserID, wid string) string {
+	if wid == "" {
+		return fmt.Sprintf("USER#%s#", uid)
+	}
+	return fmt.Sprintf("USER#%s#WEBHOOK_DELIVERY#%s#", uid, wid)
+}
+
+// webhookRetryKey returns the key of the copy of the pending delivery.
+func webhookRetryKey(d *DynamoWebhookDelivery) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: webhookRetriesPK},
+		"SK": &types.AttributeValueMemberS{Value: webhookRetryPrefix(d.UserID, d.WebhookID) + d.ID},
+	}
+}
+
+// WebhookDeliveryRetention is how long an entry of the delivery log is kept after the delivery is created.
+const WebhookDeliveryRetention = 30 * 24 * time.Hour
+
+func NewDynamoWebhookDelivery(userID auth.UserID, webhookID, deliveryID string) *DynamoWebhookDelivery {
+	return &DynamoWebhookDelivery{
+		PK:        fmt.Sprintf("USER#%s", userID),
+		SK:        fmt.Sprintf("WEBHOOK_DELIVERY#%s#%s", webhookID, deliveryID),
+		ID:        deliveryID,
+		UserID:    userID,
+		WebhookID: webhookID,
+	}
+}
+
+type DynamoRepositoryCreateWebhookInput struct {
+	UserID auth.UserID
+	URL    string
+	Secret string
+}
+
+func (r *DynamoRepository) CreateWebhook(ctx context.Context, in *DynamoRepositoryCreateWebhookInput) (*DynamoWebhook, error) {
+	wh := NewDynamoWebhook(in.UserID, uuid.New().String())
+	wh.URL = in.URL
+	wh.Secret = in.Secret
+	wh.CreatedAt = time.Now().Round(time.Nanosecond)
+
+	item, err := attributevalue.MarshalMap(wh)
+	if err != nil {
+		return nil, fmt.Errorf("marshal webhook: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.TableName,
+		Item:      item,
+	}); err != nil {
+		return nil, fmt.Errorf("put item: %w", err)
+	}
+	return wh, nil
+}
+
+// ListWebhooks returns the webhooks of the user in the order of their IDs.
+func (r *DynamoRepository) ListWebhooks(ctx context.Context, uid auth.UserID) ([]*DynamoWebhook, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").BeginsWith("WEBHOOK#")),
+		).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	var webhooks []*DynamoWebhook
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query paginator: %w", err)
+		}
+
+		var pageItems []*DynamoWebhook
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &pageItems); err != nil {
+			return nil, fmt.Errorf("unmarshal items: %w", err)
+		}
+		webhooks = append(webhooks, pageItems...)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the webhook, its delivery log and its pending deliveries.
+// Deleting a webhook which does not exist is not an error.
+func (r *DynamoRepository) DeleteWebhook(ctx context.Context, uid auth.UserID, wid string) error {
+	// The webhook is deleted first, so that PutWebhookDelivery fails and no more deliveries are logged.
+	if err := r.deleteItems(ctx, []map[string]types.AttributeValue{NewDynamoWebhook(uid, wid).GetKey()}); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if err := r.deletePrefix(ctx, fmt.Sprintf("USER#%s", uid), fmt.Sprintf("WEBHOOK_DELIVERY#%s#", wid)); err != nil {
+		return fmt.Errorf("delete deliveries: %w", err)
+	}
+	if err := r.deletePrefix(ctx, webhookRetriesPK, webhookRetryPrefix(uid, wid)); err != nil {
+		return fmt.Errorf("delete pending deliveries: %w", err)
+	}
+	return nil
+}
+
+// PutWebhookDelivery creates or updates the entry of the delivery log, which expires after WebhookDeliveryRetention.
+// A pending delivery is also copied to be listed by ListDueWebhookDeliveries, and the copy is deleted when it is not pending.
+// It returns apperrors.ErrNotFound if the webhook is deleted, so that a deleted webhook leaves no orphaned log.
+func (r *DynamoRepository) PutWebhookDelivery(ctx context.Context, d *DynamoWebhookDelivery) error {
+	d.ExpiresAt = d.CreatedAt.Add(WebhookDeliveryRetention).Unix()
+	item, err := attributevalue.MarshalMap(d)
+	if err != nil {
+		return fmt.Errorf("marshal webhook delivery: %w", err)
+	}
+	retry := types.TransactWriteItem{
+		Delete: &types.Delete{TableName: &r.TableName, Key: webhookRetryKey(d)},
+	}
+	if d.Status == WebhookDeliveryPending {
+		retryItem, err := attributevalue.MarshalMap(d)
+		if err != nil {
+			return fmt.Errorf("marshal pending webhook delivery: %w", err)
+		}
+		maps.Copy(retryItem, webhookRetryKey(d))
+		retry = types.TransactWriteItem{
+			Put: &types.Put{TableName: &r.TableName, Item: retryItem},
+		}
+	}
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name("PK"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("build expression: %w", err)
+	}
+
+	if _, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				ConditionCheck: &types.ConditionCheck{
+					TableName:                &r.TableName,
+					Key:                      NewDynamoWebhook(d.UserID, d.WebhookID).GetKey(),
+					ConditionExpression:      expr.Condition(),
+					ExpressionAttributeNames: expr.Names(),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: &r.TableName,
+					Item:      item,
+				},
+			},
+			retry,
+		},
+	}); err != nil {
+		var tce *types.TransactionCanceledException
+		if errors.As(err, &tce) && len(tce.CancellationReasons) > 0 && tce.CancellationReasons[0].Code != nil &&
+			types.BatchStatementErrorCodeEnum(*tce.CancellationReasons[0].Code) == types.BatchStatementErrorCodeEnumConditionalCheckFailed {
+			return fmt.Errorf("webhook [%s]: %w", d.WebhookID, apperrors.ErrNotFound)
+		}
+		return fmt.Errorf("transact write items: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the latest deliveries of the webhook, from the latest.
+func (r *DynamoRepository) ListWebhookDeliveries(ctx context.Context, uid auth.UserID, wid string, limit int32) ([]*DynamoWebhookDelivery, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key("PK").Equal(expression.Value(fmt.Sprintf("USER#%s", uid))).
+				And(expression.Key("SK").BeginsWith(fmt.Sprintf("WEBHOOK_DELIVERY#%s#", wid))),
+		).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	resp, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		Limit:                     &limit,
+		ScanIndexForward:          aws.Bool(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	var deliveries []*DynamoWebhookDelivery
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &deliveries); err != nil {
+		return nil, fmt.Errorf("unmarshal items: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ListDueWebhookDeliveries returns the pending deliveries of all users whose next attempt is at or before now.
+func (r *DynamoRepository) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*DynamoWebhookDelivery, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("PK").Equal(expression.Value(webhookRetriesPK))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("build expression: %w", err)
+	}
+
+	var deliveries []*DynamoWebhookDelivery
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:                 &r.TableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query paginator: %w", err)
+		}
+
+		var pageItems []*DynamoWebhookDelivery
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &pageItems); err != nil {
+			return nil, fmt.Errorf("unmarshal items: %w", err)
+		}
+		for _, d := range pageItems {
+			// The times are compared here, since their strings are not ordered by the fractions of a second.
+			if d.NextAttemptAt.After(now) {
+				continue
+			}
+			key := NewDynamoWebhookDelivery(d.UserID, d.WebhookID, d.ID)
+			d.PK, d.SK = key.PK, key.SK
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,709 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+)
+
+// MemoryRepository is an in-memory implementation of the operations of DynamoRepository.
+// It stores the items under the same keys as DynamoRepository and fails in the same way on missing items and conflicts,
+// so that it can replace DynamoDB in tests and local runs.
+type MemoryRepository struct {
+	mu sync.Mutex
+	// items maps a partition key to the items of the partition by their sort keys.
+	items map[string]map[string]any
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		items: map[string]map[string]any{},
+	}
+}
+
+func (r *MemoryRepository) AllHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return queryItems[*DynamoHabit](r, userPK(uid), "HABITS#", cloneHabit), nil
+}
+
+func (r *MemoryRepository) AllArchivedHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return queryItems[*DynamoHabit](r, userPK(uid), "ARCHIVED_HABITS#", cloneHabit), nil
+}
+
+func (r *MemoryRepository) FindHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoHabit(uid, hid)
+	h, ok := r.items[key.PK][key.SK].(*DynamoHabit)
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	return cloneHabit(h), nil
+}
+
+func (r *MemoryRepository) FindArchivedHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewArchivedDynamoHabit(uid, hid)
+	h, ok := r.items[key.PK][key.SK].(*DynamoHabit)
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	return cloneHabit(h), nil
+}
+
+func (r *MemoryRepository) CreateHabit(ctx context.Context, in *DynamoRepositoryCreateHabitInput) (*DynamoHabit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := NewDynamoHabit(in.UserID, uuid.New().String())
+	h.Title = in.Title
+	h.Schedule = in.Schedule
+	h.Unit = in.Unit
+	h.Target = in.Target
+	h.CreatedAt = time.Now().Round(time.Nanosecond)
+	h.UpdatedAt = h.CreatedAt
+
+	r.put(h.PK, h.SK, cloneHabit(h))
+	return h, nil
+}
+
+func (r *MemoryRepository) UpdateHabit(ctx context.Context, in *DynamoRepositoryUpdateHabitInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, err := r.activeHabit(in.UserID, in.HabitID)
+	if err != nil {
+		return err
+	}
+	if h.Version != in.Version {
+		return fmt.Errorf("habit [%s] is version %d, not %d: %w", h.ID, h.Version, in.Version, apperrors.ErrConflict)
+	}
+
+	h.Title = in.Title
+	h.Schedule = in.Schedule
+	h.Unit = in.Unit
+	h.Target = in.Target
+	h.UpdatedAt = time.Now().Round(time.Nanosecond)
+	r.saveHabit(h)
+	return nil
+}
+
+func (r *MemoryRepository) ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.moveHabit(NewDynamoHabit(uid, hid), fmt.Sprintf("ARCHIVED_HABITS#%s", hid))
+}
+
+func (r *MemoryRepository) UnarchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.moveHabit(NewArchivedDynamoHabit(uid, hid), fmt.Sprintf("HABITS#%s", hid))
+}
+
+// moveHabit moves the habit item of the key to the sort key, like DynamoRepository.moveHabit.
+func (r *MemoryRepository) moveHabit(key *DynamoHabit, sk string) error {
+	h, ok := r.items[key.PK][key.SK].(*DynamoHabit)
+	if !ok || h.DeletingAt != nil {
+		return fmt.Errorf("find a habit [%s]: %w", key.ID, apperrors.ErrNotFound)
+	}
+	if _, ok := r.items[h.PK][sk]; ok {
+		return fmt.Errorf("habit [%s] is changed concurrently: %w", h.ID, apperrors.ErrConflict)
+	}
+
+	h = cloneHabit(h)
+	delete(r.items[h.PK], h.SK)
+	h.SK = sk
+	h.Version++
+	r.put(h.PK, h.SK, h)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pk := userPK(uid)
+	for sk := range r.items[pk] {
+		if strings.HasPrefix(sk, fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid)) {
+			delete(r.items[pk], sk)
+		}
+	}
+	delete(r.items[pk], NewDynamoHabit(uid, hid).SK)
+	delete(r.items[pk], NewArchivedDynamoHabit(uid, hid).SK)
+	delete(r.items[remindersPK], NewDynamoReminder(uid, hid).SK)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteUserData(ctx context.Context, uid auth.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.items, userPK(uid))
+	r.deletePrefix(remindersPK, reminderUserPrefix(uid)+"#HABIT#")
+	r.deletePrefix(webhookRetriesPK, webhookRetryPrefix(uid, ""))
+	return nil
+}
+
+func (r *MemoryRepository) CreateCheck(ctx context.Context, in *DynamoRepositoryCreateCheckInput) (*DynamoCheck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, err := r.activeHabit(in.UserID, in.HabitID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCheckValue(h, in.Value); err != nil {
+		return nil, err
+	}
+
+	c := NewDynamoCheck(in.UserID, in.HabitID, in.Date)
+	c.Value = in.Value
+	c.Note = in.Note
+	c.CreatedAt = time.Now().Round(time.Nanosecond)
+	c.UpdatedAt = c.CreatedAt
+	if _, ok := r.items[c.PK][c.SK]; ok {
+		return nil, fmt.Errorf("check [%s] already exists: %w", c.Date, apperrors.ErrConflict)
+	}
+
+	r.put(c.PK, c.SK, cloneCheck(c))
+	r.saveHabit(h)
+	return c, nil
+}
+
+func (r *MemoryRepository) BackfillChecks(ctx context.Context, in *DynamoRepositoryBackfillChecksInput) ([]string, error) {
+	checks, err := backfillChecks(in)
+	if err != nil {
+		return nil, err
+	}
+	return r.importChecks(ctx, &DynamoRepositoryImportChecksInput{UserID: in.UserID, HabitID: in.HabitID, Checks: checks},
+		func(h *DynamoHabit) error { return validateCheckValue(h, in.Value) })
+}
+
+func (r *MemoryRepository) ImportChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput) ([]string, error) {
+	return r.importChecks(ctx, in, func(h *DynamoHabit) error { return validateImportValues(h, in.Checks) })
+}
+
+func (r *MemoryRepository) importChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput, validate func(h *DynamoHabit) error) ([]string, error) {
+	checks, err := uniqueImportChecks(in.Checks)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, err := r.activeHabit(in.UserID, in.HabitID)
+	if err != nil {
+		return nil, err
+	}
+	if validate != nil {
+		if err := validate(h); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().Round(time.Nanosecond)
+	var created []string
+	for _, ic := range checks {
+		c := NewDynamoCheck(in.UserID, in.HabitID, ic.Date)
+		if _, ok := r.items[c.PK][c.SK]; ok {
+			continue
+		}
+		c.Value = ic.Value
+		c.CreatedAt = now
+		c.UpdatedAt = now
+		r.put(c.PK, c.SK, c)
+		created = append(created, ic.Date)
+	}
+	if len(created) > 0 {
+		r.saveHabit(h)
+	}
+	slices.Sort(created)
+	return created, nil
+}
+
+// CreateChecks creates the checks one by one, like DynamoRepository.CreateChecks.
+func (r *MemoryRepository) CreateChecks(ctx context.Context, in []*DynamoRepositoryCreateCheckInput) ([]*DynamoCheck, []error) {
+	checks := make([]*DynamoCheck, len(in))
+	errs := make([]error, len(in))
+	for i, c := range in {
+		checks[i], errs[i] = r.CreateCheck(ctx, c)
+	}
+	return checks, errs
+}
+
+func (r *MemoryRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, err := r.activeHabit(uid, hid)
+	if err != nil {
+		return err
+	}
+
+	c := NewDynamoCheck(uid, hid, date)
+	if _, ok := r.items[c.PK][c.SK]; !ok {
+		return fmt.Errorf("check [%s] does not exist: %w", date, apperrors.ErrNotFound)
+	}
+
+	delete(r.items[c.PK], c.SK)
+	r.saveHabit(h)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateCheckNote(ctx context.Context, in *DynamoRepositoryUpdateCheckNoteInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoCheck(in.UserID, in.HabitID, in.Date)
+	c, ok := r.items[key.PK][key.SK].(*DynamoCheck)
+	if !ok {
+		return fmt.Errorf("check [%s] does not exist: %w", in.Date, apperrors.ErrNotFound)
+	}
+	c.Note = in.Note
+	c.UpdatedAt = time.Now().Round(time.Nanosecond)
+	return nil
+}
+
+func (r *MemoryRepository) ListLatestChecksWithLimit(ctx context.Context, uid auth.UserID, hid string, limit int32) ([]*DynamoCheck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checks := queryItems[*DynamoCheck](r, userPK(uid), fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid), cloneCheck)
+	slices.Reverse(checks)
+	if len(checks) > int(limit) {
+		checks = checks[:limit]
+	}
+	return checks, nil
+}
+
+func (r *MemoryRepository) ListChecks(ctx context.Context, in *DynamoRepositoryListChecksInput) (*DynamoRepositoryListChecksOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, to := checkDateRange(in.From, in.To)
+	all := queryItems[*DynamoCheck](r, userPK(in.UserID), fmt.Sprintf("HABIT#%s__CHECK_DATE#", in.HabitID), cloneCheck)
+	if !in.Ascending {
+		slices.Reverse(all)
+	}
+
+	out := &DynamoRepositoryListChecksOutput{}
+	for _, c := range all {
+		if c.Date < from || c.Date > to {
+			continue
+		}
+		if in.After != "" && (in.Ascending && c.Date <= in.After || !in.Ascending && c.Date >= in.After) {
+			continue
+		}
+		out.Checks = append(out.Checks, c)
+		if len(out.Checks) == int(in.Limit) {
+			out.LastEvaluatedDate = c.Date
+			break
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryRepository) ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID, today time.Time) ([]*DynamoCheck, error) {
+	return r.ListChecksBetweenInAllHabits(ctx, uid, today.AddDate(0, 0, -7).Format("2006-01-02"), "")
+}
+
+func (r *MemoryRepository) ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*DynamoCheck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, to = checkDateRange(from, to)
+	var checks []*DynamoCheck
+	for _, c := range queryItems[*DynamoCheck](r, userPK(uid), fmt.Sprintf("HABIT#%s__CHECK_DATE#", hid), cloneCheck) {
+		if c.Date >= from && c.Date <= to {
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+func (r *MemoryRepository) ListChecksBetweenInAllHabits(ctx context.Context, uid auth.UserID, from, to string) ([]*DynamoCheck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, to = checkDateRange(from, to)
+	var checks []*DynamoCheck
+	for _, c := range queryItems[*DynamoCheck](r, userPK(uid), "HABIT#", cloneCheck) {
+		if c.Date >= from && c.Date <= to {
+			checks = append(checks, c)
+		}
+	}
+	// Sorted in the order of CheckDateLSI.
+	slices.SortFunc(checks, func(a, b *DynamoCheck) int {
+		return strings.Compare(a.CheckDateLSISK, b.CheckDateLSISK)
+	})
+	return checks, nil
+}
+
+func (r *MemoryRepository) ListCheckNotes(ctx context.Context, in *DynamoRepositoryListCheckNotesInput) (*DynamoRepositoryListCheckNotesOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var checks []*DynamoCheck
+	for _, c := range queryItems[*DynamoCheck](r, userPK(in.UserID), fmt.Sprintf("HABIT#%s__CHECK_DATE#", in.HabitID), cloneCheck) {
+		if c.Note != "" && (in.Before == "" || c.Date < in.Before) {
+			checks = append(checks, c)
+		}
+	}
+	slices.Reverse(checks)
+
+	out := &DynamoRepositoryListCheckNotesOutput{Checks: checks}
+	if len(checks) > int(in.Limit) {
+		out.Checks = checks[:in.Limit]
+		out.LastEvaluatedDate = out.Checks[len(out.Checks)-1].Date
+	}
+	return out, nil
+}
+
+func (r *MemoryRepository) FindProfile(ctx context.Context, uid auth.UserID) (*DynamoProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := NewDynamoProfile(uid)
+	if stored, ok := r.items[p.PK][p.SK].(*DynamoProfile); ok {
+		*p = *stored
+	}
+	return p, nil
+}
+
+func (r *MemoryRepository) UpdateProfile(ctx context.Context, in *DynamoRepositoryUpdateProfileInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := NewDynamoProfile(in.UserID)
+	if stored, ok := r.items[p.PK][p.SK].(*DynamoProfile); ok {
+		*p = *stored
+	}
+	now := time.Now().Round(time.Nanosecond)
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.TimeZone = in.TimeZone
+	p.UpdatedAt = now
+	r.put(p.PK, p.SK, p)
+	return nil
+}
+
+func (r *MemoryRepository) FindFeedToken(ctx context.Context, uid auth.UserID) (*DynamoFeedToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoFeedToken(uid)
+	t, ok := r.items[key.PK][key.SK].(*DynamoFeedToken)
+	if !ok {
+		return nil, fmt.Errorf("feed token of user [%s]: %w", uid, apperrors.ErrNotFound)
+	}
+	v := *t
+	return &v, nil
+}
+
+func (r *MemoryRepository) PutFeedToken(ctx context.Context, in *DynamoRepositoryPutFeedTokenInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := NewDynamoFeedToken(in.UserID)
+	t.TokenHash = in.TokenHash
+	t.CreatedAt = time.Now().Round(time.Nanosecond)
+	r.put(t.PK, t.SK, t)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteFeedToken(ctx context.Context, uid auth.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoFeedToken(uid)
+	delete(r.items[key.PK], key.SK)
+	return nil
+}
+
+func (r *MemoryRepository) CreateAccessToken(ctx context.Context, in *DynamoRepositoryCreateAccessTokenInput) (*DynamoAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := NewDynamoAccessToken(in.UserID, uuid.New().String())
+	t.Name = in.Name
+	t.Scope = in.Scope
+	t.TokenHash = in.TokenHash
+	t.CreatedAt = time.Now().Round(time.Nanosecond)
+	r.put(t.PK, t.SK, cloneAccessToken(t))
+	return t, nil
+}
+
+func (r *MemoryRepository) ListAccessTokens(ctx context.Context, uid auth.UserID) ([]*DynamoAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return queryItems[*DynamoAccessToken](r, userPK(uid), "ACCESS_TOKEN#", cloneAccessToken), nil
+}
+
+func (r *MemoryRepository) FindAccessToken(ctx context.Context, uid auth.UserID, tid string) (*DynamoAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoAccessToken(uid, tid)
+	t, ok := r.items[key.PK][key.SK].(*DynamoAccessToken)
+	if !ok {
+		return nil, fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+	}
+	return cloneAccessToken(t), nil
+}
+
+func (r *MemoryRepository) TouchAccessToken(ctx context.Context, uid auth.UserID, tid string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoAccessToken(uid, tid)
+	t, ok := r.items[key.PK][key.SK].(*DynamoAccessToken)
+	if !ok {
+		return fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+	}
+	t = cloneAccessToken(t)
+	at = at.Round(time.Nanosecond)
+	t.LastUsedAt = &at
+	r.put(t.PK, t.SK, t)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteAccessToken(ctx context.Context, uid auth.UserID, tid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoAccessToken(uid, tid)
+	delete(r.items[key.PK], key.SK)
+	return nil
+}
+
+func (r *MemoryRepository) CreateWebhook(ctx context.Context, in *DynamoRepositoryCreateWebhookInput) (*DynamoWebhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wh := NewDynamoWebhook(in.UserID, uuid.New().String())
+	wh.URL = in.URL
+	wh.Secret = in.Secret
+	wh.CreatedAt = time.Now().Round(time.Nanosecond)
+	c := *wh
+	r.put(wh.PK, wh.SK, &c)
+	return wh, nil
+}
+
+func (r *MemoryRepository) ListWebhooks(ctx context.Context, uid auth.UserID) ([]*DynamoWebhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return queryItems(r, userPK(uid), "WEBHOOK#", func(wh *DynamoWebhook) *DynamoWebhook {
+		c := *wh
+		return &c
+	}), nil
+}
+
+func (r *MemoryRepository) DeleteWebhook(ctx context.Context, uid auth.UserID, wid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wh := NewDynamoWebhook(uid, wid)
+	delete(r.items[wh.PK], wh.SK)
+	r.deletePrefix(wh.PK, fmt.Sprintf("WEBHOOK_DELIVERY#%s#", wid))
+	r.deletePrefix(webhookRetriesPK, webhookRetryPrefix(uid, wid))
+	return nil
+}
+
+func (r *MemoryRepository) PutWebhookDelivery(ctx context.Context, d *DynamoWebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wh := NewDynamoWebhook(d.UserID, d.WebhookID)
+	if _, ok := r.items[wh.PK][wh.SK]; !ok {
+		return fmt.Errorf("webhook [%s]: %w", d.WebhookID, apperrors.ErrNotFound)
+	}
+	d.ExpiresAt = d.CreatedAt.Add(WebhookDeliveryRetention).Unix()
+	c := *d
+	r.put(d.PK, d.SK, &c)
+	retrySK := webhookRetryPrefix(d.UserID, d.WebhookID) + d.ID
+	if d.Status == WebhookDeliveryPending {
+		retry := *d
+		r.put(webhookRetriesPK, retrySK, &retry)
+	} else {
+		delete(r.items[webhookRetriesPK], retrySK)
+	}
+
+	// The expired entries are deleted as DynamoDB does by TTL.
+	now := time.Now().Unix()
+	for _, old := range queryItems(r, d.PK, fmt.Sprintf("WEBHOOK_DELIVERY#%s#", d.WebhookID), func(d *DynamoWebhookDelivery) *DynamoWebhookDelivery { return d }) {
+		if old.ExpiresAt < now {
+			delete(r.items[old.PK], old.SK)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) ListWebhookDeliveries(ctx context.Context, uid auth.UserID, wid string, limit int32) ([]*DynamoWebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := queryItems(r, userPK(uid), fmt.Sprintf("WEBHOOK_DELIVERY#%s#", wid), func(d *DynamoWebhookDelivery) *DynamoWebhookDelivery {
+		c := *d
+		return &c
+	})
+	slices.Reverse(deliveries)
+	return deliveries[:min(len(deliveries), int(limit))], nil
+}
+
+func (r *MemoryRepository) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*DynamoWebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := queryItems(r, webhookRetriesPK, "", func(d *DynamoWebhookDelivery) *DynamoWebhookDelivery {
+		c := *d
+		return &c
+	})
+	return slices.DeleteFunc(deliveries, func(d *DynamoWebhookDelivery) bool {
+		return d.NextAttemptAt.After(now)
+	}), nil
+}
+
+func (r *MemoryRepository) PutReminder(ctx context.Context, in *DynamoRepositoryPutReminderInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rem := NewDynamoReminder(in.UserID, in.HabitID)
+	if len(in.Times) == 0 {
+		delete(r.items[rem.PK], rem.SK)
+		return nil
+	}
+	rem.Times = slices.Clone(in.Times)
+	rem.UpdatedAt = time.Now().Round(time.Nanosecond)
+	r.put(rem.PK, rem.SK, rem)
+	return nil
+}
+
+func (r *MemoryRepository) FindReminder(ctx context.Context, uid auth.UserID, hid string) (*DynamoReminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := NewDynamoReminder(uid, hid)
+	rem, ok := r.items[key.PK][key.SK].(*DynamoReminder)
+	if !ok {
+		return nil, fmt.Errorf("reminder of habit [%s]: %w", hid, apperrors.ErrNotFound)
+	}
+	return cloneReminder(rem), nil
+}
+
+func (r *MemoryRepository) AllReminders(ctx context.Context) ([]*DynamoReminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return queryItems(r, remindersPK, "", cloneReminder), nil
+}
+
+// activeHabit returns a copy of the active habit to write it or its checks.
+func (r *MemoryRepository) activeHabit(uid auth.UserID, hid string) (*DynamoHabit, error) {
+	key := NewDynamoHabit(uid, hid)
+	h, ok := r.items[key.PK][key.SK].(*DynamoHabit)
+	if !ok {
+		archived := NewArchivedDynamoHabit(uid, hid)
+		if _, ok := r.items[archived.PK][archived.SK]; ok {
+			return nil, fmt.Errorf("habit [%s]: %w", hid, apperrors.ErrArchived)
+		}
+		return nil, fmt.Errorf("get a habit [%s]: %w", hid, apperrors.ErrNotFound)
+	}
+	if h.DeletingAt != nil {
+		return nil, fmt.Errorf("habit [%s] is being deleted: %w", hid, apperrors.ErrNotFound)
+	}
+	return cloneHabit(h), nil
+}
+
+// saveHabit recomputes the aggregates of the habit from its stored checks and stores it with the next version.
+func (r *MemoryRepository) saveHabit(h *DynamoHabit) {
+	aggregate(h, queryItems[*DynamoCheck](r, h.PK, fmt.Sprintf("HABIT#%s__CHECK_DATE#", h.ID), cloneCheck))
+	h.Version++
+	r.put(h.PK, h.SK, cloneHabit(h))
+}
+
+func (r *MemoryRepository) put(pk, sk string, item any) {
+	if r.items[pk] == nil {
+		r.items[pk] = map[string]any{}
+	}
+	r.items[pk][sk] = item
+}
+
+// deletePrefix deletes the items in the partition whose sort keys begin with the prefix.
+func (r *MemoryRepository) deletePrefix(pk, prefix string) {
+	for sk := range r.items[pk] {
+		if strings.HasPrefix(sk, prefix) {
+			delete(r.items[pk], sk)
+		}
+	}
+}
+
+// queryItems returns copies of the items of the type in the partition whose sort keys begin with the prefix,
+// in ascending order of the sort keys like a query of DynamoDB.
+func queryItems[T any](r *MemoryRepository, pk, prefix string, clone func(T) T) []T {
+	var items []T
+	for _, sk := range slices.Sorted(maps.Keys(r.items[pk])) {
+		if !strings.HasPrefix(sk, prefix) {
+			continue
+		}
+		if item, ok := r.items[pk][sk].(T); ok {
+			items = append(items, clone(item))
+		}
+	}
+	return items
+}
+
+func userPK(uid auth.UserID) string {
+	return fmt.Sprintf("USER#%s", uid)
+}
+
+func cloneReminder(rem *DynamoReminder) *DynamoReminder {
+	c := *rem
+	c.Times = slices.Clone(rem.Times)
+	return &c
+}
+
+func cloneHabit(h *DynamoHabit) *DynamoHabit {
+	c := *h
+	c.Schedule.Weekdays = slices.Clone(h.Schedule.Weekdays)
+	if h.DeletingAt != nil {
+		t := *h.DeletingAt
+		c.DeletingAt = &t
+	}
+	return &c
+}
+
+func cloneCheck(c *DynamoCheck) *DynamoCheck {
+	v := *c
+	return &v
+}
+
+func cloneAccessToken(t *DynamoAccessToken) *DynamoAccessToken {
+	c := *t
+	if t.LastUsedAt != nil {
+		at := *t.LastUsedAt
+		c.LastUsedAt = &at
+	}
+	return &c
+}
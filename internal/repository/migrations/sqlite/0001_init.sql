@@ -0,0 +1,41 @@
+-- Habits are stored in a single table and archived in place, so an active and an archived copy can not coexist.
+CREATE TABLE habits (
+    user_id         TEXT    NOT NULL,
+    id              TEXT    NOT NULL,
+    archived        INTEGER NOT NULL DEFAULT 0,
+    title           TEXT    NOT NULL,
+    schedule        TEXT    NOT NULL DEFAULT '{}',
+    unit            TEXT    NOT NULL DEFAULT '',
+    target          REAL    NOT NULL DEFAULT 0,
+    checks_count    INTEGER NOT NULL DEFAULT 0,
+    total_value     REAL    NOT NULL DEFAULT 0,
+    current_streak  INTEGER NOT NULL DEFAULT 0,
+    longest_streak  INTEGER NOT NULL DEFAULT 0,
+    last_check_date TEXT    NOT NULL DEFAULT '',
+    version         INTEGER NOT NULL DEFAULT 0,
+    deleting_at     TEXT,
+    created_at      TEXT    NOT NULL,
+    updated_at      TEXT    NOT NULL,
+    PRIMARY KEY (user_id, id)
+);
+
+CREATE TABLE checks (
+    user_id    TEXT NOT NULL,
+    habit_id   TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    value      REAL NOT NULL DEFAULT 0,
+    note       TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, habit_id, date)
+);
+
+-- Same order as CheckDateLSI of DynamoDB.
+CREATE INDEX checks_user_id_date ON checks (user_id, date, habit_id);
+
+CREATE TABLE profiles (
+    user_id    TEXT PRIMARY KEY,
+    time_zone  TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
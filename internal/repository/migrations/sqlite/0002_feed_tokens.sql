@@ -0,0 +1,6 @@
+-- Only the hash of the token of the calendar feed is stored.
+CREATE TABLE feed_tokens (
+    user_id    TEXT PRIMARY KEY,
+    token_hash TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Only the hash of the secret of a personal access token is stored.
+CREATE TABLE access_tokens (
+    user_id      TEXT NOT NULL,
+    id           TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    scope        TEXT NOT NULL,
+    token_hash   TEXT NOT NULL,
+    last_used_at TEXT,
+    created_at   TEXT NOT NULL,
+    PRIMARY KEY (user_id, id)
+);
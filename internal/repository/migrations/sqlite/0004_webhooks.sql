@@ -0,0 +1,25 @@
+CREATE TABLE webhooks (
+    user_id    TEXT NOT NULL,
+    id         TEXT NOT NULL,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, id)
+);
+
+-- The delivery log of the webhooks. id is a UUIDv7, so it is in the order of the deliveries.
+CREATE TABLE webhook_deliveries (
+    user_id     TEXT NOT NULL,
+    webhook_id  TEXT NOT NULL,
+    id          TEXT NOT NULL,
+    event_id    TEXT NOT NULL,
+    event_type  TEXT NOT NULL,
+    payload     TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    attempts    INTEGER NOT NULL,
+    status_code INTEGER NOT NULL,
+    error       TEXT NOT NULL,
+    created_at  TEXT NOT NULL,
+    updated_at  TEXT NOT NULL,
+    PRIMARY KEY (user_id, webhook_id, id)
+);
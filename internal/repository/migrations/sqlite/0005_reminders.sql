@@ -0,0 +1,8 @@
+-- The reminder times of the habits. times is a JSON array of "15:04" in the time zone of the user.
+CREATE TABLE reminders (
+    user_id    TEXT NOT NULL,
+    habit_id   TEXT NOT NULL,
+    times      TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, habit_id)
+);
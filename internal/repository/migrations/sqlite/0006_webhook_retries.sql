@@ -0,0 +1,4 @@
+-- The time of the next attempt of a pending delivery, which is empty otherwise.
+ALTER TABLE webhook_deliveries ADD COLUMN next_attempt_at TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX webhook_deliveries_pending ON webhook_deliveries (status) WHERE status = 'pending';
@@ -0,0 +1,992 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteRepository is an implementation of the operations of DynamoRepository on SQLite for self-hosting.
+// It returns the same items as DynamoRepository, including their keys, and fails in the same way on missing items and conflicts.
+type SQLiteRepository struct {
+	DB *sql.DB
+}
+
+// OpenSQLiteRepository opens the database file at path and migrates its schema to the latest version.
+// ":memory:" opens a database which is discarded when it is closed.
+func OpenSQLiteRepository(ctx context.Context, path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// A single connection serializes the transactions, which SQLite does not run concurrently anyway,
+	// and keeps an in-memory database alive.
+	db.SetMaxOpenConns(1)
+
+	r := &SQLiteRepository{DB: db}
+	if err := r.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return r, nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.DB.Close()
+}
+
+// Migrate applies the migrations which are not applied yet, each in a transaction.
+// The migrations are the files in migrations/sqlite, applied in the order of their names.
+func (r *SQLiteRepository) Migrate(ctx context.Context) error {
+	if _, err := r.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(sqliteMigrations, "migrations/sqlite/*.sql")
+	if err != nil {
+		return fmt.Errorf("glob migrations: %w", err)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		version := path.Base(name)
+		if err := r.inTx(ctx, func(tx *sql.Tx) error {
+			var n int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&n); err != nil {
+				return fmt.Errorf("select schema_migrations: %w", err)
+			}
+			if n > 0 {
+				return nil
+			}
+
+			b, err := sqliteMigrations.ReadFile(name)
+			if err != nil {
+				return fmt.Errorf("read migration: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, string(b)); err != nil {
+				return fmt.Errorf("exec migration: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version); err != nil {
+				return fmt.Errorf("insert schema_migrations: %w", err)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+const sqliteHabitColumns = `id, archived, title, schedule, unit, target, checks_count, total_value,
+	current_streak, longest_streak, last_check_date, version, deleting_at, created_at, updated_at`
+
+func (r *SQLiteRepository) AllHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error) {
+	return r.queryHabits(ctx, uid, false)
+}
+
+func (r *SQLiteRepository) AllArchivedHabits(ctx context.Context, uid auth.UserID) ([]*DynamoHabit, error) {
+	return r.queryHabits(ctx, uid, true)
+}
+
+func (r *SQLiteRepository) queryHabits(ctx context.Context, uid auth.UserID, archived bool) ([]*DynamoHabit, error) {
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT `+sqliteHabitColumns+` FROM habits WHERE user_id = ? AND archived = ? ORDER BY id`,
+		uid, archived)
+	if err != nil {
+		return nil, fmt.Errorf("query habits: %w", err)
+	}
+	defer rows.Close()
+
+	var habits []*DynamoHabit
+	for rows.Next() {
+		h, err := scanSQLiteHabit(uid, rows)
+		if err != nil {
+			return nil, err
+		}
+		habits = append(habits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate habits: %w", err)
+	}
+	return habits, nil
+}
+
+func (r *SQLiteRepository) FindHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	return findSQLiteHabit(ctx, r.DB, uid, hid, false)
+}
+
+func (r *SQLiteRepository) FindArchivedHabit(ctx context.Context, uid auth.UserID, hid string) (*DynamoHabit, error) {
+	return findSQLiteHabit(ctx, r.DB, uid, hid, true)
+}
+
+func (r *SQLiteRepository) CreateHabit(ctx context.Context, in *DynamoRepositoryCreateHabitInput) (*DynamoHabit, error) {
+	h := NewDynamoHabit(in.UserID, uuid.New().String())
+	h.Title = in.Title
+	h.Schedule = in.Schedule
+	h.Unit = in.Unit
+	h.Target = in.Target
+	h.CreatedAt = time.Now().UTC().Round(time.Nanosecond)
+	h.UpdatedAt = h.CreatedAt
+
+	sched, err := json.Marshal(h.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schedule: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO habits (user_id, id, title, schedule, unit, target, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		in.UserID, h.ID, h.Title, string(sched), h.Unit, h.Target, formatSQLiteTime(h.CreatedAt), formatSQLiteTime(h.UpdatedAt),
+	); err != nil {
+		return nil, fmt.Errorf("insert habit: %w", err)
+	}
+	return h, nil
+}
+
+func (r *SQLiteRepository) UpdateHabit(ctx context.Context, in *DynamoRepositoryUpdateHabitInput) error {
+	return r.writeHabit(ctx, in.UserID, in.HabitID, func(tx *sql.Tx, h *DynamoHabit) error {
+		if h.Version != in.Version {
+			return fmt.Errorf("habit [%s] is version %d, not %d: %w", h.ID, h.Version, in.Version, apperrors.ErrConflict)
+		}
+		h.Title = in.Title
+		h.Schedule = in.Schedule
+		h.Unit = in.Unit
+		h.Target = in.Target
+		h.UpdatedAt = time.Now().UTC().Round(time.Nanosecond)
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) ArchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	return r.setArchived(ctx, uid, hid, true)
+}
+
+func (r *SQLiteRepository) UnarchiveHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	return r.setArchived(ctx, uid, hid, false)
+}
+
+// setArchived moves the habit between the active and the archived habits.
+func (r *SQLiteRepository) setArchived(ctx context.Context, uid auth.UserID, hid string, archived bool) error {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE habits SET archived = ?, version = version + 1
+		WHERE user_id = ? AND id = ? AND archived = ? AND deleting_at IS NULL`,
+		archived, uid, hid, !archived)
+	if err != nil {
+		return fmt.Errorf("update habit: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("find a habit [%s]: %w", hid, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteHabit deletes the habit, active or archived, and all of its checks in a transaction.
+func (r *SQLiteRepository) DeleteHabit(ctx context.Context, uid auth.UserID, hid string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM checks WHERE user_id = ? AND habit_id = ?`, uid, hid); err != nil {
+			return fmt.Errorf("delete checks: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM habits WHERE user_id = ? AND id = ?`, uid, hid); err != nil {
+			return fmt.Errorf("delete habit: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM reminders WHERE user_id = ? AND habit_id = ?`, uid, hid); err != nil {
+			return fmt.Errorf("delete reminder: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) DeleteUserData(ctx context.Context, uid auth.UserID) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"checks", "habits", "profiles", "feed_tokens", "access_tokens", "webhooks", "webhook_deliveries", "reminders"} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, uid); err != nil {
+				return fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) CreateCheck(ctx context.Context, in *DynamoRepositoryCreateCheckInput) (*DynamoCheck, error) {
+	c := NewDynamoCheck(in.UserID, in.HabitID, in.Date)
+	c.Value = in.Value
+	c.Note = in.Note
+	c.CreatedAt = time.Now().UTC().Round(time.Nanosecond)
+	c.UpdatedAt = c.CreatedAt
+
+	if err := r.writeHabit(ctx, in.UserID, in.HabitID, func(tx *sql.Tx, h *DynamoHabit) error {
+		if err := validateCheckValue(h, c.Value); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO checks (user_id, habit_id, date, value, note, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			in.UserID, c.HabitID, c.Date, c.Value, c.Note, formatSQLiteTime(c.CreatedAt), formatSQLiteTime(c.UpdatedAt))
+		if err != nil {
+			return fmt.Errorf("insert check: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		} else if n == 0 {
+			return fmt.Errorf("check [%s] already exists: %w", c.Date, apperrors.ErrConflict)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// BackfillChecks creates the checks of the habit on the dates in the range which are not checked yet, in a transaction.
+func (r *SQLiteRepository) BackfillChecks(ctx context.Context, in *DynamoRepositoryBackfillChecksInput) ([]string, error) {
+	checks, err := backfillChecks(in)
+	if err != nil {
+		return nil, err
+	}
+	return r.importChecks(ctx, &DynamoRepositoryImportChecksInput{UserID: in.UserID, HabitID: in.HabitID, Checks: checks},
+		func(h *DynamoHabit) error { return validateCheckValue(h, in.Value) })
+}
+
+// ImportChecks creates the checks of the habit on the dates which are not checked yet, in a transaction.
+// The values must be positive if the habit is quantitative.
+func (r *SQLiteRepository) ImportChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput) ([]string, error) {
+	return r.importChecks(ctx, in, func(h *DynamoHabit) error { return validateImportValues(h, in.Checks) })
+}
+
+func (r *SQLiteRepository) importChecks(ctx context.Context, in *DynamoRepositoryImportChecksInput, validate func(h *DynamoHabit) error) ([]string, error) {
+	checks, err := uniqueImportChecks(in.Checks)
+	if err != nil {
+		return nil, err
+	}
+
+	now := formatSQLiteTime(time.Now())
+	var created []string
+	if err := r.writeHabit(ctx, in.UserID, in.HabitID, func(tx *sql.Tx, h *DynamoHabit) error {
+		if validate != nil {
+			if err := validate(h); err != nil {
+				return err
+			}
+		}
+		for _, c := range checks {
+			res, err := tx.ExecContext(ctx,
+				`INSERT INTO checks (user_id, habit_id, date, value, note, created_at, updated_at) VALUES (?, ?, ?, ?, '', ?, ?)
+				ON CONFLICT DO NOTHING`,
+				in.UserID, in.HabitID, c.Date, c.Value, now, now)
+			if err != nil {
+				return fmt.Errorf("insert check: %w", err)
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return fmt.Errorf("rows affected: %w", err)
+			} else if n > 0 {
+				created = append(created, c.Date)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	slices.Sort(created)
+	return created, nil
+}
+
+// CreateChecks creates the checks one by one, like DynamoRepository.CreateChecks.
+func (r *SQLiteRepository) CreateChecks(ctx context.Context, in []*DynamoRepositoryCreateCheckInput) ([]*DynamoCheck, []error) {
+	checks := make([]*DynamoCheck, len(in))
+	errs := make([]error, len(in))
+	for i, c := range in {
+		checks[i], errs[i] = r.CreateCheck(ctx, c)
+	}
+	return checks, errs
+}
+
+func (r *SQLiteRepository) DeleteCheck(ctx context.Context, uid auth.UserID, hid, date string) error {
+	return r.writeHabit(ctx, uid, hid, func(tx *sql.Tx, _ *DynamoHabit) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM checks WHERE user_id = ? AND habit_id = ? AND date = ?`, uid, hid, date)
+		if err != nil {
+			return fmt.Errorf("delete check: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		} else if n == 0 {
+			return fmt.Errorf("check [%s] does not exist: %w", date, apperrors.ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// UpdateCheckNote replaces the note of the check. An empty note removes it.
+func (r *SQLiteRepository) UpdateCheckNote(ctx context.Context, in *DynamoRepositoryUpdateCheckNoteInput) error {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE checks SET note = ?, updated_at = ? WHERE user_id = ? AND habit_id = ? AND date = ?`,
+		in.Note, formatSQLiteTime(time.Now()), in.UserID, in.HabitID, in.Date)
+	if err != nil {
+		return fmt.Errorf("update check: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("check [%s] does not exist: %w", in.Date, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ListLatestChecksWithLimit(ctx context.Context, uid auth.UserID, hid string, limit int32) ([]*DynamoCheck, error) {
+	return r.queryChecks(ctx, uid,
+		`WHERE user_id = ? AND habit_id = ? ORDER BY date DESC LIMIT ?`, uid, hid, limit)
+}
+
+// ListChecks returns a page of the checks of the habit in the range of the dates, like DynamoRepository.ListChecks.
+func (r *SQLiteRepository) ListChecks(ctx context.Context, in *DynamoRepositoryListChecksInput) (*DynamoRepositoryListChecksOutput, error) {
+	from, to := checkDateRange(in.From, in.To)
+	where := `WHERE user_id = ? AND habit_id = ? AND date BETWEEN ? AND ?`
+	args := []any{in.UserID, in.HabitID, from, to}
+	if in.After != "" {
+		if in.Ascending {
+			where += ` AND date > ?`
+		} else {
+			where += ` AND date < ?`
+		}
+		args = append(args, in.After)
+	}
+	if in.Ascending {
+		where += ` ORDER BY date LIMIT ?`
+	} else {
+		where += ` ORDER BY date DESC LIMIT ?`
+	}
+	args = append(args, in.Limit)
+
+	checks, err := r.queryChecks(ctx, in.UserID, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := &DynamoRepositoryListChecksOutput{Checks: checks}
+	if len(checks) == int(in.Limit) {
+		out.LastEvaluatedDate = checks[len(checks)-1].Date
+	}
+	return out, nil
+}
+
+// ListLastWeekChecksInAllHabits returns the checks of the last 7 days before today in all habits.
+func (r *SQLiteRepository) ListLastWeekChecksInAllHabits(ctx context.Context, uid auth.UserID, today time.Time) ([]*DynamoCheck, error) {
+	return r.ListChecksBetweenInAllHabits(ctx, uid, today.AddDate(0, 0, -7).Format("2006-01-02"), "")
+}
+
+// ListChecksBetween returns the checks of the habit between the dates from and to, inclusive, in ascending order of the date.
+func (r *SQLiteRepository) ListChecksBetween(ctx context.Context, uid auth.UserID, hid, from, to string) ([]*DynamoCheck, error) {
+	from, to = checkDateRange(from, to)
+	return r.queryChecks(ctx, uid,
+		`WHERE user_id = ? AND habit_id = ? AND date BETWEEN ? AND ? ORDER BY date`, uid, hid, from, to)
+}
+
+// ListChecksBetweenInAllHabits returns the checks of all habits between the dates from and to, inclusive,
+// in ascending order of the date and then the habit ID.
+func (r *SQLiteRepository) ListChecksBetweenInAllHabits(ctx context.Context, uid auth.UserID, from, to string) ([]*DynamoCheck, error) {
+	from, to = checkDateRange(from, to)
+	return r.queryChecks(ctx, uid,
+		`WHERE user_id = ? AND date BETWEEN ? AND ? ORDER BY date, habit_id`, uid, from, to)
+}
+
+// ListCheckNotes returns a page of the checks of the habit which have a note, from the latest.
+func (r *SQLiteRepository) ListCheckNotes(ctx context.Context, in *DynamoRepositoryListCheckNotesInput) (*DynamoRepositoryListCheckNotesOutput, error) {
+	where := `WHERE user_id = ? AND habit_id = ? AND note != ''`
+	args := []any{in.UserID, in.HabitID}
+	if in.Before != "" {
+		where += ` AND date < ?`
+		args = append(args, in.Before)
+	}
+	// One more check tells whether there is a next page.
+	where += ` ORDER BY date DESC LIMIT ?`
+	args = append(args, in.Limit+1)
+
+	checks, err := r.queryChecks(ctx, in.UserID, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := &DynamoRepositoryListCheckNotesOutput{Checks: checks}
+	if len(checks) > int(in.Limit) {
+		out.Checks = checks[:in.Limit]
+		out.LastEvaluatedDate = out.Checks[len(out.Checks)-1].Date
+	}
+	return out, nil
+}
+
+func (r *SQLiteRepository) queryChecks(ctx context.Context, uid auth.UserID, where string, args ...any) ([]*DynamoCheck, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT habit_id, date, value, note, created_at, updated_at FROM checks `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query checks: %w", err)
+	}
+	defer rows.Close()
+
+	var checks []*DynamoCheck
+	for rows.Next() {
+		var hid, date, createdAt, updatedAt string
+		var value float64
+		var note string
+		if err := rows.Scan(&hid, &date, &value, &note, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan check: %w", err)
+		}
+		c := NewDynamoCheck(uid, hid, date)
+		c.Value = value
+		c.Note = note
+		if c.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		if c.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate checks: %w", err)
+	}
+	return checks, nil
+}
+
+func (r *SQLiteRepository) FindProfile(ctx context.Context, uid auth.UserID) (*DynamoProfile, error) {
+	p := NewDynamoProfile(uid)
+	var createdAt, updatedAt string
+	err := r.DB.QueryRowContext(ctx, `SELECT time_zone, created_at, updated_at FROM profiles WHERE user_id = ?`, uid).
+		Scan(&p.TimeZone, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select profile: %w", err)
+	}
+	if p.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if p.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdateProfile saves the profile of the user, creating it if it does not exist yet.
+func (r *SQLiteRepository) UpdateProfile(ctx context.Context, in *DynamoRepositoryUpdateProfileInput) error {
+	now := formatSQLiteTime(time.Now())
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO profiles (user_id, time_zone, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET time_zone = excluded.time_zone, updated_at = excluded.updated_at`,
+		in.UserID, in.TimeZone, now, now,
+	); err != nil {
+		return fmt.Errorf("upsert profile: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) FindFeedToken(ctx context.Context, uid auth.UserID) (*DynamoFeedToken, error) {
+	t := NewDynamoFeedToken(uid)
+	var createdAt string
+	err := r.DB.QueryRowContext(ctx, `SELECT token_hash, created_at FROM feed_tokens WHERE user_id = ?`, uid).
+		Scan(&t.TokenHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("feed token of user [%s]: %w", uid, apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select feed token: %w", err)
+	}
+	if t.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// PutFeedToken saves the feed token of the user, which replaces and so revokes the previous one.
+func (r *SQLiteRepository) PutFeedToken(ctx context.Context, in *DynamoRepositoryPutFeedTokenInput) error {
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO feed_tokens (user_id, token_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		in.UserID, in.TokenHash, formatSQLiteTime(time.Now()),
+	); err != nil {
+		return fmt.Errorf("upsert feed token: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) DeleteFeedToken(ctx context.Context, uid auth.UserID) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM feed_tokens WHERE user_id = ?`, uid); err != nil {
+		return fmt.Errorf("delete feed token: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) CreateAccessToken(ctx context.Context, in *DynamoRepositoryCreateAccessTokenInput) (*DynamoAccessToken, error) {
+	t := NewDynamoAccessToken(in.UserID, uuid.New().String())
+	t.Name = in.Name
+	t.Scope = in.Scope
+	t.TokenHash = in.TokenHash
+	t.CreatedAt = time.Now().UTC().Round(time.Nanosecond)
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO access_tokens (user_id, id, name, scope, token_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.UserID, t.ID, t.Name, t.Scope, t.TokenHash, formatSQLiteTime(t.CreatedAt),
+	); err != nil {
+		return nil, fmt.Errorf("insert access token: %w", err)
+	}
+	return t, nil
+}
+
+// ListAccessTokens returns the access tokens of the user in the order of their IDs.
+func (r *SQLiteRepository) ListAccessTokens(ctx context.Context, uid auth.UserID) ([]*DynamoAccessToken, error) {
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT id, name, scope, token_hash, last_used_at, created_at FROM access_tokens WHERE user_id = ? ORDER BY id`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("select access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*DynamoAccessToken
+	for rows.Next() {
+		t, err := scanSQLiteAccessToken(rows, uid)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *SQLiteRepository) FindAccessToken(ctx context.Context, uid auth.UserID, tid string) (*DynamoAccessToken, error) {
+	row := r.DB.QueryRowContext(ctx,
+		`SELECT id, name, scope, token_hash, last_used_at, created_at FROM access_tokens WHERE user_id = ? AND id = ?`, uid, tid)
+	t, err := scanSQLiteAccessToken(row, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+	}
+	return t, err
+}
+
+func scanSQLiteAccessToken(row interface{ Scan(...any) error }, uid auth.UserID) (*DynamoAccessToken, error) {
+	var (
+		id, name, scope, tokenHash, createdAt string
+		lastUsedAt                            sql.NullString
+	)
+	if err := row.Scan(&id, &name, &scope, &tokenHash, &lastUsedAt, &createdAt); err != nil {
+		return nil, fmt.Errorf("scan access token: %w", err)
+	}
+	t := NewDynamoAccessToken(uid, id)
+	t.Name = name
+	t.Scope = AccessTokenScope(scope)
+	t.TokenHash = tokenHash
+	var err error
+	if t.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		at, err := parseSQLiteTime(lastUsedAt.String)
+		if err != nil {
+			return nil, err
+		}
+		t.LastUsedAt = &at
+	}
+	return t, nil
+}
+
+func (r *SQLiteRepository) TouchAccessToken(ctx context.Context, uid auth.UserID, tid string, at time.Time) error {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE access_tokens SET last_used_at = ? WHERE user_id = ? AND id = ?`, formatSQLiteTime(at), uid, tid)
+	if err != nil {
+		return fmt.Errorf("update access token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("access token [%s]: %w", tid, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) DeleteAccessToken(ctx context.Context, uid auth.UserID, tid string) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM access_tokens WHERE user_id = ? AND id = ?`, uid, tid); err != nil {
+		return fmt.Errorf("delete access token: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) CreateWebhook(ctx context.Context, in *DynamoRepositoryCreateWebhookInput) (*DynamoWebhook, error) {
+	wh := NewDynamoWebhook(in.UserID, uuid.New().String())
+	wh.URL = in.URL
+	wh.Secret = in.Secret
+	wh.CreatedAt = time.Now().UTC().Round(time.Nanosecond)
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO webhooks (user_id, id, url, secret, created_at) VALUES (?, ?, ?, ?, ?)`,
+		wh.UserID, wh.ID, wh.URL, wh.Secret, formatSQLiteTime(wh.CreatedAt),
+	); err != nil {
+		return nil, fmt.Errorf("insert webhook: %w", err)
+	}
+	return wh, nil
+}
+
+// ListWebhooks returns the webhooks of the user in the order of their IDs.
+func (r *SQLiteRepository) ListWebhooks(ctx context.Context, uid auth.UserID) ([]*DynamoWebhook, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, url, secret, created_at FROM webhooks WHERE user_id = ? ORDER BY id`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("select webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*DynamoWebhook
+	for rows.Next() {
+		var id, url, secret, createdAt string
+		if err := rows.Scan(&id, &url, &secret, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		wh := NewDynamoWebhook(uid, id)
+		wh.URL = url
+		wh.Secret = secret
+		if wh.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *SQLiteRepository) DeleteWebhook(ctx context.Context, uid auth.UserID, wid string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE user_id = ? AND id = ?`, uid, wid); err != nil {
+			return fmt.Errorf("delete webhook: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE user_id = ? AND webhook_id = ?`, uid, wid); err != nil {
+			return fmt.Errorf("delete webhook deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
+// PutWebhookDelivery creates or updates the entry of the delivery log.
+// The entries of the webhook older than WebhookDeliveryRetention are deleted, as DynamoDB does by TTL.
+// It returns apperrors.ErrNotFound if the webhook is deleted.
+func (r *SQLiteRepository) PutWebhookDelivery(ctx context.Context, d *DynamoWebhookDelivery) error {
+	d.ExpiresAt = d.CreatedAt.Add(WebhookDeliveryRetention).Unix()
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhooks WHERE user_id = ? AND id = ?`, d.UserID, d.WebhookID).Scan(&n); err != nil {
+			return fmt.Errorf("select webhook: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("webhook [%s]: %w", d.WebhookID, apperrors.ErrNotFound)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO webhook_deliveries
+				(user_id, webhook_id, id, event_id, event_type, payload, status, attempts, status_code, error, created_at, updated_at, next_attempt_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			d.UserID, d.WebhookID, d.ID, d.EventID, d.EventType, d.Payload, d.Status, d.Attempts, d.StatusCode, d.Error,
+			formatSQLiteTime(d.CreatedAt), formatSQLiteTime(d.UpdatedAt), formatSQLiteNextAttempt(d.NextAttemptAt),
+		); err != nil {
+			return fmt.Errorf("insert webhook delivery: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE user_id = ? AND webhook_id = ? AND created_at < ?`,
+			d.UserID, d.WebhookID, formatSQLiteTime(time.Now().Add(-WebhookDeliveryRetention))); err != nil {
+			return fmt.Errorf("delete expired webhook deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListWebhookDeliveries returns the latest deliveries of the webhook, from the latest.
+func (r *SQLiteRepository) ListWebhookDeliveries(ctx context.Context, uid auth.UserID, wid string, limit int32) ([]*DynamoWebhookDelivery, error) {
+	return r.queryWebhookDeliveries(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE user_id = ? AND webhook_id = ? ORDER BY id DESC LIMIT ?`,
+		uid, wid, limit)
+}
+
+// ListDueWebhookDeliveries returns the pending deliveries of all users whose next attempt is at or before now.
+func (r *SQLiteRepository) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*DynamoWebhookDelivery, error) {
+	deliveries, err := r.queryWebhookDeliveries(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE status = ? ORDER BY user_id, webhook_id, id`,
+		WebhookDeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	// The times are compared here, since their strings are not ordered by the fractions of a second.
+	return slices.DeleteFunc(deliveries, func(d *DynamoWebhookDelivery) bool {
+		return d.NextAttemptAt.After(now)
+	}), nil
+}
+
+const webhookDeliveryColumns = `user_id, webhook_id, id, event_id, event_type, payload, status, attempts, status_code, error, created_at, updated_at, next_attempt_at`
+
+// queryWebhookDeliveries runs the query which selects webhookDeliveryColumns and scans the deliveries.
+func (r *SQLiteRepository) queryWebhookDeliveries(ctx context.Context, query string, args ...any) ([]*DynamoWebhookDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*DynamoWebhookDelivery
+	for rows.Next() {
+		var uid, wid, id, status, createdAt, updatedAt, nextAttemptAt string
+		var d DynamoWebhookDelivery
+		if err := rows.Scan(&uid, &wid, &id, &d.EventID, &d.EventType, &d.Payload, &status, &d.Attempts, &d.StatusCode, &d.Error,
+			&createdAt, &updatedAt, &nextAttemptAt); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		key := NewDynamoWebhookDelivery(auth.UserID(uid), wid, id)
+		d.PK, d.SK, d.ID, d.UserID, d.WebhookID = key.PK, key.SK, key.ID, key.UserID, key.WebhookID
+		d.Status = WebhookDeliveryStatus(status)
+		if d.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		if d.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+			return nil, err
+		}
+		if nextAttemptAt != "" {
+			if d.NextAttemptAt, err = parseSQLiteTime(nextAttemptAt); err != nil {
+				return nil, err
+			}
+		}
+		d.ExpiresAt = d.CreatedAt.Add(WebhookDeliveryRetention).Unix()
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// PutReminder saves the reminder times of the habit, replacing the previous ones. No times deletes the reminder.
+func (r *SQLiteRepository) PutReminder(ctx context.Context, in *DynamoRepositoryPutReminderInput) error {
+	if len(in.Times) == 0 {
+		if _, err := r.DB.ExecContext(ctx, `DELETE FROM reminders WHERE user_id = ? AND habit_id = ?`, in.UserID, in.HabitID); err != nil {
+			return fmt.Errorf("delete reminder: %w", err)
+		}
+		return nil
+	}
+	times, err := json.Marshal(in.Times)
+	if err != nil {
+		return fmt.Errorf("marshal times: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx,
+		`INSERT INTO reminders (user_id, habit_id, times, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, habit_id) DO UPDATE SET times = excluded.times, updated_at = excluded.updated_at`,
+		in.UserID, in.HabitID, string(times), formatSQLiteTime(time.Now()),
+	); err != nil {
+		return fmt.Errorf("upsert reminder: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) FindReminder(ctx context.Context, uid auth.UserID, hid string) (*DynamoReminder, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT user_id, habit_id, times, updated_at FROM reminders WHERE user_id = ? AND habit_id = ?`, uid, hid)
+	rem, err := scanSQLiteReminder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reminder of habit [%s]: %w", hid, apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rem, nil
+}
+
+// AllReminders returns the reminders of all users, in the order of users and habits.
+func (r *SQLiteRepository) AllReminders(ctx context.Context) ([]*DynamoReminder, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT user_id, habit_id, times, updated_at FROM reminders ORDER BY user_id, habit_id`)
+	if err != nil {
+		return nil, fmt.Errorf("select reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []*DynamoReminder
+	for rows.Next() {
+		rem, err := scanSQLiteReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, rem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func scanSQLiteReminder(row interface{ Scan(...any) error }) (*DynamoReminder, error) {
+	var uid, hid, times, updatedAt string
+	if err := row.Scan(&uid, &hid, &times, &updatedAt); err != nil {
+		return nil, fmt.Errorf("scan reminder: %w", err)
+	}
+	rem := NewDynamoReminder(auth.UserID(uid), hid)
+	if err := json.Unmarshal([]byte(times), &rem.Times); err != nil {
+		return nil, fmt.Errorf("unmarshal times: %w", err)
+	}
+	var err error
+	if rem.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return rem, nil
+}
+
+// writeHabit runs fn in a transaction with the active habit, and then recomputes the aggregates of the habit
+// from its checks and increments its version, like DynamoRepository.writeHabit.
+func (r *SQLiteRepository) writeHabit(ctx context.Context, uid auth.UserID, hid string, fn func(tx *sql.Tx, h *DynamoHabit) error) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		h, err := findSQLiteHabit(ctx, tx, uid, hid, false)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			if _, aerr := findSQLiteHabit(ctx, tx, uid, hid, true); aerr == nil {
+				return fmt.Errorf("habit [%s]: %w", hid, apperrors.ErrArchived)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("get a habit [%s]: %w", hid, err)
+		}
+		if h.DeletingAt != nil {
+			return fmt.Errorf("habit [%s] is being deleted: %w", hid, apperrors.ErrNotFound)
+		}
+
+		if err := fn(tx, h); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT date, value FROM checks WHERE user_id = ? AND habit_id = ? ORDER BY date`, uid, hid)
+		if err != nil {
+			return fmt.Errorf("query check values: %w", err)
+		}
+		var checks []*DynamoCheck
+		for rows.Next() {
+			c := &DynamoCheck{}
+			if err := rows.Scan(&c.Date, &c.Value); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan check value: %w", err)
+			}
+			checks = append(checks, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate check values: %w", err)
+		}
+		aggregate(h, checks)
+
+		sched, err := json.Marshal(h.Schedule)
+		if err != nil {
+			return fmt.Errorf("marshal schedule: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE habits SET title = ?, schedule = ?, unit = ?, target = ?, updated_at = ?,
+				checks_count = ?, total_value = ?, current_streak = ?, longest_streak = ?, last_check_date = ?,
+				version = version + 1
+			WHERE user_id = ? AND id = ?`,
+			h.Title, string(sched), h.Unit, h.Target, formatSQLiteTime(h.UpdatedAt),
+			h.ChecksCount, h.TotalValue, h.CurrentStreak, h.LongestStreak, h.LastCheckDate,
+			uid, hid,
+		); err != nil {
+			return fmt.Errorf("update habit: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// sqliteQueryer is either *sql.DB or *sql.Tx.
+type sqliteQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func findSQLiteHabit(ctx context.Context, q sqliteQueryer, uid auth.UserID, hid string, archived bool) (*DynamoHabit, error) {
+	row := q.QueryRowContext(ctx,
+		`SELECT `+sqliteHabitColumns+` FROM habits WHERE user_id = ? AND id = ? AND archived = ?`,
+		uid, hid, archived)
+	h, err := scanSQLiteHabit(uid, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	return h, err
+}
+
+// scanSQLiteHabit scans a row of sqliteHabitColumns into a habit with the same keys as DynamoRepository.
+func scanSQLiteHabit(uid auth.UserID, row interface{ Scan(dest ...any) error }) (*DynamoHabit, error) {
+	var (
+		hid, sched, createdAt, updatedAt string
+		archived                         bool
+		deletingAt                       sql.NullString
+		h                                DynamoHabit
+	)
+	if err := row.Scan(&hid, &archived, &h.Title, &sched, &h.Unit, &h.Target, &h.ChecksCount, &h.TotalValue,
+		&h.CurrentStreak, &h.LongestStreak, &h.LastCheckDate, &h.Version, &deletingAt, &createdAt, &updatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan habit: %w", err)
+	}
+
+	key := NewDynamoHabit(uid, hid)
+	if archived {
+		key = NewArchivedDynamoHabit(uid, hid)
+	}
+	h.PK, h.SK, h.UserID, h.ID = key.PK, key.SK, key.UserID, key.ID
+
+	if err := json.Unmarshal([]byte(sched), &h.Schedule); err != nil {
+		return nil, fmt.Errorf("unmarshal schedule: %w", err)
+	}
+	var err error
+	if h.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if h.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if deletingAt.Valid {
+		t, err := parseSQLiteTime(deletingAt.String)
+		if err != nil {
+			return nil, err
+		}
+		h.DeletingAt = &t
+	}
+	return &h, nil
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// formatSQLiteNextAttempt formats the next attempt time of a delivery, which is empty if it is not pending.
+func formatSQLiteNextAttempt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatSQLiteTime(t)
+}
+
+func parseSQLiteTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse time %q: %w", s, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLiteRepository_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "habits.db")
+	uid := auth.UserID("user-1")
+
+	repo, err := OpenSQLiteRepository(ctx, path)
+	require.NoError(t, err)
+	h, err := repo.CreateHabit(ctx, &DynamoRepositoryCreateHabitInput{UserID: uid, Title: "Running"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// The applied migrations are not applied again.
+	repo, err = OpenSQLiteRepository(ctx, path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	got, err := repo.FindHabit(ctx, uid, h.ID)
+	require.NoError(t, err)
+	assert.Equal(t, h, got)
+
+	names, err := fs.Glob(sqliteMigrations, "migrations/sqlite/*.sql")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, repo.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&n))
+	assert.Equal(t, len(names), n)
+}
+
+func TestSQLiteRepository_PutWebhookDelivery_Expired(t *testing.T) {
+	ctx := context.Background()
+	repo, err := OpenSQLiteRepository(ctx, filepath.Join(t.TempDir(), "habits.db"))
+	require.NoError(t, err)
+	defer repo.Close()
+	uid := auth.UserID("user-1")
+
+	wh, err := repo.CreateWebhook(ctx, &DynamoRepositoryCreateWebhookInput{UserID: uid, URL: "https://example.com/hook", Secret: "secret"})
+	require.NoError(t, err)
+	put := func(createdAt time.Time) {
+		d := NewDynamoWebhookDelivery(uid, wh.ID, uuid.Must(uuid.NewV7()).String())
+		d.Status = WebhookDeliverySucceeded
+		d.CreatedAt = createdAt
+		d.UpdatedAt = createdAt
+		require.NoError(t, repo.PutWebhookDelivery(ctx, d))
+	}
+	put(time.Now().Add(-WebhookDeliveryRetention - time.Hour))
+	put(time.Now())
+
+	// The expired delivery is deleted by the next one, as DynamoDB deletes it by TTL.
+	got, err := repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.WithinDuration(t, time.Now(), got[0].CreatedAt, time.Minute)
+}
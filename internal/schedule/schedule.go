@@ -0,0 +1,380 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of a habit schedule.
+type Kind string
+
+const (
+	// KindDaily means the habit is due every day.
+	KindDaily Kind = "daily"
+	// KindWeekdays means the habit is due on the specific weekdays.
+	KindWeekdays Kind = "weekdays"
+	// KindTimesPerWeek means the habit should be done N times in a week (Monday to Sunday).
+	KindTimesPerWeek Kind = "times_per_week"
+	// KindInterval means the habit is due N days after the last check.
+	KindInterval Kind = "interval"
+)
+
+// MaxIntervalDays is the maximum value of Schedule.IntervalDays.
+const MaxIntervalDays = 30
+
+// Schedule describes when a habit is due.
+// The zero value is a daily schedule, which is the schedule of habits created before schedules existed.
+type Schedule struct {
+	Kind         Kind           `dynamodbav:",omitempty"`
+	Weekdays     []time.Weekday `dynamodbav:",omitempty"`
+	TimesPerWeek int            `dynamodbav:",omitempty"`
+	IntervalDays int            `dynamodbav:",omitempty"`
+}
+
+// Daily returns a schedule which is due every day.
+func Daily() Schedule {
+	return Schedule{Kind: KindDaily}
+}
+
+// EvaluationDays returns the number of days before today whose checks an Evaluator needs
+// to evaluate the last week, the current week and whether the current streak is alive.
+func (s Schedule) EvaluationDays() int {
+	return max(14, 7+s.IntervalDays)
+}
+
+func (s Schedule) kind() Kind {
+	if s.Kind == "" {
+		return KindDaily
+	}
+	return s.Kind
+}
+
+// Validate returns an error if the schedule is inconsistent.
+func (s Schedule) Validate() error {
+	switch s.kind() {
+	case KindDaily:
+		return nil
+	case KindWeekdays:
+		if len(s.Weekdays) == 0 {
+			return errors.New("weekdays must not be empty")
+		}
+		for i, wd := range s.Weekdays {
+			if wd < time.Sunday || wd > time.Saturday {
+				return fmt.Errorf("invalid weekday: %d", wd)
+			}
+			if slices.Contains(s.Weekdays[:i], wd) {
+				return fmt.Errorf("duplicated weekday: %s", wd)
+			}
+		}
+		return nil
+	case KindTimesPerWeek:
+		if s.TimesPerWeek < 1 || s.TimesPerWeek > 7 {
+			return errors.New("times per week must be between 1 and 7")
+		}
+		return nil
+	case KindInterval:
+		if s.IntervalDays < 2 || s.IntervalDays > MaxIntervalDays {
+			return fmt.Errorf("interval days must be between 2 and %d", MaxIntervalDays)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown schedule kind: %q", s.Kind)
+}
+
+// String returns a human readable description of the schedule.
+func (s Schedule) String() string {
+	switch s.kind() {
+	case KindWeekdays:
+		wds := slices.Clone(s.Weekdays)
+		slices.Sort(wds)
+		names := make([]string, 0, len(wds))
+		for _, wd := range wds {
+			names = append(names, wd.String()[:3])
+		}
+		return strings.Join(names, ", ")
+	case KindTimesPerWeek:
+		if s.TimesPerWeek == 1 {
+			return "Once a week"
+		}
+		return fmt.Sprintf("%d times a week", s.TimesPerWeek)
+	case KindInterval:
+		return fmt.Sprintf("Every %d days", s.IntervalDays)
+	}
+	return "Every day"
+}
+
+// Status is the status of a habit on a day.
+type Status string
+
+const (
+	// StatusDone means the habit is checked on the day.
+	StatusDone Status = "done"
+	// StatusPartial means the habit is checked on the day, but the value did not reach the target.
+	StatusPartial Status = "partial"
+	// StatusDue means the habit should be checked today.
+	StatusDue Status = "due"
+	// StatusMissed means the habit should have been checked on the past day.
+	StatusMissed Status = "missed"
+	// StatusRest means the habit is not scheduled on the day.
+	StatusRest Status = "rest"
+)
+
+// Day is the status of a habit on a date.
+type Day struct {
+	Date   string
+	Status Status
+}
+
+const dateLayout = "2006-01-02"
+
+// Check is a checked date of a habit.
+type Check struct {
+	// Date is formatted as "2006-01-02".
+	Date string
+	// Ratio is the progress of the day, 1 if the target is reached. See Ratio.
+	Ratio float64
+}
+
+// Ratio returns the progress of a check whose value is v against the daily target.
+// A habit without a target is always complete.
+func Ratio(v, target float64) float64 {
+	if target <= 0 {
+		return 1
+	}
+	return min(v/target, 1)
+}
+
+// Dates returns checks which are complete on each date.
+func Dates(dates ...string) []Check {
+	checks := make([]Check, 0, len(dates))
+	for _, d := range dates {
+		checks = append(checks, Check{Date: d, Ratio: 1})
+	}
+	return checks
+}
+
+// Evaluator evaluates a schedule against checks.
+type Evaluator struct {
+	Schedule Schedule
+	// Since is the first day the habit can be due, typically the day it was created.
+	Since time.Time
+	// Today is the current day of the user.
+	Today time.Time
+
+	// checked maps checked dates to their ratio.
+	checked map[string]float64
+}
+
+// NewEvaluator returns a new evaluator.
+func NewEvaluator(s Schedule, since, today time.Time, checks []Check) *Evaluator {
+	checked := make(map[string]float64, len(checks))
+	for _, c := range checks {
+		checked[c.Date] = c.Ratio
+	}
+	return &Evaluator{
+		Schedule: s,
+		Since:    civil(since),
+		Today:    civil(today),
+		checked:  checked,
+	}
+}
+
+// Days returns the statuses of the last n days, ending with today.
+func (e *Evaluator) Days(n int) []Day {
+	days := make([]Day, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		d := e.Today.AddDate(0, 0, -i)
+		days = append(days, Day{
+			Date:   d.Format(dateLayout),
+			Status: e.status(d),
+		})
+	}
+	return days
+}
+
+// CompletionRate returns the rate of the scheduled days which are checked in the last n days, ending with today.
+// A partial check counts as its ratio, and today counts only if it is checked.
+// It returns false if no day is scheduled.
+func (e *Evaluator) CompletionRate(n int) (float64, bool) {
+	return e.completionRate(e.Days(n))
+}
+
+// WeekdayCompletionRates returns the completion rate of each weekday in the last n days, ending with today,
+// indexed by time.Weekday. It is computed as CompletionRate, from the days of the weekday only,
+// so that a weekday which is scheduled less often is not ranked lower.
+// ok is false for a weekday which is never scheduled.
+func (e *Evaluator) WeekdayCompletionRates(n int) (rates [7]float64, ok [7]bool) {
+	var days [7][]Day
+	for i, d := range e.Days(n) {
+		wd := e.Today.AddDate(0, 0, i-n+1).Weekday()
+		days[wd] = append(days[wd], d)
+	}
+	for wd := range days {
+		rates[wd], ok[wd] = e.completionRate(days[wd])
+	}
+	return rates, ok
+}
+
+func (e *Evaluator) completionRate(days []Day) (float64, bool) {
+	scheduled, done := 0, 0.0
+	for _, d := range days {
+		switch d.Status {
+		case StatusDone, StatusPartial:
+			scheduled++
+			done += e.checked[d.Date]
+		case StatusMissed:
+			scheduled++
+		}
+	}
+	if scheduled == 0 {
+		return 0, false
+	}
+	return done / float64(scheduled), true
+}
+
+// Current returns the status of today.
+// Unlike Days, a times-per-week habit whose weekly target is already reached is reported as done.
+func (e *Evaluator) Current() Status {
+	if _, ok := e.checked[e.Today.Format(dateLayout)]; !ok &&
+		e.Schedule.kind() == KindTimesPerWeek && e.WeekCount() >= float64(e.Schedule.TimesPerWeek) {
+		return StatusDone
+	}
+	return e.status(e.Today)
+}
+
+// WeekCount returns the number of checks in the current week.
+// A partial check counts as a part of a day.
+func (e *Evaluator) WeekCount() float64 {
+	return e.countWeek(WeekStart(e.Today))
+}
+
+func (e *Evaluator) status(d time.Time) Status {
+	if ratio, ok := e.checked[d.Format(dateLayout)]; ok {
+		if ratio < 1 {
+			return StatusPartial
+		}
+		return StatusDone
+	}
+	if d.Before(e.Since) || d.After(e.Today) {
+		return StatusRest
+	}
+
+	switch e.Schedule.kind() {
+	case KindWeekdays:
+		if !slices.Contains(e.Schedule.Weekdays, d.Weekday()) {
+			return StatusRest
+		}
+	case KindTimesPerWeek:
+		start := WeekStart(d)
+		if e.countWeek(start) >= float64(e.Schedule.TimesPerWeek) {
+			return StatusRest
+		}
+		// The target of the current week can still be reached on the remaining days.
+		if start.Equal(WeekStart(e.Today)) && !d.Equal(e.Today) {
+			return StatusRest
+		}
+	case KindInterval:
+		for i := 1; i < e.Schedule.IntervalDays; i++ {
+			if _, ok := e.checked[d.AddDate(0, 0, -i).Format(dateLayout)]; ok {
+				return StatusRest
+			}
+		}
+	}
+
+	if d.Equal(e.Today) {
+		return StatusDue
+	}
+	return StatusMissed
+}
+
+func (e *Evaluator) countWeek(start time.Time) float64 {
+	n := 0.0
+	for i := range 7 {
+		n += e.checked[start.AddDate(0, 0, i).Format(dateLayout)]
+	}
+	return n
+}
+
+// civil returns the calendar date of t as midnight in UTC, so that day arithmetic is not affected by DST.
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// WeekStart returns the Monday of the week of d.
+func WeekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// Streaks returns the current and the longest streak of the checks.
+// A streak is the number of checks in a row which is not broken by a missed day,
+// and the current streak is the one ending on the latest date.
+// A partial check keeps the streak, but does not extend it.
+func Streaks(s Schedule, checks []Check) (current, longest int) {
+	days := make([]time.Time, 0, len(checks))
+	for _, c := range checks {
+		t, err := time.Parse(dateLayout, c.Date)
+		if err != nil {
+			continue
+		}
+		days = append(days, t)
+	}
+	if len(days) == 0 {
+		return 0, 0
+	}
+	first, last := slices.MinFunc(days, compareTime), slices.MaxFunc(days, compareTime)
+
+	// Evaluate as if today is the latest date, so that the last period is still open.
+	e := NewEvaluator(s, first, last, checks)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		switch e.status(d) {
+		case StatusDone:
+			current++
+			longest = max(longest, current)
+		case StatusMissed:
+			current = 0
+		}
+	}
+	return current, longest
+}
+
+// Broken reports whether a day before the week of to is missed by the checks, so that the streaks after it
+// do not depend on the checks before it, nor are changed by the checks on and after to.
+// The checks must include all checks since from. The status of a day depends on the checks of the days before it,
+// so only the days EvaluationDays after from are evaluated.
+func Broken(s Schedule, checks []Check, from, to time.Time) bool {
+	from, to = civil(from), civil(to)
+	e := NewEvaluator(s, from, to, checks)
+	for d := from.AddDate(0, 0, s.EvaluationDays()); d.Before(WeekStart(to)); d = d.AddDate(0, 0, 1) {
+		if e.status(d) == StatusMissed {
+			return true
+		}
+	}
+	return false
+}
+
+// StreakAlive reports whether the streak ending on the last date is not broken yet,
+// which means no day between the last date and today is missed.
+func (e *Evaluator) StreakAlive(last string) bool {
+	d, err := time.Parse(dateLayout, last)
+	if err != nil {
+		return false
+	}
+	// Every schedule is due at least once in two weeks or an interval, so a streak can not survive longer without checks.
+	if e.Today.Sub(d) > time.Duration(max(14, e.Schedule.IntervalDays))*24*time.Hour {
+		return false
+	}
+	for d = d.AddDate(0, 0, 1); d.Before(e.Today); d = d.AddDate(0, 0, 1) {
+		if e.status(d) == StatusMissed {
+			return false
+		}
+	}
+	return true
+}
+
+func compareTime(a, b time.Time) int {
+	return a.Compare(b)
+}
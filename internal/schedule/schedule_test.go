@@ -0,0 +1,318 @@
+package schedule
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		s       Schedule
+		wantErr bool
+	}{
+		{name: "zero value", s: Schedule{}},
+		{name: "daily", s: Daily()},
+		{name: "weekdays", s: Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Friday}}},
+		{name: "empty weekdays", s: Schedule{Kind: KindWeekdays}, wantErr: true},
+		{name: "duplicated weekdays", s: Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Monday}}, wantErr: true},
+		{name: "invalid weekday", s: Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{7}}, wantErr: true},
+		{name: "times per week", s: Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 3}},
+		{name: "zero times per week", s: Schedule{Kind: KindTimesPerWeek}, wantErr: true},
+		{name: "interval", s: Schedule{Kind: KindInterval, IntervalDays: 2}},
+		{name: "too long interval", s: Schedule{Kind: KindInterval, IntervalDays: MaxIntervalDays + 1}, wantErr: true},
+		{name: "unknown kind", s: Schedule{Kind: "monthly"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.s.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Days(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)  // Monday
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // Wednesday
+
+	tests := []struct {
+		name  string
+		s     Schedule
+		dates []string
+		want  []Status
+	}{
+		{
+			name:  "daily",
+			s:     Daily(),
+			dates: []string{"2024-01-05", "2024-01-07"},
+			// 01-04 (Thu) to 01-10 (Wed)
+			want: []Status{StatusMissed, StatusDone, StatusMissed, StatusDone, StatusMissed, StatusMissed, StatusDue},
+		},
+		{
+			name:  "weekdays",
+			s:     Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}},
+			dates: []string{"2024-01-05"},
+			want:  []Status{StatusRest, StatusDone, StatusRest, StatusRest, StatusMissed, StatusRest, StatusDue},
+		},
+		{
+			name:  "times per week",
+			s:     Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 3},
+			dates: []string{"2024-01-02", "2024-01-05", "2024-01-08"},
+			// The last week is only checked twice, so its unchecked days are missed.
+			want: []Status{StatusMissed, StatusDone, StatusMissed, StatusMissed, StatusDone, StatusRest, StatusDue},
+		},
+		{
+			name:  "interval",
+			s:     Schedule{Kind: KindInterval, IntervalDays: 3},
+			dates: []string{"2024-01-04", "2024-01-08"},
+			want:  []Status{StatusDone, StatusRest, StatusRest, StatusMissed, StatusDone, StatusRest, StatusRest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			days := NewEvaluator(tt.s, since, today, Dates(tt.dates...)).Days(7)
+			require.Len(t, days, 7)
+			assert.Equal(t, "2024-01-04", days[0].Date)
+			assert.Equal(t, "2024-01-10", days[6].Date)
+
+			got := make([]Status, 0, len(days))
+			for _, d := range days {
+				got = append(got, d.Status)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluator_Days_BeforeSince(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 9, 12, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+
+	days := NewEvaluator(Daily(), since, today, Dates("2024-01-07")).Days(4)
+	assert.Equal(t, []Day{
+		{Date: "2024-01-07", Status: StatusDone},
+		{Date: "2024-01-08", Status: StatusRest},
+		{Date: "2024-01-09", Status: StatusMissed},
+		{Date: "2024-01-10", Status: StatusDue},
+	}, days)
+}
+
+func TestEvaluator_CompletionRate(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)  // Monday
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // Wednesday
+
+	// 01-04 to 01-09 are scheduled, and 01-10 is due.
+	e := NewEvaluator(Daily(), since, today, []Check{
+		{Date: "2024-01-05", Ratio: 1},
+		{Date: "2024-01-07", Ratio: 0.5},
+	})
+	rate, ok := e.CompletionRate(7)
+	require.True(t, ok)
+	assert.InDelta(t, 1.5/6, rate, 1e-9)
+
+	// Before since, no day is scheduled.
+	_, ok = NewEvaluator(Daily(), today, today, nil).CompletionRate(7)
+	assert.False(t, ok)
+
+	e = NewEvaluator(Daily(), today, today, Dates("2024-01-10"))
+	rate, ok = e.CompletionRate(7)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, rate)
+}
+
+func TestEvaluator_WeekdayCompletionRates(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)  // Monday
+	today := time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC) // Sunday
+	s := Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Thursday}}
+
+	// Mondays are scheduled three times and Thursdays three times.
+	e := NewEvaluator(s, since, today, []Check{
+		{Date: "2024-01-01", Ratio: 1},
+		{Date: "2024-01-08", Ratio: 1},
+		{Date: "2024-01-04", Ratio: 0.5},
+	})
+	rates, ok := e.WeekdayCompletionRates(21)
+	require.True(t, ok[time.Monday])
+	assert.InDelta(t, 2.0/3, rates[time.Monday], 1e-9)
+	require.True(t, ok[time.Thursday])
+	assert.InDelta(t, 0.5/3, rates[time.Thursday], 1e-9)
+	for _, wd := range []time.Weekday{time.Sunday, time.Tuesday, time.Wednesday, time.Friday, time.Saturday} {
+		assert.False(t, ok[wd], wd)
+	}
+}
+
+func TestEvaluator_Current(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // Wednesday
+	weekly := Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 2}
+
+	assert.Equal(t, StatusDue, NewEvaluator(weekly, since, today, Dates("2024-01-08")).Current())
+	assert.Equal(t, StatusDone, NewEvaluator(weekly, since, today, Dates("2024-01-08", "2024-01-09")).Current())
+	assert.Equal(t, StatusDone, NewEvaluator(Daily(), since, today, Dates("2024-01-10")).Current())
+	assert.Equal(t, StatusRest, NewEvaluator(Schedule{Kind: KindInterval, IntervalDays: 2}, since, today, Dates("2024-01-09")).Current())
+}
+
+func TestStreaks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		s           Schedule
+		dates       []string
+		wantCurrent int
+		wantLongest int
+	}{
+		{name: "no checks", s: Daily()},
+		{
+			name:        "daily",
+			s:           Daily(),
+			dates:       []string{"2024-01-05", "2024-01-01", "2024-01-02", "2024-01-03", "2024-01-06"},
+			wantCurrent: 2,
+			wantLongest: 3,
+		},
+		{
+			name:        "weekdays skip rest days",
+			s:           Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Friday}},
+			dates:       []string{"2024-01-01", "2024-01-05", "2024-01-08", "2024-01-12"},
+			wantCurrent: 4,
+			wantLongest: 4,
+		},
+		{
+			name: "times per week",
+			s:    Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 2},
+			// The second week is checked only once.
+			dates:       []string{"2024-01-01", "2024-01-07", "2024-01-14", "2024-01-15", "2024-01-16"},
+			wantCurrent: 3,
+			wantLongest: 3,
+		},
+		{
+			name:        "interval",
+			s:           Schedule{Kind: KindInterval, IntervalDays: 2},
+			dates:       []string{"2024-01-01", "2024-01-03", "2024-01-05", "2024-01-08"},
+			wantCurrent: 1,
+			wantLongest: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			current, longest := Streaks(tt.s, Dates(tt.dates...))
+			assert.Equal(t, tt.wantCurrent, current)
+			assert.Equal(t, tt.wantLongest, longest)
+		})
+	}
+}
+
+func TestStreaks_Partial(t *testing.T) {
+	t.Parallel()
+
+	current, longest := Streaks(Daily(), []Check{
+		{Date: "2024-01-01", Ratio: 1},
+		{Date: "2024-01-02", Ratio: 0.5},
+		{Date: "2024-01-03", Ratio: 1},
+	})
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 2, longest)
+}
+
+func TestEvaluator_Partial(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // Wednesday
+	checks := []Check{
+		{Date: "2024-01-08", Ratio: 0.5},
+		{Date: "2024-01-09", Ratio: 0.5},
+	}
+
+	e := NewEvaluator(Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 1}, since, today, checks)
+	assert.Equal(t, 1.0, e.WeekCount())
+	assert.Equal(t, StatusDone, e.Current())
+
+	e = NewEvaluator(Daily(), since, today, checks)
+	assert.Equal(t, []Day{
+		{Date: "2024-01-08", Status: StatusPartial},
+		{Date: "2024-01-09", Status: StatusPartial},
+		{Date: "2024-01-10", Status: StatusDue},
+	}, e.Days(3))
+}
+
+func TestRatio(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1.0, Ratio(0, 0))
+	assert.Equal(t, 1.0, Ratio(3, 0))
+	assert.Equal(t, 0.0, Ratio(0, 5))
+	assert.Equal(t, 0.6, Ratio(3, 5))
+	assert.Equal(t, 1.0, Ratio(8, 5))
+}
+
+func TestBroken(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) // Monday
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)   // Thursday
+	var every []string
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		every = append(every, d.Format("2006-01-02"))
+	}
+	without := func(dates ...string) []Check {
+		return Dates(slices.DeleteFunc(slices.Clone(every), func(d string) bool { return slices.Contains(dates, d) })...)
+	}
+
+	assert.False(t, Broken(Daily(), Dates(every...), from, to))
+	assert.True(t, Broken(Daily(), without("2024-01-20"), from, to))
+	// The days too close to from are not evaluated, and neither is the week of to.
+	assert.False(t, Broken(Daily(), without("2024-01-10"), from, to))
+	assert.False(t, Broken(Daily(), without("2024-01-29"), from, to))
+
+	twice := Schedule{Kind: KindTimesPerWeek, TimesPerWeek: 2}
+	assert.False(t, Broken(twice, Dates("2024-01-15", "2024-01-21", "2024-01-22", "2024-01-23"), from, to))
+	assert.True(t, Broken(twice, Dates("2024-01-15", "2024-01-22", "2024-01-23"), from, to))
+
+	every20 := Schedule{Kind: KindInterval, IntervalDays: 20}
+	assert.False(t, Broken(every20, Dates("2024-01-05", "2024-01-24"), from, to))
+	assert.True(t, Broken(every20, Dates("2024-01-01"), from, to))
+}
+
+func TestEvaluator_StreakAlive(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // Wednesday
+	mwf := Schedule{Kind: KindWeekdays, Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}}
+
+	assert.True(t, NewEvaluator(Daily(), since, today, Dates("2024-01-09")).StreakAlive("2024-01-09"))
+	assert.True(t, NewEvaluator(Daily(), since, today, Dates("2024-01-10")).StreakAlive("2024-01-10"))
+	assert.False(t, NewEvaluator(Daily(), since, today, Dates("2024-01-08")).StreakAlive("2024-01-08"))
+	assert.True(t, NewEvaluator(mwf, since, today, Dates("2024-01-08")).StreakAlive("2024-01-08"))
+	assert.False(t, NewEvaluator(mwf, since, today, Dates("2024-01-05")).StreakAlive("2024-01-05"))
+	assert.False(t, NewEvaluator(Daily(), since, today, nil).StreakAlive(""))
+
+	every20 := Schedule{Kind: KindInterval, IntervalDays: 20}
+	today = time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	assert.True(t, NewEvaluator(every20, since, today, Dates("2024-01-12")).StreakAlive("2024-01-12"))
+	assert.False(t, NewEvaluator(every20, since, today, Dates("2024-01-09")).StreakAlive("2024-01-09"))
+}
@@ -0,0 +1,67 @@
+// Package storage opens the repository of the backend chosen at startup.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/hareku/habit-tracker-app/internal/api"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+const (
+	BackendDynamoDB = "dynamodb"
+	BackendSQLite   = "sqlite"
+)
+
+type Config struct {
+	// Backend is either BackendDynamoDB or BackendSQLite. The empty string means BackendDynamoDB.
+	Backend string
+	// SQLitePath is the path of the database file of BackendSQLite.
+	SQLitePath string
+}
+
+// ConfigFromEnv reads the config from STORAGE_BACKEND and SQLITE_PATH.
+func ConfigFromEnv() Config {
+	return Config{
+		Backend:    os.Getenv("STORAGE_BACKEND"),
+		SQLitePath: os.Getenv("SQLITE_PATH"),
+	}
+}
+
+// Open returns the repository of the backend and a function which releases it.
+func Open(ctx context.Context, c Config) (api.DynamoRepository, func() error, error) {
+	switch c.Backend {
+	case "", BackendDynamoDB:
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load aws config: %w", err)
+		}
+		cfg.Region = "ap-northeast-1"
+		if e := os.Getenv("AWS_ENDPOINT"); e != "" {
+			cfg.BaseEndpoint = aws.String(e)
+			slog.Info("Loaded AWS_ENDPOINT env", slog.String("endpoint", e))
+		}
+		return &repository.DynamoRepository{
+			Client:    dynamodb.NewFromConfig(cfg),
+			TableName: "HabitTrackerApp",
+		}, func() error { return nil }, nil
+	case BackendSQLite:
+		if c.SQLitePath == "" {
+			return nil, nil, fmt.Errorf("sqlite path is empty")
+		}
+		repo, err := repository.OpenSQLiteRepository(ctx, c.SQLitePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open sqlite repository: %w", err)
+		}
+		slog.Info("Opened SQLite repository", slog.String("path", c.SQLitePath))
+		return repo, repo.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", c.Backend)
+	}
+}
@@ -0,0 +1,323 @@
+// Package webhook delivers the events of users to the URLs they registered, signed with HMAC-SHA256.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hareku/habit-tracker-app/internal/apperrors"
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+)
+
+// The types of events.
+const (
+	EventCheckCreated  = "check.created"
+	EventCheckDeleted  = "check.deleted"
+	EventHabitCreated  = "habit.created"
+	EventHabitArchived = "habit.archived"
+	EventHabitDeleted  = "habit.deleted"
+)
+
+// The headers of a delivery.
+const (
+	// HeaderEvent is the type of the event.
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderDelivery is the ID of the delivery, which is the same on all attempts.
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderTimestamp is the Unix time of the attempt in seconds, which is signed to prevent replays.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is the signature of the attempt. See Sign.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	// ID is the same on all attempts and all webhooks, so receivers can ignore duplicates.
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature of a delivery: "sha256=" and the hex encoded HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed by the secret of the webhook.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature is of the timestamp and the body, in constant time.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+type Repository interface {
+	ListWebhooks(ctx context.Context, uid auth.UserID) ([]*repository.DynamoWebhook, error)
+	PutWebhookDelivery(ctx context.Context, d *repository.DynamoWebhookDelivery) error
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]*repository.DynamoWebhookDelivery, error)
+}
+
+// DefaultBackoff is the waits before the retries of a failed delivery.
+// The retries are made by RetryDue, so they are later by up to the interval of its schedule.
+var DefaultBackoff = []time.Duration{time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour}
+
+// lostAttemptDelay is the wait before a delivery whose first attempt is not recorded is retried,
+// e.g. because the process exited during the attempt. It is longer than the timeout of the client.
+const lostAttemptDelay = time.Minute
+
+// defaultRetryConcurrency is the number of the retries which RetryDue makes at once.
+const defaultRetryConcurrency = 10
+
+// Dispatcher delivers events. Every attempt is recorded in the delivery log of the webhook.
+//
+// A delivery is recorded as pending before its first attempt, which is made in the background.
+// The failed attempts are retried by RetryDue, which a scheduled worker calls, so no retry is lost if the process exits.
+// A delivery may be attempted again after its attempt was made but not recorded,
+// so receivers should ignore the events whose IDs they have seen.
+type Dispatcher struct {
+	Repository Repository
+	// Client sends the deliveries. The default client refuses the addresses of the server's own network. See checkAddress.
+	Client *http.Client
+	// Backoff is the waits before the retries. A delivery is attempted len(Backoff)+1 times at most.
+	Backoff []time.Duration
+	// RetryConcurrency is the number of the retries which RetryDue makes at once.
+	RetryConcurrency int
+
+	now func() time.Time
+	wg  sync.WaitGroup
+}
+
+// ErrForbiddenAddress is returned when a webhook resolves to an address of the server's own network.
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// checkAddress rejects the loopback, private, link-local and unspecified addresses,
+// so that a webhook can not reach the server itself or the services in its network, such as the instance metadata.
+// It is the Control of the dialer, so that the address is checked on every connection after the name is resolved,
+// and a name resolving to another address later (DNS rebinding) is rejected too.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("parse address %q: %w", address, err)
+	}
+	ip := ap.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("dial %s %s: %w", network, address, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+func NewDispatcher(repo Repository) *Dispatcher {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: checkAddress}
+	return &Dispatcher{
+		Repository: repo,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+			// No proxy is used, since the dialer would check the address of the proxy instead of the webhook.
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// A redirect is a failure, so that a webhook can not be moved to another host silently.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Backoff:          DefaultBackoff,
+		RetryConcurrency: defaultRetryConcurrency,
+		now:              time.Now,
+	}
+}
+
+// Publish records a delivery of the event to every webhook of the user, and makes their first attempts in the background.
+// data is marshaled as JSON.
+func (d *Dispatcher) Publish(ctx context.Context, uid auth.UserID, eventType string, data any) {
+	if err := d.publish(ctx, uid, eventType, data); err != nil {
+		slog.ErrorContext(ctx, fmt.Errorf("publish %s event: %w", eventType, err).Error())
+	}
+}
+
+func (d *Dispatcher) publish(ctx context.Context, uid auth.UserID, eventType string, data any) error {
+	webhooks, err := d.Repository.ListWebhooks(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("list webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := d.now().UTC()
+	e := &Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      data,
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	// The attempts outlive the request.
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	for _, wh := range webhooks {
+		del := repository.NewDynamoWebhookDelivery(wh.UserID, wh.ID, uuid.Must(uuid.NewV7()).String())
+		del.EventID = e.ID
+		del.EventType = e.Type
+		del.Payload = string(body)
+		del.Status = repository.WebhookDeliveryPending
+		del.CreatedAt = now
+		del.UpdatedAt = now
+		del.NextAttemptAt = now.Add(lostAttemptDelay)
+		// The delivery is recorded before the attempt, so that it is retried even if the attempt is lost.
+		err := d.Repository.PutWebhookDelivery(ctx, del)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			// The delivery is attempted even if it is not recorded, but it is not retried.
+			errs = append(errs, fmt.Errorf("put delivery to webhook [%s]: %w", wh.ID, err))
+		}
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.attempt(ctx, wh, del)
+		}()
+	}
+	return errors.Join(errs...)
+}
+
+// RetryDue retries the pending deliveries of all users whose next attempts are at or before now,
+// and returns the number of the attempts. A scheduled worker calls it every few minutes.
+func (d *Dispatcher) RetryDue(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := d.Repository.ListDueWebhookDeliveries(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("list due webhook deliveries: %w", err)
+	}
+
+	webhooks := map[auth.UserID][]*repository.DynamoWebhook{}
+	sem := make(chan struct{}, max(d.RetryConcurrency, 1))
+	var wg sync.WaitGroup
+	attempted := 0
+	var errs []error
+	for _, del := range deliveries {
+		whs, ok := webhooks[del.UserID]
+		if !ok {
+			whs, err = d.Repository.ListWebhooks(ctx, del.UserID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("list webhooks of user [%s]: %w", del.UserID, err))
+				continue
+			}
+			webhooks[del.UserID] = whs
+		}
+		i := slices.IndexFunc(whs, func(wh *repository.DynamoWebhook) bool { return wh.ID == del.WebhookID })
+		if i < 0 {
+			// The webhook is deleted, and its deliveries are being deleted.
+			continue
+		}
+
+		attempted++
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.attempt(ctx, whs[i], del)
+		}()
+	}
+	wg.Wait()
+	return attempted, errors.Join(errs...)
+}
+
+// attempt makes an attempt of the delivery and records it as succeeded, as failed after the last attempt,
+// or as pending until the next retry.
+func (d *Dispatcher) attempt(ctx context.Context, wh *repository.DynamoWebhook, del *repository.DynamoWebhookDelivery) {
+	code, err := d.send(ctx, wh, del, []byte(del.Payload))
+	del.Attempts++
+	del.StatusCode = code
+	del.Error = ""
+	del.UpdatedAt = d.now().UTC()
+	del.NextAttemptAt = time.Time{}
+	switch {
+	case err == nil:
+		del.Status = repository.WebhookDeliverySucceeded
+	case del.Attempts > len(d.Backoff):
+		del.Status = repository.WebhookDeliveryFailed
+		del.Error = err.Error()
+	default:
+		del.Status = repository.WebhookDeliveryPending
+		del.Error = err.Error()
+		del.NextAttemptAt = del.UpdatedAt.Add(d.Backoff[del.Attempts-1])
+	}
+
+	// The webhook may be deleted during the attempt, and then the delivery is not recorded.
+	if err := d.Repository.PutWebhookDelivery(ctx, del); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		slog.ErrorContext(ctx, fmt.Errorf("put webhook delivery: %w", err).Error())
+	}
+}
+
+// send makes an attempt of the delivery and returns the status code of the response, if any.
+func (d *Dispatcher) send(ctx context.Context, wh *repository.DynamoWebhook, del *repository.DynamoWebhookDelivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+	ts := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "habit-tracker-app-webhook")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, del.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, ts, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Read some of the body to reuse the connection.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Wait waits for the first attempts which are made in the background, or until ctx is done.
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hareku/habit-tracker-app/internal/auth"
+	"github.com/hareku/habit-tracker-app/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	// echo -n '1609664400.{}' | openssl dgst -sha256 -hmac secret
+	sig := Sign("secret", 1609664400, []byte("{}"))
+	assert.Equal(t, "sha256=14d6419b15fcbbbc07f56c58c5a2b37798ed744b430fedd7fc1b569e50f890a3", sig)
+	assert.True(t, Verify("secret", 1609664400, []byte("{}"), sig))
+	assert.False(t, Verify("other", 1609664400, []byte("{}"), sig))
+	assert.False(t, Verify("secret", 1609664401, []byte("{}"), sig))
+	assert.False(t, Verify("secret", 1609664400, []byte("{ }"), sig))
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := context.Background()
+
+	// The receiver fails the first 2 attempts of each delivery.
+	var attempts atomic.Int32
+	received := make(chan *Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !assert.NoError(t, err) || !assert.True(t, Verify("secret", ts, body, r.Header.Get(HeaderSignature))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var e Event
+		if assert.NoError(t, json.Unmarshal(body, &e)) {
+			assert.Equal(t, e.Type, r.Header.Get(HeaderEvent))
+			received <- &e
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := repository.NewMemoryRepository()
+	wh, err := repo.CreateWebhook(ctx, &repository.DynamoRepositoryCreateWebhookInput{UserID: uid, URL: receiver.URL, Secret: "secret"})
+	require.NoError(t, err)
+
+	d := NewDispatcher(repo)
+	// The receiver listens on the loopback address, which the default client refuses.
+	d.Client = receiver.Client()
+	d.Backoff = []time.Duration{time.Minute, time.Minute}
+	d.Publish(ctx, uid, EventCheckCreated, map[string]string{"habit_id": "h1", "date": "2021-01-03"})
+	// The users without webhooks are skipped.
+	d.Publish(ctx, auth.UserID("other"), EventCheckCreated, nil)
+	require.NoError(t, d.Wait(ctx))
+
+	// The failed first attempt is retried after the backoff.
+	deliveries, err := repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, repository.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	n, err := d.RetryDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	retry := func() {
+		t.Helper()
+		n, err := d.RetryDue(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+	retry()
+	retry()
+
+	e := <-received
+	assert.Equal(t, EventCheckCreated, e.Type)
+	assert.Equal(t, map[string]any{"habit_id": "h1", "date": "2021-01-03"}, e.Data)
+
+	deliveries, err = repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, repository.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].StatusCode)
+	assert.Equal(t, e.ID, deliveries[0].EventID)
+	assert.Empty(t, deliveries[0].Error)
+
+	// All attempts fail.
+	attempts.Store(-10)
+	d.Publish(ctx, uid, EventHabitDeleted, map[string]string{"id": "h1"})
+	require.NoError(t, d.Wait(ctx))
+	retry()
+	retry()
+	n, err = d.RetryDue(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	deliveries, err = repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, EventHabitDeleted, deliveries[0].EventType)
+	assert.Equal(t, repository.WebhookDeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].StatusCode)
+	assert.Equal(t, "unexpected status 503", deliveries[0].Error)
+}
+
+func TestDispatcher_LostAttempt(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := context.Background()
+
+	received := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "delivery-1", r.Header.Get(HeaderDelivery))
+			received <- body
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := repository.NewMemoryRepository()
+	wh, err := repo.CreateWebhook(ctx, &repository.DynamoRepositoryCreateWebhookInput{UserID: uid, URL: receiver.URL, Secret: "secret"})
+	require.NoError(t, err)
+
+	// The process exited during the first attempt, so only the pending delivery is recorded.
+	at := time.Now().UTC()
+	del := repository.NewDynamoWebhookDelivery(uid, wh.ID, "delivery-1")
+	del.EventID = "event-1"
+	del.EventType = EventHabitCreated
+	del.Payload = `{"id":"event-1"}`
+	del.Status = repository.WebhookDeliveryPending
+	del.CreatedAt = at
+	del.UpdatedAt = at
+	del.NextAttemptAt = at.Add(lostAttemptDelay)
+	require.NoError(t, repo.PutWebhookDelivery(ctx, del))
+
+	d := NewDispatcher(repo)
+	// The receiver listens on the loopback address, which the default client refuses.
+	d.Client = receiver.Client()
+	n, err := d.RetryDue(ctx, at.Add(lostAttemptDelay))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, `{"id":"event-1"}`, string(<-received))
+
+	deliveries, err := repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, repository.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.True(t, deliveries[0].NextAttemptAt.IsZero())
+	due, err := repo.ListDueWebhookDeliveries(ctx, at.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestDispatcher_DeletedWebhook(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := context.Background()
+
+	repo := repository.NewMemoryRepository()
+	var wh *repository.DynamoWebhook
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		// The webhook is deleted while it is delivered.
+		assert.NoError(t, repo.DeleteWebhook(ctx, uid, wh.ID))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	wh, err := repo.CreateWebhook(ctx, &repository.DynamoRepositoryCreateWebhookInput{UserID: uid, URL: receiver.URL, Secret: "secret"})
+	require.NoError(t, err)
+
+	d := NewDispatcher(repo)
+	// The receiver listens on the loopback address, which the default client refuses.
+	d.Client = receiver.Client()
+	d.Publish(ctx, uid, EventHabitArchived, nil)
+	require.NoError(t, d.Wait(ctx))
+	n, err := d.RetryDue(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// It is not retried nor logged.
+	assert.Equal(t, int32(1), attempts.Load())
+	deliveries, err := repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestDispatcher_ForbiddenAddress(t *testing.T) {
+	t.Parallel()
+
+	uid := auth.UserID("123")
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+	}))
+	defer receiver.Close()
+
+	repo := repository.NewMemoryRepository()
+	wh, err := repo.CreateWebhook(ctx, &repository.DynamoRepositoryCreateWebhookInput{UserID: uid, URL: receiver.URL, Secret: "secret"})
+	require.NoError(t, err)
+
+	d := NewDispatcher(repo)
+	d.Backoff = nil
+	d.Publish(ctx, uid, EventHabitCreated, nil)
+	require.NoError(t, d.Wait(ctx))
+
+	assert.Zero(t, attempts.Load())
+	deliveries, err := repo.ListWebhookDeliveries(ctx, uid, wh.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, repository.WebhookDeliveryFailed, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].Error, ErrForbiddenAddress.Error())
+}
+
+func TestCheckAddress(t *testing.T) {
+	t.Parallel()
+
+	for addr, forbidden := range map[string]bool{
+		"93.184.216.34:443":       false,
+		"[2606:2800:220:1::]:443": false,
+		"127.0.0.1:80":            true,
+		"[::1]:80":                true,
+		"[::ffff:127.0.0.1]:80":   true,
+		"10.0.0.1:80":             true,
+		"172.16.0.1:80":           true,
+		"192.168.1.1:80":          true,
+		"[fd00::1]:80":            true,
+		"169.254.169.254:80":      true,
+		"[fe80::1]:80":            true,
+		"0.0.0.0:80":              true,
+		"[::]:80":                 true,
+	} {
+		err := checkAddress("tcp", addr, nil)
+		if forbidden {
+			assert.ErrorIs(t, err, ErrForbiddenAddress, addr)
+		} else {
+			assert.NoError(t, err, addr)
+		}
+	}
+}